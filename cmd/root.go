@@ -4,8 +4,10 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"k8s-to-drawio/internal/converter"
+	"k8s-to-drawio/internal/output"
 
 	"github.com/spf13/cobra"
 )
@@ -18,11 +20,32 @@ var (
 	convertNamespace       string
 	convertLayout          string
 	convertNoNamespaces    bool
+	convertInclude         []string
+	convertExclude         []string
+	convertStrict          bool
+	convertRulesFile       string
+	convertTimestamp       string
+	convertUpdateFile      string
+	convertMarkDeleted     bool
+	convertPages           bool
+	convertIcons           string
+	convertTheme           string
+	convertCompress        bool
+	convertAnnotations     []string
+	convertLegend          bool
+	convertLayers          bool
+	convertFormat          string
+	convertVerbose         bool
 
 	// Validate command flags
 	validateInputDir        string
 	validateEnableKustomize bool
 	validateNamespace       string
+	validateInclude         []string
+	validateExclude         []string
+	validateStrict          bool
+	validateRulesFile       string
+	validateVerbose         bool
 )
 
 var rootCmd = &cobra.Command{
@@ -38,6 +61,9 @@ var convertCmd = &cobra.Command{
 		if convertInputDir == "" {
 			return fmt.Errorf("input directory is required")
 		}
+		if convertOutputFile == "" {
+			convertOutputFile = convertUpdateFile
+		}
 		if convertOutputFile == "" {
 			return fmt.Errorf("output file is required")
 		}
@@ -55,6 +81,22 @@ var convertCmd = &cobra.Command{
 			Namespace:    convertNamespace,
 			Layout:       convertLayout,
 			NoNamespaces: convertNoNamespaces,
+			Include:      convertInclude,
+			Exclude:      convertExclude,
+			Strict:       convertStrict,
+			RulesFile:    convertRulesFile,
+			Timestamp:    convertTimestamp,
+			UpdateFile:   convertUpdateFile,
+			MarkDeleted:  convertMarkDeleted,
+			MultiPage:    convertPages,
+			Icons:        convertIcons,
+			Theme:        convertTheme,
+			Compressed:   convertCompress,
+			Annotations:  convertAnnotations,
+			Legend:       convertLegend,
+			Layers:       convertLayers,
+			Format:       convertFormat,
+			Verbose:      convertVerbose,
 		})
 
 		// Execute conversion
@@ -74,6 +116,11 @@ var validateCmd = &cobra.Command{
 			InputDir:     validateInputDir,
 			UseKustomize: validateEnableKustomize,
 			Namespace:    validateNamespace,
+			Include:      validateInclude,
+			Exclude:      validateExclude,
+			Strict:       validateStrict,
+			RulesFile:    validateRulesFile,
+			Verbose:      validateVerbose,
 		})
 
 		return conv.Validate()
@@ -91,16 +138,37 @@ var versionCmd = &cobra.Command{
 func init() {
 	// Convert command flags
 	convertCmd.Flags().StringVarP(&convertInputDir, "input", "i", "", "Input directory containing Kubernetes manifests")
-	convertCmd.Flags().StringVarP(&convertOutputFile, "output", "o", "", "Output Draw.io file path")
+	convertCmd.Flags().StringVarP(&convertOutputFile, "output", "o", "", "Output file path")
 	convertCmd.Flags().BoolVarP(&convertEnableKustomize, "kustomize", "k", false, "Enable Kustomize processing")
 	convertCmd.Flags().StringVarP(&convertNamespace, "namespace", "n", "", "Filter by namespace")
-	convertCmd.Flags().StringVarP(&convertLayout, "layout", "l", "hierarchical", "Layout algorithm (hierarchical/grid/vertical)")
+	convertCmd.Flags().StringVarP(&convertFormat, "format", "f", "", fmt.Sprintf("Output format (%s) (default detected from the output file extension, drawio if unknown)", strings.Join(output.Names(), "/")))
+	convertCmd.Flags().StringVarP(&convertLayout, "layout", "l", "hierarchical", "Layout algorithm (hierarchical/grid/vertical/force)")
 	convertCmd.Flags().BoolVar(&convertNoNamespaces, "no-namespaces", false, "Disable namespace grouping (flat layout)")
+	convertCmd.Flags().StringSliceVar(&convertInclude, "include", nil, "Glob patterns of manifest files to parse (default *.yaml,*.yml)")
+	convertCmd.Flags().StringSliceVar(&convertExclude, "exclude", nil, "Glob patterns of files or directories to skip")
+	convertCmd.Flags().BoolVar(&convertStrict, "strict", false, "Fail if any YAML document cannot be parsed instead of skipping it")
+	convertCmd.Flags().StringVar(&convertRulesFile, "rules", "", "YAML file with additional reference rules")
+	convertCmd.Flags().StringVar(&convertTimestamp, "timestamp", "", "Modification time recorded in the diagram, RFC 3339 or Unix seconds (default $SOURCE_DATE_EPOCH, none if unset)")
+	convertCmd.Flags().StringVar(&convertUpdateFile, "update", "", "Existing Draw.io file to update, keeping its manual layout and added shapes (default output file)")
+	convertCmd.Flags().BoolVar(&convertMarkDeleted, "mark-deleted", false, "With --update, mark cells of deleted resources instead of removing them")
+	convertCmd.Flags().BoolVar(&convertPages, "pages", false, "Write an overview page plus one page per namespace")
+	convertCmd.Flags().StringVar(&convertIcons, "icons", "default", "Icon set (default/kubernetes)")
+	convertCmd.Flags().StringVar(&convertTheme, "theme", "default", "Theme (default/dark/colorblind-safe) or YAML/JSON theme file")
+	convertCmd.Flags().BoolVar(&convertCompress, "compress", false, "Write compressed pages (deflate and base64, like Draw.io's compressed files)")
+	convertCmd.Flags().BoolVar(&convertLegend, "legend", false, "Add a legend of the shapes and connection styles used")
+	convertCmd.Flags().BoolVar(&convertLayers, "layers", false, "Put resources and connections on one Draw.io layer per category (workload/networking/config/storage/rbac/monitoring/vault)")
+	convertCmd.Flags().StringSliceVar(&convertAnnotations, "annotations", nil, "Patterns of annotations included in the resource properties (default description,*/description,vault.security.banzaicloud.io/*)")
+	convertCmd.Flags().BoolVarP(&convertVerbose, "verbose", "v", false, "Report YAML documents ignored because they are not Kubernetes manifests")
 
 	// Validate command flags
 	validateCmd.Flags().StringVarP(&validateInputDir, "input", "i", "", "Input directory containing Kubernetes manifests")
 	validateCmd.Flags().BoolVarP(&validateEnableKustomize, "kustomize", "k", false, "Enable Kustomize processing")
 	validateCmd.Flags().StringVarP(&validateNamespace, "namespace", "n", "", "Filter by namespace")
+	validateCmd.Flags().StringSliceVar(&validateInclude, "include", nil, "Glob patterns of manifest files to parse (default *.yaml,*.yml)")
+	validateCmd.Flags().StringSliceVar(&validateExclude, "exclude", nil, "Glob patterns of files or directories to skip")
+	validateCmd.Flags().BoolVar(&validateStrict, "strict", false, "Fail if any YAML document cannot be parsed instead of skipping it")
+	validateCmd.Flags().StringVar(&validateRulesFile, "rules", "", "YAML file with additional reference rules")
+	validateCmd.Flags().BoolVarP(&validateVerbose, "verbose", "v", false, "Report YAML documents ignored because they are not Kubernetes manifests")
 
 	rootCmd.AddCommand(convertCmd)
 	rootCmd.AddCommand(validateCmd)
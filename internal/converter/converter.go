@@ -1,13 +1,20 @@
 package converter
 
 import (
+	"bufio"
 	"fmt"
+	"io"
 	"os"
-	"strings"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
 
 	"k8s-to-drawio/internal/drawio"
 	"k8s-to-drawio/internal/k8s"
 	"k8s-to-drawio/internal/kustomize"
+	"k8s-to-drawio/internal/output"
+	_ "k8s-to-drawio/internal/output/all" // register every output format
 	"k8s-to-drawio/pkg/models"
 )
 
@@ -18,6 +25,22 @@ type Config struct {
 	Namespace    string
 	Layout       string
 	NoNamespaces bool
+	Include      []string
+	Exclude      []string
+	Strict       bool
+	RulesFile    string
+	Timestamp    string   // modification time written to the diagram, RFC 3339 or Unix seconds
+	UpdateFile   string   // existing diagram whose manual layout and user cells are kept
+	MarkDeleted  bool     // mark cells of deleted resources in UpdateFile instead of removing them
+	MultiPage    bool     // write an overview page and one page per namespace
+	Icons        string   // icon set, "default" or "kubernetes"
+	Theme        string   // built-in theme name or theme file
+	Compressed   bool     // write compressed pages
+	Annotations  []string // patterns of annotations included in node properties, defaults to DefaultAnnotations
+	Legend       bool     // add a legend of the shapes and connection styles used
+	Layers       bool     // put resources and connections on one layer per category
+	Format       string   // registered output format, detected from the OutputFile extension when empty
+	Verbose      bool     // report the documents skipped because they are not manifests
 }
 
 type Converter struct {
@@ -31,20 +54,15 @@ func New(config Config) *Converter {
 }
 
 func (c *Converter) Convert() error {
-	// Parse Kubernetes resources
-	var collection *models.ResourceCollection
-	var err error
-
-	if c.config.UseKustomize {
-		processor := kustomize.NewProcessor(c.config.Namespace)
-		collection, err = processor.Process(c.config.InputDir)
-	} else {
-		parser := k8s.NewParser(c.config.Namespace)
-		collection, err = parser.ParseDirectory(c.config.InputDir)
+	format, err := c.outputFormat()
+	if err != nil {
+		return err
 	}
 
+	// Parse Kubernetes resources
+	collection, err := c.parse()
 	if err != nil {
-		return fmt.Errorf("failed to parse resources: %w", err)
+		return err
 	}
 
 	// Validate resources
@@ -59,47 +77,219 @@ func (c *Converter) Convert() error {
 		return fmt.Errorf("failed to convert to diagram: %w", err)
 	}
 
-	// Generate Draw.io XML
-	generator := drawio.NewGenerator(c.config.Layout, c.config.NoNamespaces)
-	xml, err := generator.Generate(diagram)
+	modified, err := c.modifiedTime()
+	if err != nil {
+		return err
+	}
+	generator, err := format.New(c.outputOptions(modified))
 	if err != nil {
-		return fmt.Errorf("failed to generate Draw.io XML: %w", err)
+		return err
 	}
 
-	// Write to file
-	if err := os.WriteFile(c.config.OutputFile, []byte(xml), 0644); err != nil {
+	// Stream the output to the file
+	err = writeFile(c.config.OutputFile, func(w io.Writer) error {
+		if err := generator.Generate(w, diagram); err != nil {
+			return fmt.Errorf("failed to generate %s output: %w", format.Name, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Successfully converted %d resources to %s%s\n", len(collection.Resources), c.config.OutputFile, skippedSuffix(collection))
+	return nil
+}
+
+// outputFormat returns the configured output format, or else the format registered
+// for the extension of the output file, defaulting to Draw.io
+func (c *Converter) outputFormat() (output.Format, error) {
+	name := c.config.Format
+	if name == "" {
+		name = drawio.FormatDrawio
+		if format, exists := output.Detect(c.config.OutputFile); exists {
+			name = format.Name
+		}
+	}
+
+	format, err := output.Lookup(name)
+	if err != nil {
+		return output.Format{}, err
+	}
+	for _, option := range c.formatOptions() {
+		if option.set && !format.Supports(option.feature) {
+			return output.Format{}, fmt.Errorf("%s is not supported for the %s format", option.flag, format.Name)
+		}
+	}
+	return format, nil
+}
+
+// formatOption is a setting that only applies to the formats supporting its feature
+type formatOption struct {
+	flag    string
+	feature output.Feature
+	set     bool
+}
+
+// formatOptions returns the settings that depend on the output format, by command
+// line flag, in the order they are checked
+func (c *Converter) formatOptions() []formatOption {
+	return []formatOption{
+		{"--update", output.FeatureUpdate, c.config.UpdateFile != ""},
+		{"--mark-deleted", output.FeatureUpdate, c.config.MarkDeleted},
+		{"--pages", output.FeatureMultiPage, c.config.MultiPage},
+		{"--icons", output.FeatureIcons, c.config.Icons != "" && c.config.Icons != drawio.IconsDefault},
+		{"--theme", output.FeatureTheme, c.config.Theme != "" && c.config.Theme != drawio.ThemeDefault},
+		{"--compress", output.FeatureCompressed, c.config.Compressed},
+		{"--legend", output.FeatureLegend, c.config.Legend},
+		{"--layers", output.FeatureLayers, c.config.Layers},
+	}
+}
+
+// outputOptions returns the settings passed to the generator of the output format
+func (c *Converter) outputOptions(modified time.Time) output.Options {
+	return output.Options{
+		Layout:       c.config.Layout,
+		NoNamespaces: c.config.NoNamespaces,
+		Modified:     modified,
+		UpdateFile:   c.config.UpdateFile,
+		MarkDeleted:  c.config.MarkDeleted,
+		MultiPage:    c.config.MultiPage,
+		Icons:        c.config.Icons,
+		Theme:        c.config.Theme,
+		Compressed:   c.config.Compressed,
+		Legend:       c.config.Legend,
+		Layers:       c.config.Layers,
+	}
+}
+
+// writeFile streams the output of write to a temporary file that replaces filename
+// once complete, so a failed conversion leaves an existing file untouched
+func writeFile(filename string, write func(io.Writer) error) error {
+	file, err := os.CreateTemp(filepath.Dir(filename), "."+filepath.Base(filename)+".*.tmp")
+	if err != nil {
 		return fmt.Errorf("failed to write output file: %w", err)
 	}
+	defer os.Remove(file.Name())
 
-	fmt.Printf("Successfully converted %d resources to %s\n", len(collection.Resources), c.config.OutputFile)
+	buffered := bufio.NewWriter(file)
+	if err := write(buffered); err != nil {
+		file.Close()
+		return err
+	}
+	if err := buffered.Flush(); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write output file: %w", err)
+	}
+	if err := file.Chmod(0644); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write output file: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to write output file: %w", err)
+	}
+	if err := os.Rename(file.Name(), filename); err != nil {
+		return fmt.Errorf("failed to write output file: %w", err)
+	}
 	return nil
 }
 
 func (c *Converter) Validate() error {
 	// Parse Kubernetes resources
+	collection, err := c.parse()
+	if err != nil {
+		return err
+	}
+
+	// Validate resources
+	validator := k8s.NewValidator()
+	if err := validator.Validate(collection); err != nil {
+		return fmt.Errorf("validation failed: %w", err)
+	}
+
+	fmt.Printf("Successfully validated %d resources%s\n", len(collection.Resources), skippedSuffix(collection))
+	return nil
+}
+
+// parse reads the resources from the input directory and reports skipped documents
+func (c *Converter) parse() (*models.ResourceCollection, error) {
 	var collection *models.ResourceCollection
-	var err error
+
+	opts, err := c.parserOptions()
+	if err != nil {
+		return nil, err
+	}
 
 	if c.config.UseKustomize {
-		processor := kustomize.NewProcessor(c.config.Namespace)
+		processor := kustomize.NewProcessor(opts)
 		collection, err = processor.Process(c.config.InputDir)
 	} else {
-		parser := k8s.NewParser(c.config.Namespace)
+		parser := k8s.NewParserWithOptions(opts)
 		collection, err = parser.ParseDirectory(c.config.InputDir)
 	}
 
 	if err != nil {
-		return fmt.Errorf("failed to parse resources: %w", err)
+		return nil, fmt.Errorf("failed to parse resources: %w", err)
 	}
 
-	// Validate resources
-	validator := k8s.NewValidator()
-	if err := validator.Validate(collection); err != nil {
-		return fmt.Errorf("validation failed: %w", err)
+	for _, diagnostic := range collection.Diagnostics {
+		fmt.Fprintf(os.Stderr, "Warning: skipped %v\n", diagnostic)
+	}
+	if c.config.Verbose {
+		for _, skipped := range collection.Skipped {
+			fmt.Fprintf(os.Stderr, "Debug: ignored %v\n", skipped)
+		}
 	}
 
-	fmt.Printf("Successfully validated %d resources\n", len(collection.Resources))
-	return nil
+	return collection, nil
+}
+
+// modifiedTime returns the modification time to record in the diagram. It is taken
+// from the Timestamp option or else from SOURCE_DATE_EPOCH; without either, no time
+// is recorded so identical input produces identical output.
+func (c *Converter) modifiedTime() (time.Time, error) {
+	value, source := c.config.Timestamp, "timestamp"
+	if value == "" {
+		value, source = os.Getenv("SOURCE_DATE_EPOCH"), "SOURCE_DATE_EPOCH"
+	}
+	if value == "" {
+		return time.Time{}, nil
+	}
+
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Unix(seconds, 0).UTC(), nil
+	}
+	modified, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid %s %q: expected RFC 3339 or Unix seconds", source, value)
+	}
+	return modified.UTC(), nil
+}
+
+func (c *Converter) parserOptions() (k8s.ParserOptions, error) {
+	opts := k8s.ParserOptions{
+		Namespace: c.config.Namespace,
+		Include:   c.config.Include,
+		Exclude:   c.config.Exclude,
+		Strict:    c.config.Strict,
+	}
+
+	if c.config.RulesFile != "" {
+		rules, err := k8s.LoadRules(c.config.RulesFile)
+		if err != nil {
+			return opts, err
+		}
+		opts.Rules = rules
+	}
+
+	return opts, nil
+}
+
+func skippedSuffix(collection *models.ResourceCollection) string {
+	if len(collection.Diagnostics) == 0 {
+		return ""
+	}
+	return fmt.Sprintf(" (%d document(s) skipped, see warnings above)", len(collection.Diagnostics))
 }
 
 func (c *Converter) convertToDiagram(collection *models.ResourceCollection) (*models.Diagram, error) {
@@ -113,167 +303,81 @@ func (c *Converter) convertToDiagram(collection *models.ResourceCollection) (*mo
 	// Debug: Print dependencies
 	// fmt.Printf("Dependencies found: %+v\n", collection.Dependencies)
 
-	// Create nodes for each resource (excluding Namespace resources which are represented as containers)
-	nodeIndex := 0
+	// resource identity -> node ID, so references resolve within the right namespace
+	nodeMap := make(map[string]string)
+
+	// Create nodes for each resource (excluding Namespace resources which are represented as containers).
+	// Node IDs are derived from the resource identity so they stay stable across runs.
+	sources := make(map[string]string)
 	for _, resource := range collection.Resources {
-		// Skip Namespace and Kustomization resources as they should be containers/metadata, not nodes
-		if resource.Kind == "Namespace" || resource.Kind == "Kustomization" {
+		// Skip Namespace resources as they are represented as containers, not nodes
+		if resource.Kind == "Namespace" {
+			continue
+		}
+
+		id := k8s.ResourceIdentity(resource)
+		key := id.String()
+		if _, exists := nodeMap[key]; exists {
+			fmt.Fprintf(os.Stderr, "Warning: duplicate resource %s in %s, already defined in %s\n", key, resource.SourceFile, sources[key])
 			continue
 		}
 
 		node := models.DiagramNode{
-			ID:        fmt.Sprintf("node-%d", nodeIndex),
-			Label:     resource.Name,
-			Kind:      resource.Kind,
-			Namespace: resource.Namespace,
-			X:         0, // Will be set by layout algorithm
-			Y:         0, // Will be set by layout algorithm
-			Width:     120,
-			Height:    60,
+			ID:         NodeID(id),
+			Label:      resource.Name,
+			Kind:       resource.Kind,
+			Namespace:  resource.Namespace,
+			Labels:     resource.Labels,
+			Properties: c.nodeProperties(resource),
+			X:          0, // Will be set by layout algorithm
+			Y:          0, // Will be set by layout algorithm
+			Width:      120,
+			Height:     60,
 		}
 		diagram.Nodes = append(diagram.Nodes, node)
-		nodeIndex++
+		nodeMap[key] = node.ID
+		sources[key] = resource.SourceFile
 	}
 
 	// Collect all virtual Vault secrets referenced in dependencies
-	virtualVaultSecrets := make(map[string]bool)
-	for _, dependencies := range collection.Dependencies {
-		for _, depName := range dependencies {
-			if strings.HasPrefix(depName, "vault-secret-") {
-				virtualVaultSecrets[depName] = true
-			}
+	virtualVaultSecrets := make(map[string]models.ResourceID)
+	for _, dep := range collection.Dependencies {
+		if dep.Target.Kind == "VaultSecret" {
+			virtualVaultSecrets[dep.Target.String()] = dep.Target
 		}
 	}
 
-	// Create virtual nodes for Vault secrets
-	// nodeIndex is already set to the count of non-Namespace resources
-	for vaultSecretName := range virtualVaultSecrets {
-		// Extract the original path from the virtual name
-		// "vault-secret-myapp-config" -> "secret/myapp/config"
-		originalPath := strings.TrimPrefix(vaultSecretName, "vault-secret-")
-		originalPath = strings.ReplaceAll(originalPath, "-", "/")
-		// Re-add the "secret/" prefix if it doesn't already exist
-		if !strings.HasPrefix(originalPath, "secret/") {
-			originalPath = "secret/" + originalPath
-		}
+	vaultSecretKeys := make([]string, 0, len(virtualVaultSecrets))
+	for key := range virtualVaultSecrets {
+		vaultSecretKeys = append(vaultSecretKeys, key)
+	}
+	sort.Strings(vaultSecretKeys)
 
+	// Create virtual nodes for Vault secrets, labelled with their Vault path.
+	// Their identity is derived from the Vault path, so the IDs are stable as well.
+	for _, key := range vaultSecretKeys {
+		vaultSecret := virtualVaultSecrets[key]
 		node := models.DiagramNode{
-			ID:        fmt.Sprintf("node-%d", nodeIndex),
-			Label:     originalPath,
+			ID:        NodeID(vaultSecret),
+			Label:     vaultSecret.Name,
 			Kind:      "VaultSecret",
-			Namespace: "vaultstore",
-			X:         0, // Will be set by layout algorithm
-			Y:         0, // Will be set by layout algorithm
-			Width:     140,
-			Height:    80,
+			Namespace: vaultSecret.Namespace,
+			Properties: []models.Property{
+				{Name: "kind", Value: "VaultSecret"},
+				{Name: "path", Value: vaultSecret.Name},
+				{Name: "namespace", Value: vaultSecret.Namespace},
+			},
+			X:      0, // Will be set by layout algorithm
+			Y:      0, // Will be set by layout algorithm
+			Width:  140,
+			Height: 80,
 		}
 		diagram.Nodes = append(diagram.Nodes, node)
-		nodeIndex++
+		nodeMap[key] = node.ID
 	}
 
 	// Create connections based on dependencies
-	nodeMap := make(map[string]string)     // resource name -> node ID
-	resourceMap := make(map[string]string) // "kind/name" -> node ID
-
-	// Map actual Kubernetes resources (excluding Namespace resources)
-	nodeIndex = 0
-	for _, resource := range collection.Resources {
-		// Skip Namespace and Kustomization resources as they are not represented as nodes
-		if resource.Kind == "Namespace" || resource.Kind == "Kustomization" {
-			continue
-		}
-
-		nodeID := fmt.Sprintf("node-%d", nodeIndex)
-		// Use composite key to avoid conflicts between resources with same name
-		compositeKey := fmt.Sprintf("%s/%s", resource.Kind, resource.Name)
-		// For nodeMap, prioritize workload resources (Deployment, StatefulSet, DaemonSet) over Services
-		// This ensures Service selectors resolve to the correct workload resources
-		if _, exists := nodeMap[resource.Name]; exists {
-			// If name collision, check if current resource should take priority
-			if resource.Kind == "Deployment" || resource.Kind == "StatefulSet" || resource.Kind == "DaemonSet" {
-				nodeMap[resource.Name] = nodeID // Override with workload resource
-			}
-			// If existing resource was a workload and current is Service, keep existing
-		} else {
-			nodeMap[resource.Name] = nodeID
-		}
-		resourceMap[compositeKey] = nodeID
-		nodeIndex++
-	}
-
-	// Map virtual Vault secrets
-	// nodeIndex is already correctly positioned after non-Namespace resources
-	vaultNodeIndex := nodeIndex // VaultSecret nodes start after regular resources
-	for vaultSecretName := range virtualVaultSecrets {
-		nodeID := fmt.Sprintf("node-%d", vaultNodeIndex)
-		nodeMap[vaultSecretName] = nodeID
-		resourceMap[fmt.Sprintf("VaultSecret/%s", vaultSecretName)] = nodeID
-		// fmt.Printf("Mapping VaultSecret: %s -> %s\n", vaultSecretName, nodeID)
-		vaultNodeIndex++
-	}
-
-	for resourceKey, dependencies := range collection.Dependencies {
-		targetID, targetExists := resourceMap[resourceKey]
-		if !targetExists {
-			continue
-		}
-
-		for _, depName := range dependencies {
-			// First check if this dependency should be resolved to a specific resource type
-			var sourceID string
-			var sourceExists bool
-
-			// Special handling for Route, ServiceMonitor, and Ingress dependencies
-			resourceParts := strings.Split(resourceKey, "/")
-			if len(resourceParts) == 2 {
-				resourceKind := resourceParts[0]
-				if resourceKind == "Route" {
-					// Routes should connect to Services
-					serviceKey := fmt.Sprintf("Service/%s", depName)
-					if serviceNodeID, exists := resourceMap[serviceKey]; exists {
-						sourceID = serviceNodeID
-						sourceExists = true
-					}
-				} else if resourceKind == "ServiceMonitor" {
-					// ServiceMonitors should connect to Services
-					serviceKey := fmt.Sprintf("Service/%s", depName)
-					if serviceNodeID, exists := resourceMap[serviceKey]; exists {
-						sourceID = serviceNodeID
-						sourceExists = true
-					}
-				} else if resourceKind == "Ingress" {
-					// Ingress should connect to Services
-					serviceKey := fmt.Sprintf("Service/%s", depName)
-					if serviceNodeID, exists := resourceMap[serviceKey]; exists {
-						sourceID = serviceNodeID
-						sourceExists = true
-					}
-				}
-			}
-
-			// Fall back to name-based lookup if specific type lookup failed
-			if !sourceExists {
-				sourceID, sourceExists = nodeMap[depName]
-			}
-
-			if !sourceExists {
-				continue
-			}
-
-			// Avoid self-references by checking if source and target are the same
-			if sourceID == targetID {
-				continue
-			}
-
-			connection := models.Connection{
-				SourceID: targetID,
-				TargetID: sourceID,
-				Label:    "uses",
-				Style:    "default",
-			}
-			diagram.Connections = append(diagram.Connections, connection)
-		}
-	}
+	diagram.Connections = NewMapper().MapDependenciesToConnections(collection.Dependencies, nodeMap)
 
 	return diagram, nil
 }
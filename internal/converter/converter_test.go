@@ -0,0 +1,249 @@
+package converter
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"k8s-to-drawio/internal/k8s"
+	"k8s-to-drawio/internal/output"
+	"k8s-to-drawio/pkg/models"
+)
+
+func TestOutputFormat(t *testing.T) {
+	tests := []struct {
+		name   string
+		config Config
+		want   string // format name, empty if rejected
+		err    string
+	}{
+		{
+			name:   "default",
+			config: Config{OutputFile: "out/diagram"},
+			want:   "drawio",
+		},
+		{
+			name:   "detected from the extension",
+			config: Config{OutputFile: "diagram.MMD", Theme: "default", Icons: "default"},
+			want:   "mermaid",
+		},
+		{
+			name:   "format overrides the extension",
+			config: Config{OutputFile: "diagram.svg", Format: "dot"},
+			want:   "dot",
+		},
+		{
+			name:   "unknown format",
+			config: Config{OutputFile: "diagram.drawio", Format: "png"},
+			err:    `unknown output format "png", expected one of dot, drawio, html, json, mermaid, svg`,
+		},
+		{
+			name:   "Draw.io options",
+			config: Config{OutputFile: "diagram.drawio", UpdateFile: "diagram.drawio", MarkDeleted: true, Theme: "dark", Icons: "kubernetes", Compressed: true, Legend: true, Layers: true},
+			want:   "drawio",
+		},
+		{
+			name:   "HTML pages",
+			config: Config{OutputFile: "diagram.html", MultiPage: true, Theme: "dark", Legend: true, Layers: true},
+			want:   "html",
+		},
+		{
+			name:   "HTML update",
+			config: Config{OutputFile: "diagram.html", UpdateFile: "diagram.drawio"},
+			err:    "--update is not supported for the html format",
+		},
+		{
+			name:   "mermaid theme",
+			config: Config{OutputFile: "diagram.mmd", Theme: "dark"},
+			err:    "--theme is not supported for the mermaid format",
+		},
+		{
+			name:   "dot icons",
+			config: Config{OutputFile: "diagram.dot", Icons: "kubernetes"},
+			err:    "--icons is not supported for the dot format",
+		},
+		{
+			name:   "svg legend",
+			config: Config{OutputFile: "diagram.svg", Legend: true},
+			err:    "--legend is not supported for the svg format",
+		},
+		{
+			name:   "svg layers",
+			config: Config{OutputFile: "diagram.svg", Layers: true},
+			err:    "--layers is not supported for the svg format",
+		},
+		{
+			name:   "json pages",
+			config: Config{OutputFile: "diagram.json", MultiPage: true},
+			err:    "--pages is not supported for the json format",
+		},
+		{
+			name:   "json compress",
+			config: Config{OutputFile: "diagram.json", Compressed: true},
+			err:    "--compress is not supported for the json format",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			format, err := New(tt.config).outputFormat()
+			if tt.err != "" {
+				if err == nil || err.Error() != tt.err {
+					t.Errorf("error = %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("outputFormat: %v", err)
+			}
+			if format.Name != tt.want {
+				t.Errorf("format = %s, want %s", format.Name, tt.want)
+			}
+		})
+	}
+}
+
+func TestConvertResolvesNamespaces(t *testing.T) {
+	diagram := convertManifests(t, `
+apiVersion: v1
+kind: ConfigMap
+metadata: {name: config, namespace: shop}
+---
+apiVersion: v1
+kind: ConfigMap
+metadata: {name: config}
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata: {name: web, namespace: shop}
+spec:
+  template:
+    spec:
+      volumes:
+      - {name: config, configMap: {name: config}}
+---
+apiVersion: extensions/v1beta1
+kind: Deployment
+metadata: {name: web, namespace: shop}
+`)
+
+	// The legacy Deployment is the same resource as the apps/v1 one
+	if len(diagram.Nodes) != 3 {
+		t.Fatalf("got %d nodes, want 3", len(diagram.Nodes))
+	}
+	shopConfig := NodeID(models.ResourceID{Kind: "ConfigMap", Namespace: "shop", Name: "config"})
+	web := NodeID(models.ResourceID{Group: "apps", Kind: "Deployment", Namespace: "shop", Name: "web"})
+	if len(diagram.Connections) != 1 {
+		t.Fatalf("got connections %+v, want 1", diagram.Connections)
+	}
+	if connection := diagram.Connections[0]; connection.SourceID != web || connection.TargetID != shopConfig {
+		t.Errorf("connection %s -> %s, want %s -> %s", connection.SourceID, connection.TargetID, web, shopConfig)
+	}
+}
+
+// convertManifests converts multi-document YAML to a diagram without layout
+func convertManifests(t *testing.T, manifests string) *models.Diagram {
+	t.Helper()
+
+	collection, err := k8s.NewParser("").ParseBuildOutput("fixture", []byte(manifests))
+	if err != nil {
+		t.Fatalf("ParseBuildOutput: %v", err)
+	}
+	diagram, err := New(Config{}).convertToDiagram(collection)
+	if err != nil {
+		t.Fatalf("convertToDiagram: %v", err)
+	}
+	return diagram
+}
+
+func TestModifiedTime(t *testing.T) {
+	tests := []struct {
+		name       string
+		timestamp  string
+		sourceDate string
+		want       string // RFC 3339, empty for no time
+		err        bool
+	}{
+		{name: "none"},
+		{name: "Unix seconds", timestamp: "1700000000", want: "2023-11-14T22:13:20Z"},
+		{name: "RFC 3339", timestamp: "2024-03-01T12:00:00+02:00", want: "2024-03-01T10:00:00Z"},
+		{name: "SOURCE_DATE_EPOCH", sourceDate: "1700000000", want: "2023-11-14T22:13:20Z"},
+		{name: "timestamp overrides SOURCE_DATE_EPOCH", timestamp: "0", sourceDate: "1700000000", want: "1970-01-01T00:00:00Z"},
+		{name: "invalid timestamp", timestamp: "yesterday", err: true},
+		{name: "invalid SOURCE_DATE_EPOCH", sourceDate: "2024-03-01", err: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("SOURCE_DATE_EPOCH", tt.sourceDate)
+
+			modified, err := New(Config{Timestamp: tt.timestamp}).modifiedTime()
+			if tt.err {
+				if err == nil {
+					t.Errorf("modifiedTime() = %v, want an error", modified)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("modifiedTime: %v", err)
+			}
+
+			got := ""
+			if !modified.IsZero() {
+				got = modified.Format(time.RFC3339)
+			}
+			if got != tt.want {
+				t.Errorf("modifiedTime() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConvertDeterministic(t *testing.T) {
+	t.Setenv("SOURCE_DATE_EPOCH", "")
+
+	for _, format := range output.Names() {
+		t.Run(format, func(t *testing.T) {
+			dir := t.TempDir()
+			var outputs [][]byte
+			for i := 0; i < 2; i++ {
+				filename := filepath.Join(dir, fmt.Sprintf("diagram-%d", i))
+				config := Config{InputDir: "../../examples/complex-microservices", OutputFile: filename, Format: format, Layout: "hierarchical"}
+				if err := New(config).Convert(); err != nil {
+					t.Fatalf("Convert: %v", err)
+				}
+				content, err := os.ReadFile(filename)
+				if err != nil {
+					t.Fatal(err)
+				}
+				outputs = append(outputs, content)
+			}
+
+			if !bytes.Equal(outputs[0], outputs[1]) {
+				t.Error("converting the same input twice gives different output")
+			}
+			if bytes.Contains(outputs[0], []byte("modified=")) {
+				t.Error("output records a modification time without a timestamp")
+			}
+		})
+	}
+}
+
+func TestConvertTimestamp(t *testing.T) {
+	t.Setenv("SOURCE_DATE_EPOCH", "1700000000")
+
+	filename := filepath.Join(t.TempDir(), "diagram.drawio")
+	if err := New(Config{InputDir: "../../examples/simple-app", OutputFile: filename, Layout: "hierarchical"}).Convert(); err != nil {
+		t.Fatalf("Convert: %v", err)
+	}
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `modified="2023-11-14T22:13:20.000Z"`; !bytes.Contains(content, []byte(want)) {
+		t.Errorf("output does not contain %s", want)
+	}
+}
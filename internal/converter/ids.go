@@ -0,0 +1,48 @@
+package converter
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+
+	"k8s-to-drawio/pkg/models"
+)
+
+// NodeID derives the cell ID of a resource from its identity, so a resource keeps
+// its ID across runs no matter which other manifests are added or removed. The ID
+// is a readable kind-namespace-name slug followed by a short hash of the full
+// identity, which keeps resources apart that only differ in API group or in
+// characters dropped from the slug.
+func NodeID(id models.ResourceID) string {
+	parts := []string{id.Kind}
+	if id.Namespace != "" {
+		parts = append(parts, id.Namespace)
+	}
+	parts = append(parts, id.Name)
+	return slug(strings.Join(parts, "-")) + "-" + shortHash(id.String())
+}
+
+// ConnectionID derives the cell ID of a connection from its endpoints and relation
+func ConnectionID(sourceID, targetID string, relation models.Relation) string {
+	return "edge-" + shortHash(sourceID+"|"+targetID+"|"+string(relation))
+}
+
+func shortHash(value string) string {
+	sum := sha256.Sum256([]byte(value))
+	return hex.EncodeToString(sum[:4])
+}
+
+// slug lowercases value and replaces everything but letters, digits, dots and
+// dashes with a dash
+func slug(value string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(value) {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '.', r == '-':
+			b.WriteRune(r)
+		default:
+			b.WriteRune('-')
+		}
+	}
+	return strings.Trim(b.String(), "-")
+}
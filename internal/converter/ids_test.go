@@ -0,0 +1,90 @@
+package converter
+
+import (
+	"testing"
+
+	"k8s-to-drawio/pkg/models"
+)
+
+// The IDs below are stored in every diagram written so far. Updating a diagram
+// matches cells by ID, so if these change every existing diagram loses its layout.
+
+func TestNodeID(t *testing.T) {
+	tests := []struct {
+		id   models.ResourceID
+		want string
+	}{
+		{
+			id:   models.ResourceID{Group: "apps", Kind: "Deployment", Namespace: "shop", Name: "web"},
+			want: "deployment-shop-web-f9619453",
+		},
+		{
+			id:   models.ResourceID{Group: "rbac.authorization.k8s.io", Kind: "ClusterRole", Name: "view"},
+			want: "clusterrole-view-a5ca6fd5",
+		},
+		{
+			id:   models.ResourceID{Kind: "Secret", Namespace: "default", Name: "My_Secret:v1"},
+			want: "secret-default-my-secret-v1-f99d386b",
+		},
+		{
+			id:   models.ResourceID{Kind: "VaultSecret", Namespace: "vaultstore", Name: "secret/data/app"},
+			want: "vaultsecret-vaultstore-secret-data-app-61bca63b",
+		},
+		{
+			id:   models.ResourceID{Group: "networking.k8s.io", Kind: "Ingress", Namespace: "shop", Name: "web"},
+			want: "ingress-shop-web-72e1d89c",
+		},
+		{
+			id:   models.ResourceID{Group: "example.com", Kind: "Ingress", Namespace: "shop", Name: "web"},
+			want: "ingress-shop-web-0a79754c",
+		},
+	}
+
+	for _, tt := range tests {
+		if got := NodeID(tt.id); got != tt.want {
+			t.Errorf("NodeID(%v) = %q, want %q", tt.id, got, tt.want)
+		}
+	}
+}
+
+func TestConnectionID(t *testing.T) {
+	const (
+		deployment = "deployment-shop-web-f9619453"
+		secret     = "secret-default-my-secret-v1-f99d386b"
+	)
+
+	tests := []struct {
+		source, target string
+		relation       models.Relation
+		want           string
+	}{
+		{deployment, secret, models.RelationMounts, "edge-ef8daa7a"},
+		{secret, deployment, models.RelationMounts, "edge-f9132cf7"},
+		{deployment, secret, models.RelationEnvFrom, "edge-bd5121b0"},
+	}
+
+	for _, tt := range tests {
+		if got := ConnectionID(tt.source, tt.target, tt.relation); got != tt.want {
+			t.Errorf("ConnectionID(%q, %q, %q) = %q, want %q", tt.source, tt.target, tt.relation, got, tt.want)
+		}
+	}
+}
+
+func TestSlug(t *testing.T) {
+	tests := map[string]string{
+		"Deployment-shop-web":  "deployment-shop-web",
+		"my.app-v1.2":          "my.app-v1.2",
+		"secret/data/app":      "secret-data-app",
+		"My_Secret:v1":         "my-secret-v1",
+		"--leading/trailing--": "leading-trailing",
+		"a  b":                 "a--b",
+		"grüße":                "gr--e",
+		"":                     "",
+	}
+
+	for value, want := range tests {
+		if got := slug(value); got != want {
+			t.Errorf("slug(%q) = %q, want %q", value, got, want)
+		}
+	}
+}
@@ -1,6 +1,8 @@
 package converter
 
 import (
+	"fmt"
+
 	"k8s-to-drawio/pkg/models"
 )
 
@@ -24,29 +26,38 @@ func (m *Mapper) MapResourceToDiagramNode(resource models.K8sResource, nodeID st
 	}
 }
 
-func (m *Mapper) MapDependenciesToConnections(dependencies map[string][]string, nodeMap map[string]string) []models.Connection {
-	var connections []models.Connection
+// MapDependenciesToConnections maps dependencies to connections labelled by their relation.
+// nodeMap maps resource identities to node IDs; dependencies on resources without a node
+// and self-references are dropped. Repeated references with the same relation share one
+// connection, which is only optional if all of them are.
+func (m *Mapper) MapDependenciesToConnections(dependencies []models.Dependency, nodeMap map[string]string) []models.Connection {
+	connections := make([]models.Connection, 0)
+	seen := make(map[string]int)
 
-	for resourceName, deps := range dependencies {
-		sourceID, sourceExists := nodeMap[resourceName]
-		if !sourceExists {
+	for _, dep := range dependencies {
+		sourceID, sourceExists := nodeMap[dep.Source.String()]
+		targetID, targetExists := nodeMap[dep.Target.String()]
+		if !sourceExists || !targetExists || sourceID == targetID {
 			continue
 		}
 
-		for _, depName := range deps {
-			targetID, targetExists := nodeMap[depName]
-			if !targetExists {
-				continue
-			}
+		key := fmt.Sprintf("%s|%s|%s", sourceID, targetID, dep.Relation)
+		if i, exists := seen[key]; exists {
+			connections[i].Optional = connections[i].Optional && dep.Optional
+			continue
+		}
+		seen[key] = len(connections)
 
-			connection := models.Connection{
-				SourceID: sourceID,
-				TargetID: targetID,
-				Label:    m.getLabelForConnection(resourceName, depName),
-				Style:    "default",
-			}
-			connections = append(connections, connection)
+		connection := models.Connection{
+			ID:       ConnectionID(sourceID, targetID, dep.Relation),
+			SourceID: sourceID,
+			TargetID: targetID,
+			Label:    m.getLabelForConnection(dep),
+			Style:    string(dep.Relation),
+			Relation: dep.Relation,
+			Optional: dep.Optional,
 		}
+		connections = append(connections, connection)
 	}
 
 	return connections
@@ -69,6 +80,9 @@ func (m *Mapper) getStyleForKind(kind string) string {
 	}
 }
 
-func (m *Mapper) getLabelForConnection(source, target string) string {
-	return "uses"
+func (m *Mapper) getLabelForConnection(dep models.Dependency) string {
+	if dep.Relation == "" {
+		return "uses"
+	}
+	return string(dep.Relation)
 }
@@ -0,0 +1,47 @@
+package converter
+
+import (
+	"reflect"
+	"testing"
+
+	"k8s-to-drawio/pkg/models"
+)
+
+func TestMapDependenciesToConnections(t *testing.T) {
+	web := models.ResourceID{Group: "apps", Kind: "Deployment", Namespace: "shop", Name: "web"}
+	config := models.ResourceID{Kind: "ConfigMap", Namespace: "shop", Name: "config"}
+	creds := models.ResourceID{Kind: "Secret", Namespace: "shop", Name: "creds"}
+	missing := models.ResourceID{Kind: "Secret", Namespace: "shop", Name: "missing"}
+	nodeMap := map[string]string{
+		web.String():    "web",
+		config.String(): "config",
+		creds.String():  "creds",
+	}
+
+	dependencies := []models.Dependency{
+		{Source: web, Target: config, Relation: models.RelationMounts, Optional: true},
+		{Source: web, Target: config, Relation: models.RelationMounts},
+		{Source: web, Target: config, Relation: models.RelationEnvFrom, Optional: true},
+		{Source: web, Target: creds, Relation: models.RelationEnvFrom, Optional: true},
+		{Source: web, Target: creds, Relation: models.RelationEnvFrom, Optional: true},
+		{Source: web, Target: missing, Relation: models.RelationMounts},
+		{Source: web, Target: web, Relation: models.RelationSelects},
+		{Source: config, Target: creds},
+	}
+
+	// Repeated references share a connection that is optional only if all of them are,
+	// references to missing resources and self-references are dropped
+	got := NewMapper().MapDependenciesToConnections(dependencies, nodeMap)
+	want := []models.Connection{
+		{SourceID: "web", TargetID: "config", Label: "mounts", Style: "mounts", Relation: models.RelationMounts},
+		{SourceID: "web", TargetID: "config", Label: "envFrom", Style: "envFrom", Relation: models.RelationEnvFrom, Optional: true},
+		{SourceID: "web", TargetID: "creds", Label: "envFrom", Style: "envFrom", Relation: models.RelationEnvFrom, Optional: true},
+		{SourceID: "config", TargetID: "creds", Label: "uses"},
+	}
+	for i := range want {
+		want[i].ID = ConnectionID(want[i].SourceID, want[i].TargetID, want[i].Relation)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("connections:\n  %+v\nwant:\n  %+v", got, want)
+	}
+}
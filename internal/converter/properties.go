@@ -0,0 +1,195 @@
+package converter
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"k8s-to-drawio/internal/k8s"
+	"k8s-to-drawio/pkg/models"
+)
+
+// lastAppliedAnnotation holds the complete manifest applied by kubectl, including the
+// data of Secrets, so it is never included in node properties
+const lastAppliedAnnotation = "kubectl.kubernetes.io/last-applied-configuration"
+
+// DefaultAnnotations are the patterns of annotations included in node properties
+// when Config.Annotations is empty
+var DefaultAnnotations = []string{"description", "*/description", "vault.security.banzaicloud.io/*"}
+
+// replicaKinds lists the kinds with a spec.replicas field
+var replicaKinds = map[string]bool{
+	"Deployment":  true,
+	"StatefulSet": true,
+	"ReplicaSet":  true,
+}
+
+// nodeProperties returns the properties of a resource written to its cell: namespace,
+// labels, selected annotations, replicas, container images, ports and source file.
+// Only metadata and specs are read, so values of Secrets and ConfigMaps are never included.
+func (c *Converter) nodeProperties(resource models.K8sResource) []models.Property {
+	var properties []models.Property
+	add := func(name, value string) {
+		if value != "" {
+			properties = append(properties, models.Property{Name: name, Value: value})
+		}
+	}
+
+	add("kind", resource.Kind)
+	add("name", resource.Name)
+	// Namespaced resources without a namespace are created in "default", cluster-scoped
+	// resources have none
+	add("namespace", k8s.ResourceIdentity(resource).Namespace)
+	add("labels", formatMap(resource.Labels, nil))
+
+	obj, _ := resource.Object.(*unstructured.Unstructured)
+	add("annotations", formatMap(annotations(resource, obj), c.annotationSelected))
+
+	if obj != nil {
+		if replicaKinds[resource.Kind] {
+			if replicas, found, _ := unstructured.NestedInt64(obj.Object, "spec", "replicas"); found {
+				add("replicas", fmt.Sprint(replicas))
+			}
+		}
+		images, ports := containerImagesAndPorts(resource.Kind, obj)
+		add("images", strings.Join(images, ", "))
+		if resource.Kind == "Service" {
+			ports = servicePorts(obj)
+		}
+		add("ports", strings.Join(ports, ", "))
+	}
+
+	add("source", c.sourcePath(resource.SourceFile))
+	return properties
+}
+
+// annotations returns the annotations of a resource merged with those of its pod
+// template, where annotations such as Bank-Vaults' are usually set
+func annotations(resource models.K8sResource, obj *unstructured.Unstructured) map[string]string {
+	metadataPath, hasTemplate := k8s.PodTemplateMetadataPaths[resource.Kind]
+	if !hasTemplate || obj == nil {
+		return resource.Annotations
+	}
+
+	podAnnotations, _, _ := unstructured.NestedStringMap(obj.Object, append(append([]string(nil), metadataPath...), "annotations")...)
+	if len(podAnnotations) == 0 {
+		return resource.Annotations
+	}
+	merged := make(map[string]string, len(resource.Annotations)+len(podAnnotations))
+	for key, value := range podAnnotations {
+		merged[key] = value
+	}
+	for key, value := range resource.Annotations {
+		merged[key] = value
+	}
+	return merged
+}
+
+// annotationSelected reports whether an annotation matches the configured patterns
+func (c *Converter) annotationSelected(key string) bool {
+	if key == lastAppliedAnnotation {
+		return false
+	}
+
+	patterns := c.config.Annotations
+	if len(patterns) == 0 {
+		patterns = DefaultAnnotations
+	}
+	for _, pattern := range patterns {
+		if matched, _ := path.Match(pattern, key); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// sourcePath returns the manifest path relative to the input directory, so properties
+// do not depend on where the tool runs
+func (c *Converter) sourcePath(filename string) string {
+	if filename == "" {
+		return ""
+	}
+	if rel, err := filepath.Rel(c.config.InputDir, filename); err == nil && !strings.HasPrefix(rel, "..") {
+		filename = rel
+	}
+	return filepath.ToSlash(filename)
+}
+
+// containerImagesAndPorts returns the distinct images and the ports of all containers
+// of a pod-bearing resource
+func containerImagesAndPorts(kind string, obj *unstructured.Unstructured) ([]string, []string) {
+	specPath, podBearing := k8s.PodSpecPaths[kind]
+	if !podBearing {
+		return nil, nil
+	}
+
+	var images, ports []string
+	seen := make(map[string]bool)
+	for _, field := range k8s.ContainerFields {
+		containers, _, _ := unstructured.NestedSlice(obj.Object, append(append([]string(nil), specPath...), field)...)
+		for _, container := range containers {
+			container, ok := container.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			if image, _, _ := unstructured.NestedString(container, "image"); image != "" && !seen[image] {
+				seen[image] = true
+				images = append(images, image)
+			}
+			containerPorts, _, _ := unstructured.NestedSlice(container, "ports")
+			for _, port := range containerPorts {
+				if port, ok := port.(map[string]interface{}); ok {
+					ports = append(ports, formatPort(port, "containerPort", ""))
+				}
+			}
+		}
+	}
+	return images, ports
+}
+
+// servicePorts returns the ports of a Service with their target ports
+func servicePorts(obj *unstructured.Unstructured) []string {
+	var ports []string
+	servicePorts, _, _ := unstructured.NestedSlice(obj.Object, "spec", "ports")
+	for _, port := range servicePorts {
+		if port, ok := port.(map[string]interface{}); ok {
+			ports = append(ports, formatPort(port, "port", "targetPort"))
+		}
+	}
+	return ports
+}
+
+// formatPort formats a port as "name 80->8080/TCP", omitting the name and target if not set
+func formatPort(port map[string]interface{}, numberField, targetField string) string {
+	protocol, _, _ := unstructured.NestedString(port, "protocol")
+	if protocol == "" {
+		protocol = "TCP"
+	}
+
+	formatted := fmt.Sprintf("%v", port[numberField])
+	if target, exists := port[targetField]; exists && targetField != "" {
+		formatted += fmt.Sprintf("->%v", target)
+	}
+	formatted += "/" + protocol
+
+	if name, _, _ := unstructured.NestedString(port, "name"); name != "" {
+		formatted = name + " " + formatted
+	}
+	return formatted
+}
+
+// formatMap formats the entries of a map selected by include as sorted key=value lines
+func formatMap(values map[string]string, include func(string) bool) string {
+	var lines []string
+	for key, value := range values {
+		if include == nil || include(key) {
+			lines = append(lines, key+"="+value)
+		}
+	}
+	sort.Strings(lines)
+	return strings.Join(lines, "\n")
+}
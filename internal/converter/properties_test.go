@@ -0,0 +1,182 @@
+package converter
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/yaml"
+
+	"k8s-to-drawio/pkg/models"
+)
+
+func TestContainerImagesAndPorts(t *testing.T) {
+	obj := testObject(t, `
+apiVersion: batch/v1
+kind: CronJob
+metadata: {name: backup, namespace: shop}
+spec:
+  jobTemplate:
+    spec:
+      template:
+        spec:
+          initContainers:
+          - {name: wait, image: busybox:1.36}
+          containers:
+          - name: backup
+            image: shop/backup:2.0
+            ports: [{name: metrics, containerPort: 9090}]
+          - name: sidecar
+            image: busybox:1.36
+            ports: [{containerPort: 53, protocol: UDP}]
+          ephemeralContainers:
+          - {name: shell, image: nicolaka/netshoot}
+`)
+
+	images, ports := containerImagesAndPorts("CronJob", obj)
+	if want := []string{"busybox:1.36", "shop/backup:2.0", "nicolaka/netshoot"}; !reflect.DeepEqual(images, want) {
+		t.Errorf("images = %v, want %v", images, want)
+	}
+	if want := []string{"metrics 9090/TCP", "53/UDP"}; !reflect.DeepEqual(ports, want) {
+		t.Errorf("ports = %v, want %v", ports, want)
+	}
+
+	if images, ports := containerImagesAndPorts("Service", obj); images != nil || ports != nil {
+		t.Errorf("kind without pods has images %v and ports %v", images, ports)
+	}
+}
+
+func TestNodeProperties(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   Config
+		manifest string
+		want     []models.Property
+	}{
+		{
+			name:   "workload",
+			config: Config{InputDir: "manifests"},
+			manifest: `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+  namespace: shop
+  labels: {app: web, tier: frontend}
+  annotations:
+    description: Storefront
+    kubectl.kubernetes.io/last-applied-configuration: "{}"
+    deployment.kubernetes.io/revision: "3"
+spec:
+  replicas: 3
+  template:
+    metadata:
+      annotations:
+        vault.security.banzaicloud.io/vault-role: web
+        description: overridden by the resource annotation
+    spec:
+      containers:
+      - {name: app, image: shop/web:1.2, ports: [{name: http, containerPort: 8080}]}
+`,
+			want: []models.Property{
+				{Name: "kind", Value: "Deployment"},
+				{Name: "name", Value: "web"},
+				{Name: "namespace", Value: "shop"},
+				{Name: "labels", Value: "app=web\ntier=frontend"},
+				{Name: "annotations", Value: "description=Storefront\nvault.security.banzaicloud.io/vault-role=web"},
+				{Name: "replicas", Value: "3"},
+				{Name: "images", Value: "shop/web:1.2"},
+				{Name: "ports", Value: "http 8080/TCP"},
+				{Name: "source", Value: "apps/web.yaml"},
+			},
+		},
+		{
+			name: "namespace defaults to default",
+			manifest: `
+apiVersion: v1
+kind: Secret
+metadata: {name: creds}
+data: {password: c2VjcmV0}
+`,
+			want: []models.Property{
+				{Name: "kind", Value: "Secret"},
+				{Name: "name", Value: "creds"},
+				{Name: "namespace", Value: "default"},
+				{Name: "source", Value: "manifests/apps/web.yaml"},
+			},
+		},
+		{
+			name:   "cluster-scoped resource",
+			config: Config{InputDir: "manifests"},
+			manifest: `
+apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRole
+metadata: {name: view, namespace: shop}
+`,
+			want: []models.Property{
+				{Name: "kind", Value: "ClusterRole"},
+				{Name: "name", Value: "view"},
+				{Name: "source", Value: "apps/web.yaml"},
+			},
+		},
+		{
+			name:   "service with configured annotations",
+			config: Config{InputDir: "manifests", Annotations: []string{"example.com/*"}},
+			manifest: `
+apiVersion: v1
+kind: Service
+metadata:
+  name: web
+  namespace: shop
+  annotations: {description: Storefront, example.com/owner: team-a}
+spec:
+  ports:
+  - {name: http, port: 80, targetPort: 8080}
+  - {port: 53, protocol: UDP}
+`,
+			want: []models.Property{
+				{Name: "kind", Value: "Service"},
+				{Name: "name", Value: "web"},
+				{Name: "namespace", Value: "shop"},
+				{Name: "annotations", Value: "example.com/owner=team-a"},
+				{Name: "ports", Value: "http 80->8080/TCP, 53/UDP"},
+				{Name: "source", Value: "apps/web.yaml"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			obj := testObject(t, tt.manifest)
+			resource := models.K8sResource{
+				Object:      obj,
+				Kind:        obj.GetKind(),
+				Name:        obj.GetName(),
+				Namespace:   obj.GetNamespace(),
+				Labels:      obj.GetLabels(),
+				Annotations: obj.GetAnnotations(),
+				SourceFile:  filepath.Join("manifests", "apps", "web.yaml"),
+			}
+
+			if got := New(tt.config).nodeProperties(resource); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("properties:\n  %v\nwant:\n  %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// testObject decodes a YAML manifest like the parser does
+func testObject(t *testing.T, manifest string) *unstructured.Unstructured {
+	t.Helper()
+
+	data, err := yaml.YAMLToJSON([]byte(manifest))
+	if err != nil {
+		t.Fatalf("invalid YAML: %v", err)
+	}
+	obj := &unstructured.Unstructured{}
+	if err := obj.UnmarshalJSON(data); err != nil {
+		t.Fatalf("invalid manifest: %v", err)
+	}
+	return obj
+}
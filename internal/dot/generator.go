@@ -0,0 +1,195 @@
+package dot
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"k8s-to-drawio/internal/drawio"
+	"k8s-to-drawio/internal/output"
+	"k8s-to-drawio/pkg/models"
+)
+
+// NodeStyle is the Graphviz shape and colours of a resource node
+type NodeStyle struct {
+	Shape     string
+	Style     string
+	FillColor string
+	Color     string
+}
+
+// EdgeStyle is the Graphviz style of a connection
+type EdgeStyle struct {
+	Color     string
+	Style     string
+	PenWidth  string
+	ArrowHead string
+}
+
+// Shapes contains the Graphviz node shapes for the Draw.io shapes of the resource
+// styles, see drawio.GetShapeStyle. Other shapes are drawn as boxes, rounded like
+// in Draw.io.
+var Shapes = map[string]string{
+	"ellipse":   "ellipse",
+	"rhombus":   "diamond",
+	"note":      "note",
+	"cylinder3": "cylinder",
+	"hexagon":   "hexagon",
+	"trapezoid": "trapezium",
+	"monitor":   "component",
+}
+
+// GetNodeStyle returns the Graphviz style of a resource kind from its Draw.io style
+func GetNodeStyle(kind string) NodeStyle {
+	name, attrs := drawio.ParseStyle(drawio.GetShapeStyle(kind))
+	style := NodeStyle{Shape: "box", Style: "filled", FillColor: attrs["fillColor"], Color: attrs["strokeColor"]}
+	if shape, exists := Shapes[name]; exists {
+		style.Shape = shape
+	} else if attrs["rounded"] == "1" {
+		style.Style = "rounded,filled"
+	}
+	return style
+}
+
+// GetEdgeStyle returns the Graphviz style of a relation from its Draw.io style
+func GetEdgeStyle(relation string) EdgeStyle {
+	return edgeStyle(drawio.GetEdgeStyle(relation))
+}
+
+// edgeStyle converts a Draw.io edge style
+func edgeStyle(drawioStyle string) EdgeStyle {
+	_, attrs := drawio.ParseStyle(drawioStyle)
+	style := EdgeStyle{Color: attrs["strokeColor"], PenWidth: attrs["strokeWidth"]}
+	if style.Color == "" {
+		style.Color = "#000000"
+	}
+	if attrs["dashed"] == "1" {
+		style.Style = "dashed"
+		if strings.HasPrefix(attrs["dashPattern"], "1 ") {
+			style.Style = "dotted"
+		}
+	}
+	switch attrs["endArrow"] {
+	case "open":
+		style.ArrowHead = "vee"
+	case "block":
+		if attrs["endFill"] == "0" {
+			style.ArrowHead = "empty"
+		}
+	}
+	return style
+}
+
+// FormatName is the name of the output format of this package
+const FormatName = "dot"
+
+func init() {
+	output.Register(output.Format{
+		Name:       FormatName,
+		Extensions: []string{".dot", ".gv"},
+		New: func(opts output.Options) (output.Generator, error) {
+			return NewGenerator(Options{NoNamespaces: opts.NoNamespaces}), nil
+		},
+	})
+}
+
+// Options configures the generator
+type Options struct {
+	NoNamespaces bool // do not group resources in namespace clusters
+}
+
+// Generator writes diagrams as Graphviz DOT graphs. Graphviz lays out the graph
+// itself, so the positions of the diagram are not used.
+type Generator struct {
+	noNamespaces bool
+}
+
+// NewGenerator creates a generator with the given options
+func NewGenerator(opts Options) *Generator {
+	return &Generator{noNamespaces: opts.NoNamespaces}
+}
+
+// Generate writes the DOT graph of a diagram: namespaces as cluster subgraphs,
+// resources as nodes styled by kind and connections labelled by relation
+func (g *Generator) Generate(w io.Writer, diagram *models.Diagram) error {
+	b := bufio.NewWriter(w)
+	b.WriteString("digraph kubernetes {\n")
+	b.WriteString("    rankdir=TB;\n")
+	b.WriteString("    fontname=\"Helvetica\";\n")
+	b.WriteString("    node [fontname=\"Helvetica\", fontsize=11, margin=\"0.2,0.1\"];\n")
+	b.WriteString("    edge [fontname=\"Helvetica\", fontsize=9];\n")
+
+	if g.noNamespaces {
+		if len(diagram.Nodes) > 0 {
+			b.WriteString("\n")
+		}
+		for _, node := range diagram.Nodes {
+			b.WriteString("    " + nodeStatement(node) + "\n")
+		}
+	} else {
+		_, namespaceStyle := drawio.ParseStyle(drawio.NamespaceStyle)
+		namespaces, namespaceNodes := models.GroupByNamespace(diagram.Nodes)
+		for _, name := range namespaces {
+			fmt.Fprintf(b, "\n    subgraph %s {\n", quote("cluster_"+name))
+			fmt.Fprintf(b, "        label=%s;\n", quote(models.NamespaceLabel(name)))
+			b.WriteString("        style=\"rounded\";\n")
+			fmt.Fprintf(b, "        color=%s;\n", quote(namespaceStyle["strokeColor"]))
+			for _, i := range namespaceNodes[name] {
+				b.WriteString("        " + nodeStatement(diagram.Nodes[i]) + "\n")
+			}
+			b.WriteString("    }\n")
+		}
+	}
+
+	if len(diagram.Connections) > 0 {
+		b.WriteString("\n")
+	}
+	for _, connection := range diagram.Connections {
+		b.WriteString("    " + edgeStatement(connection) + "\n")
+	}
+
+	b.WriteString("}\n")
+	return b.Flush()
+}
+
+// nodeStatement returns the DOT statement of a resource node
+func nodeStatement(node models.DiagramNode) string {
+	style := GetNodeStyle(node.Kind)
+
+	label := node.Kind + "\n" + node.Label
+	if node.Kind == "VaultSecret" {
+		label = node.Label
+	}
+	return fmt.Sprintf("%s [label=%s, shape=%s, style=%s, fillcolor=%s, color=%s];",
+		quote(node.ID), quote(label), style.Shape, quote(style.Style), quote(style.FillColor), quote(style.Color))
+}
+
+// edgeStatement returns the DOT statement of a connection
+func edgeStatement(connection models.Connection) string {
+	label := connection.Label
+	drawioStyle := drawio.GetEdgeStyle(string(connection.Relation))
+	if connection.Optional {
+		label += " (optional)"
+		drawioStyle += drawio.OptionalEdgeStyle
+	}
+	style := edgeStyle(drawioStyle)
+
+	attrs := []string{"label=" + quote(label), "color=" + quote(style.Color), "fontcolor=" + quote(style.Color)}
+	if style.Style != "" {
+		attrs = append(attrs, "style="+style.Style)
+	}
+	if style.PenWidth != "" {
+		attrs = append(attrs, "penwidth="+style.PenWidth)
+	}
+	if style.ArrowHead != "" {
+		attrs = append(attrs, "arrowhead="+style.ArrowHead)
+	}
+	return fmt.Sprintf("%s -> %s [%s];", quote(connection.SourceID), quote(connection.TargetID), strings.Join(attrs, ", "))
+}
+
+// quote returns a DOT quoted string, newlines become centred line breaks. Graphviz
+// decodes HTML entities in strings, so ampersands are escaped too.
+func quote(text string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "&", "&amp;", "\n", `\n`).Replace(text) + `"`
+}
@@ -0,0 +1,136 @@
+package dot
+
+import (
+	"bytes"
+	"testing"
+
+	"k8s-to-drawio/pkg/models"
+)
+
+const header = `digraph kubernetes {
+    rankdir=TB;
+    fontname="Helvetica";
+    node [fontname="Helvetica", fontsize=11, margin="0.2,0.1"];
+    edge [fontname="Helvetica", fontsize=9];
+`
+
+const (
+	deploymentStatement  = `"deployment-shop-web-f9619453" [label="Deployment\nweb", shape=box, style="rounded,filled", fillcolor="#d5e8d4", color="#82b366"];`
+	configMapStatement   = `"configmap-shop-settings-0a1b2c3d" [label="ConfigMap\nsay \"hi\" <b> &amp; c:\\tmp", shape=note, style="filled", fillcolor="#e1d5e7", color="#9673a6"];`
+	vaultSecretStatement = `"vaultsecret-vaultstore-secret-data-db-61bca63b" [label="secret/data/db", shape=hexagon, style="filled", fillcolor="#ffe6cc", color="#d79b00"];`
+	edgeStatements       = `
+    "deployment-shop-web-f9619453" -> "configmap-shop-settings-0a1b2c3d" [label="mounts", color="#9673a6", fontcolor="#9673a6"];
+    "deployment-shop-web-f9619453" -> "vaultsecret-vaultstore-secret-data-db-61bca63b" [label="a&amp;b (optional)", color="#d79b00", fontcolor="#d79b00", style=dashed, penwidth=2];
+}
+`
+)
+
+func TestGenerate(t *testing.T) {
+	tests := []struct {
+		name string
+		opts Options
+		want string
+	}{
+		{
+			name: "namespace clusters",
+			want: header + `
+    subgraph "cluster_shop" {
+        label="Namespace: shop";
+        style="rounded";
+        color="#9673a6";
+        ` + deploymentStatement + `
+        ` + configMapStatement + `
+    }
+
+    subgraph "cluster_vaultstore" {
+        label="vaultstore";
+        style="rounded";
+        color="#9673a6";
+        ` + vaultSecretStatement + `
+    }
+` + edgeStatements,
+		},
+		{
+			name: "without namespaces",
+			opts: Options{NoNamespaces: true},
+			want: header + `
+    ` + deploymentStatement + `
+    ` + configMapStatement + `
+    ` + vaultSecretStatement + `
+` + edgeStatements,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			if err := NewGenerator(tt.opts).Generate(&out, testDiagram()); err != nil {
+				t.Fatalf("Generate: %v", err)
+			}
+			if got := out.String(); got != tt.want {
+				t.Errorf("output:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGenerateEmpty(t *testing.T) {
+	var out bytes.Buffer
+	if err := NewGenerator(Options{}).Generate(&out, &models.Diagram{}); err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	if got, want := out.String(), header+"}\n"; got != want {
+		t.Errorf("output:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestQuote(t *testing.T) {
+	tests := map[string]string{
+		"plain":          `"plain"`,
+		`a "quoted" b`:   `"a \"quoted\" b"`,
+		`c:\tmp`:         `"c:\\tmp"`,
+		"<b> & &lt;":     `"<b> &amp; &amp;lt;"`,
+		"two\nlines":     `"two\nlines"`,
+		"Namespace: a-b": `"Namespace: a-b"`,
+	}
+
+	for text, want := range tests {
+		if got := quote(text); got != want {
+			t.Errorf("quote(%q) = %s, want %s", text, got, want)
+		}
+	}
+}
+
+func TestEdgeStyle(t *testing.T) {
+	tests := []struct {
+		drawioStyle string
+		want        EdgeStyle
+	}{
+		{"endArrow=classic;", EdgeStyle{Color: "#000000"}},
+		{"strokeColor=#6c8ebf;dashed=1;endArrow=open;", EdgeStyle{Color: "#6c8ebf", Style: "dashed", ArrowHead: "vee"}},
+		{"dashed=1;dashPattern=1 4;strokeWidth=2;", EdgeStyle{Color: "#000000", Style: "dotted", PenWidth: "2"}},
+		{"endArrow=block;endFill=0;", EdgeStyle{Color: "#000000", ArrowHead: "empty"}},
+		{"endArrow=block;", EdgeStyle{Color: "#000000"}},
+	}
+
+	for _, tt := range tests {
+		if got := edgeStyle(tt.drawioStyle); got != tt.want {
+			t.Errorf("edgeStyle(%q) = %+v, want %+v", tt.drawioStyle, got, tt.want)
+		}
+	}
+}
+
+// testDiagram returns two namespaces, a label to escape and an optional connection
+func testDiagram() *models.Diagram {
+	return &models.Diagram{
+		Nodes: []models.DiagramNode{
+			{ID: "deployment-shop-web-f9619453", Kind: "Deployment", Label: "web", Namespace: "shop"},
+			{ID: "configmap-shop-settings-0a1b2c3d", Kind: "ConfigMap", Label: `say "hi" <b> & c:\tmp`, Namespace: "shop"},
+			{ID: "vaultsecret-vaultstore-secret-data-db-61bca63b", Kind: "VaultSecret", Label: "secret/data/db", Namespace: models.VaultSecretNamespace},
+		},
+		Connections: []models.Connection{
+			{SourceID: "deployment-shop-web-f9619453", TargetID: "configmap-shop-settings-0a1b2c3d", Label: "mounts", Relation: models.RelationMounts},
+			{SourceID: "deployment-shop-web-f9619453", TargetID: "vaultsecret-vaultstore-secret-data-db-61bca63b", Label: "a&b", Relation: models.RelationInjectsVaultSecret, Optional: true},
+		},
+	}
+}
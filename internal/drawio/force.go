@@ -0,0 +1,208 @@
+package drawio
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+
+	"k8s-to-drawio/pkg/models"
+)
+
+const (
+	forceSeed          = 1     // seed of the initial positions, so runs are reproducible
+	forceIterations    = 400   // simulation steps
+	forceIdealDistance = 220.0 // preferred distance between the centres of connected nodes
+	forceGravity       = 0.05  // pull towards the centre of the bounds, keeps components together
+	forceOverlapPasses = 50    // passes separating nodes that still overlap after the simulation
+)
+
+// forceLayout places nodes with a seeded Fruchterman-Reingold simulation: all nodes
+// repel each other, connected nodes attract each other, so tightly coupled resources
+// end up in clusters. Nodes are kept inside square bounds sized for their number,
+// and only connections between the given nodes are taken into account, so every
+// namespace stays within its own container.
+func forceLayout(nodeIndices []int, diagram *models.Diagram) (map[int]point, float64, float64) {
+	n := len(nodeIndices)
+	if n == 0 {
+		return nil, 0, 0
+	}
+
+	// Seed positions in node ID order, so the result does not depend on input order
+	order := append([]int(nil), nodeIndices...)
+	sort.Slice(order, func(a, b int) bool {
+		return diagram.Nodes[order[a]].ID < diagram.Nodes[order[b]].ID
+	})
+
+	vertexOf := make(map[string]int, n)
+	for v, idx := range order {
+		vertexOf[diagram.Nodes[idx].ID] = v
+	}
+
+	var edges [][2]int
+	seen := make(map[[2]int]bool)
+	for _, connection := range diagram.Connections {
+		source, sourceFound := vertexOf[connection.SourceID]
+		target, targetFound := vertexOf[connection.TargetID]
+		if !sourceFound || !targetFound || source == target {
+			continue
+		}
+		if source > target {
+			source, target = target, source
+		}
+		edge := [2]int{source, target}
+		if !seen[edge] {
+			seen[edge] = true
+			edges = append(edges, edge)
+		}
+	}
+
+	w := make([]float64, n)
+	h := make([]float64, n)
+	maxWidth, maxHeight := 0.0, 0.0
+	for v, idx := range order {
+		w[v], h[v] = diagram.Nodes[idx].Width, diagram.Nodes[idx].Height
+		maxWidth = math.Max(maxWidth, w[v])
+		maxHeight = math.Max(maxHeight, h[v])
+	}
+
+	// Node centres are kept within [minCentre, side-minCentre] in both directions,
+	// using the largest node so every node fits
+	side := forceIdealDistance * math.Ceil(math.Sqrt(float64(n))) * 1.2
+	if side < maxWidth {
+		side = maxWidth
+	}
+	if side < maxHeight {
+		side = maxHeight
+	}
+	minX, maxX := maxWidth/2, side-maxWidth/2
+	minY, maxY := maxHeight/2, side-maxHeight/2
+
+	random := rand.New(rand.NewSource(forceSeed))
+	x := make([]float64, n)
+	y := make([]float64, n)
+	for v := range order {
+		x[v] = minX + random.Float64()*(maxX-minX)
+		y[v] = minY + random.Float64()*(maxY-minY)
+	}
+
+	k := forceIdealDistance
+	dx := make([]float64, n)
+	dy := make([]float64, n)
+	for iteration := 0; iteration < forceIterations; iteration++ {
+		temperature := side / 10 * (1 - float64(iteration)/forceIterations)
+
+		for v := range dx {
+			dx[v] = forceGravity * (side/2 - x[v])
+			dy[v] = forceGravity * (side/2 - y[v])
+		}
+
+		// Repulsion between every pair of nodes
+		for v := 0; v < n; v++ {
+			for w := v + 1; w < n; w++ {
+				ddx, ddy, distance := separation(x[v], y[v], x[w], y[w], v, w)
+				force := k * k / distance
+				dx[v] += ddx / distance * force
+				dy[v] += ddy / distance * force
+				dx[w] -= ddx / distance * force
+				dy[w] -= ddy / distance * force
+			}
+		}
+
+		// Attraction along connections
+		for _, edge := range edges {
+			v, w := edge[0], edge[1]
+			ddx, ddy, distance := separation(x[v], y[v], x[w], y[w], v, w)
+			force := distance * distance / k
+			dx[v] -= ddx / distance * force
+			dy[v] -= ddy / distance * force
+			dx[w] += ddx / distance * force
+			dy[w] += ddy / distance * force
+		}
+
+		// Move every node by at most the temperature and keep it within bounds
+		for v := range x {
+			length := math.Hypot(dx[v], dy[v])
+			if length == 0 {
+				continue
+			}
+			step := math.Min(length, temperature)
+			x[v] = clamp(x[v]+dx[v]/length*step, minX, maxX)
+			y[v] = clamp(y[v]+dy[v]/length*step, minY, maxY)
+		}
+	}
+
+	removeOverlaps(x, y, w, h, minX, maxX, minY, maxY)
+
+	// Shift the nodes to the top left corner and report the used bounds
+	left, top := math.Inf(1), math.Inf(1)
+	for v := range x {
+		left = math.Min(left, x[v]-w[v]/2)
+		top = math.Min(top, y[v]-h[v]/2)
+	}
+
+	positions := make(map[int]point, n)
+	width, height := 0.0, 0.0
+	for v, idx := range order {
+		p := point{
+			x: math.Round(x[v] - w[v]/2 - left),
+			y: math.Round(y[v] - h[v]/2 - top),
+		}
+		positions[idx] = p
+		width = math.Max(width, p.x+w[v])
+		height = math.Max(height, p.y+h[v])
+	}
+
+	return positions, width, height
+}
+
+// separation returns the vector from w to v and its length. Nodes at the same
+// position are separated in a direction derived from their vertex numbers.
+func separation(xv, yv, xw, yw float64, v, w int) (float64, float64, float64) {
+	ddx, ddy := xv-xw, yv-yw
+	distance := math.Hypot(ddx, ddy)
+	if distance < 0.01 {
+		angle := float64(v*7+w*13) * 0.618
+		ddx, ddy, distance = math.Cos(angle)*0.01, math.Sin(angle)*0.01, 0.01
+	}
+	return ddx, ddy, distance
+}
+
+// removeOverlaps pushes overlapping nodes of the given widths and heights apart
+// along the axis of least overlap, leaving a small gap between them
+func removeOverlaps(x, y, widths, heights []float64, minX, maxX, minY, maxY float64) {
+	const gap = 20.0
+
+	for pass := 0; pass < forceOverlapPasses; pass++ {
+		moved := false
+		for v := range x {
+			for w := v + 1; w < len(x); w++ {
+				ddx, ddy, _ := separation(x[v], y[v], x[w], y[w], v, w)
+				spanX := (widths[v] + widths[w]) / 2
+				spanY := (heights[v] + heights[w]) / 2
+				overlapX := spanX + gap - math.Abs(ddx)
+				overlapY := spanY + gap - math.Abs(ddy)
+				if overlapX <= 0 || overlapY <= 0 {
+					continue
+				}
+
+				moved = true
+				if overlapX/spanX < overlapY/spanY {
+					shift := math.Copysign(overlapX/2, ddx)
+					x[v] = clamp(x[v]+shift, minX, maxX)
+					x[w] = clamp(x[w]-shift, minX, maxX)
+				} else {
+					shift := math.Copysign(overlapY/2, ddy)
+					y[v] = clamp(y[v]+shift, minY, maxY)
+					y[w] = clamp(y[w]-shift, minY, maxY)
+				}
+			}
+		}
+		if !moved {
+			return
+		}
+	}
+}
+
+func clamp(value, min, max float64) float64 {
+	return math.Max(min, math.Min(max, value))
+}
@@ -0,0 +1,89 @@
+package drawio
+
+import (
+	"fmt"
+	"testing"
+
+	"k8s-to-drawio/pkg/models"
+)
+
+func TestForceLayoutDeterministic(t *testing.T) {
+	first := shopDiagram()
+	if err := NewLayout("force", false).ApplyLayout(first); err != nil {
+		t.Fatalf("ApplyLayout: %v", err)
+	}
+
+	second := shopDiagram()
+	if err := NewLayout("force", false).ApplyLayout(second); err != nil {
+		t.Fatalf("ApplyLayout: %v", err)
+	}
+	comparePositions(t, first, second)
+
+	// Positions are seeded in node ID order, so the input order does not matter
+	reversed := shopDiagram()
+	reverseNodes(reversed)
+	if err := NewLayout("force", false).ApplyLayout(reversed); err != nil {
+		t.Fatalf("ApplyLayout: %v", err)
+	}
+	comparePositions(t, first, reversed)
+}
+
+func TestForceLayoutNoOverlap(t *testing.T) {
+	for _, noNamespaces := range []bool{false, true} {
+		t.Run(fmt.Sprintf("noNamespaces=%v", noNamespaces), func(t *testing.T) {
+			diagram := shopDiagram()
+			layout := NewLayout("force", noNamespaces)
+			layout.nodeSize = variedNodeSize
+			if err := layout.ApplyLayout(diagram); err != nil {
+				t.Fatalf("ApplyLayout: %v", err)
+			}
+
+			checkFinite(t, diagram)
+			checkNoOverlap(t, diagram)
+			checkWithinNamespaces(t, diagram)
+		})
+	}
+
+	// A hub of large nodes the simulation alone leaves overlapping
+	t.Run("hub", func(t *testing.T) {
+		var nodes []models.DiagramNode
+		var connections []string
+		for i := 0; i < 30; i++ {
+			nodes = append(nodes, testNode("default", "Secret", fmt.Sprintf("s%02d", i)))
+			connections = append(connections, "default-Secret-s00", fmt.Sprintf("default-Secret-s%02d", i))
+		}
+
+		diagram := testDiagram(nodes, connections...)
+		layout := NewLayout("force", false)
+		layout.nodeSize = func(models.DiagramNode) (float64, float64) { return 200, 120 }
+		if err := layout.ApplyLayout(diagram); err != nil {
+			t.Fatalf("ApplyLayout: %v", err)
+		}
+
+		checkNoOverlap(t, diagram)
+		checkWithinNamespaces(t, diagram)
+	})
+}
+
+func TestForceLayoutCycle(t *testing.T) {
+	// A ring of Secrets with a chord and a self-reference
+	var nodes []models.DiagramNode
+	var connections []string
+	for i := 0; i < 12; i++ {
+		nodes = append(nodes, testNode("default", "Secret", fmt.Sprintf("s%02d", i)))
+		connections = append(connections,
+			fmt.Sprintf("default-Secret-s%02d", i), fmt.Sprintf("default-Secret-s%02d", (i+1)%12))
+	}
+	connections = append(connections,
+		"default-Secret-s00", "default-Secret-s06",
+		"default-Secret-s03", "default-Secret-s03")
+
+	diagram := testDiagram(nodes, connections...)
+	if err := NewLayout("force", false).ApplyLayout(diagram); err != nil {
+		t.Fatalf("ApplyLayout: %v", err)
+	}
+
+	checkFinite(t, diagram)
+	checkNoOverlap(t, diagram)
+	checkWithinNamespaces(t, diagram)
+}
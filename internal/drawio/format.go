@@ -0,0 +1,74 @@
+package drawio
+
+import (
+	"fmt"
+
+	"k8s-to-drawio/internal/output"
+)
+
+// Names of the output formats of this package
+const (
+	FormatDrawio = "drawio"
+	FormatHTML   = "html"
+)
+
+// htmlFeatures are the optional settings of Draw.io files that apply to HTML pages too
+const htmlFeatures = output.FeatureMultiPage | output.FeatureIcons | output.FeatureTheme |
+	output.FeatureCompressed | output.FeatureLegend | output.FeatureLayers
+
+func init() {
+	output.Register(output.Format{
+		Name:       FormatDrawio,
+		Extensions: []string{".drawio"},
+		Features:   output.FeatureUpdate | htmlFeatures,
+		New: func(opts output.Options) (output.Generator, error) {
+			return newGenerator(opts)
+		},
+	})
+	output.Register(output.Format{
+		Name:       FormatHTML,
+		Extensions: []string{".html", ".htm"},
+		Features:   htmlFeatures,
+		New: func(opts output.Options) (output.Generator, error) {
+			generator, err := newGenerator(opts)
+			if err != nil {
+				return nil, err
+			}
+			return NewHTMLGenerator(generator), nil
+		},
+	})
+}
+
+// newGenerator creates a generator from the conversion settings, reading the theme
+// and the diagram being updated
+func newGenerator(opts output.Options) (*Generator, error) {
+	var existing *ExistingDiagram
+	if opts.UpdateFile != "" {
+		if opts.MultiPage {
+			return nil, fmt.Errorf("updating multi-page diagrams is not supported")
+		}
+		var err error
+		if existing, err = ReadDiagramFile(opts.UpdateFile); err != nil {
+			return nil, err
+		}
+	}
+
+	theme, err := LoadTheme(opts.Theme)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewGeneratorWithOptions(Options{
+		Layout:       opts.Layout,
+		NoNamespaces: opts.NoNamespaces,
+		Modified:     opts.Modified,
+		Existing:     existing,
+		MarkDeleted:  opts.MarkDeleted,
+		MultiPage:    opts.MultiPage,
+		Icons:        opts.Icons,
+		Theme:        theme,
+		Compressed:   opts.Compressed,
+		Legend:       opts.Legend,
+		Layers:       opts.Layers,
+	}), nil
+}
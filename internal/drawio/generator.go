@@ -1,107 +1,328 @@
 package drawio
 
 import (
+	"encoding/xml"
 	"fmt"
+	"io"
 	"k8s-to-drawio/pkg/models"
-	"strings"
+	"sort"
+	"time"
 )
 
+// Options configures the generator
+type Options struct {
+	Layout       string
+	NoNamespaces bool
+	Modified     time.Time        // written to the mxfile header, omitted when zero
+	Existing     *ExistingDiagram // diagram being updated, its geometry and user cells are kept
+	MarkDeleted  bool             // keep cells of deleted resources of Existing, marked with DeletedStyle
+	MultiPage    bool             // write an overview page and one page per namespace
+	Icons        string           // IconsDefault or IconsKubernetes, defaults to IconsDefault
+	Theme        *Theme           // styles, node sizes and labels, defaults to the built-in styles
+	Compressed   bool             // write pages compressed like Draw.io does by default
+	Legend       bool             // add a legend of the shapes and connection styles used
+	Layers       bool             // put resources and connections on one layer per category, see Layers
+}
+
 type Generator struct {
 	layout       *Layout
 	noNamespaces bool
+	modified     time.Time
+	existing     *ExistingDiagram
+	markDeleted  bool
+	multiPage    bool
+	icons        string
+	theme        *Theme
+	compressed   bool
+	legend       bool
+	layers       bool
 }
 
 func NewGenerator(layoutAlgorithm string, noNamespaces bool) *Generator {
+	return NewGeneratorWithOptions(Options{Layout: layoutAlgorithm, NoNamespaces: noNamespaces})
+}
+
+// NewGeneratorWithOptions creates a generator with the given options
+func NewGeneratorWithOptions(opts Options) *Generator {
+	if opts.Icons == "" {
+		opts.Icons = IconsDefault
+	}
+	if opts.Theme == nil {
+		opts.Theme = &Theme{Name: ThemeDefault}
+	}
+	layout := NewLayout(opts.Layout, opts.NoNamespaces)
+	layout.nodeSize = opts.Theme.nodeSize
 	return &Generator{
-		layout:       NewLayout(layoutAlgorithm, noNamespaces),
-		noNamespaces: noNamespaces,
+		layout:       layout,
+		noNamespaces: opts.NoNamespaces,
+		modified:     opts.Modified,
+		existing:     opts.Existing,
+		markDeleted:  opts.MarkDeleted,
+		multiPage:    opts.MultiPage,
+		icons:        opts.Icons,
+		theme:        opts.Theme,
+		compressed:   opts.Compressed,
+		legend:       opts.Legend,
+		layers:       opts.Layers,
 	}
 }
 
-func (g *Generator) Generate(diagram *models.Diagram) (string, error) {
+// Generate writes a diagram as a Draw.io file
+func (g *Generator) Generate(w io.Writer, diagram *models.Diagram) error {
+	if g.icons != IconsDefault && g.icons != IconsKubernetes {
+		return fmt.Errorf("unknown icon set %q, expected %s or %s", g.icons, IconsDefault, IconsKubernetes)
+	}
+	if err := g.theme.compile(); err != nil {
+		return fmt.Errorf("invalid theme %s: %w", g.theme.Name, err)
+	}
+
+	if g.multiPage {
+		pages, err := g.generatePages(diagram)
+		if err != nil {
+			return err
+		}
+		return g.encode(w, pages)
+	}
+
 	// Apply layout
 	if err := g.layout.ApplyLayout(diagram); err != nil {
-		return "", fmt.Errorf("failed to apply layout: %w", err)
-	}
-
-	var xmlParts []string
-
-	// Start XML document
-	xmlParts = append(xmlParts, `<?xml version="1.0" encoding="UTF-8"?>`)
-	xmlParts = append(xmlParts, `<mxfile host="Electron" modified="2023-01-01T00:00:00.000Z" agent="k8s-to-drawio" version="1.0.0" etag="k8s-diagram" type="device">`)
-	xmlParts = append(xmlParts, `  <diagram id="k8s-diagram" name="Kubernetes Architecture">`)
-	xmlParts = append(xmlParts, `    <mxGraphModel dx="1422" dy="794" grid="1" gridSize="10" guides="1" tooltips="1" connect="1" arrows="1" fold="1" page="1" pageScale="1" pageWidth="827" pageHeight="1169" math="0" shadow="0">`)
-	xmlParts = append(xmlParts, `      <root>`)
-	xmlParts = append(xmlParts, `        <mxCell id="0"/>`)
-	xmlParts = append(xmlParts, `        <mxCell id="1" parent="0"/>`)
-
-	// Generate namespace groups
-	for _, namespace := range diagram.Namespaces {
-		// Format namespace label based on namespace name
-		var namespaceLabel string
-		if namespace.Name == "vaultstore" {
-			// For vaultstore namespace, just show the name without "Namespace:" prefix
-			namespaceLabel = namespace.Name
-		} else {
-			// For other namespaces, show "Namespace: name" format
-			namespaceLabel = fmt.Sprintf("Namespace: %s", EscapeXML(namespace.Name))
-		}
+		return fmt.Errorf("failed to apply layout: %w", err)
+	}
+	g.fitNodesToShapes(diagram)
+	if g.existing != nil {
+		g.mergeExisting(diagram)
+	}
 
-		namespaceXML := FormatNamespaceGroup(
-			fmt.Sprintf("ns-%s", namespace.Name),
-			namespaceLabel,
-			namespace.X,
-			namespace.Y,
-			namespace.Width,
-			namespace.Height,
-		)
-		xmlParts = append(xmlParts, "        "+namespaceXML)
+	cells, err := g.cells(diagram)
+	if err != nil {
+		return err
 	}
+	return g.encode(w, []page{{id: "k8s-diagram", name: "Kubernetes Architecture", cells: cells}})
+}
+
+// fitNodesToShapes shrinks laid out nodes to the size of their shape. Icons are
+// centred at the top of the space the layout reserved, leaving room for the label.
+func (g *Generator) fitNodesToShapes(diagram *models.Diagram) {
+	if g.icons != IconsKubernetes {
+		return
+	}
+	for i := range diagram.Nodes {
+		diagram.Nodes[i].X += (diagram.Nodes[i].Width - iconWidth) / 2
+		diagram.Nodes[i].Width = iconWidth
+		diagram.Nodes[i].Height = iconHeight
+	}
+}
+
+// page is one <diagram> element of the generated file
+type page struct {
+	id    string
+	name  string
+	cells []Cell
+}
+
+// document returns the mxfile with the given pages
+func (g *Generator) document(pages []page) *MxFile {
+	file := NewFile()
+	if !g.modified.IsZero() {
+		file.Modified = g.modified.UTC().Format("2006-01-02T15:04:05.000Z")
+	}
+
+	for _, p := range pages {
+		model := NewGraphModel()
+		model.Background = g.theme.Background
+		model.Root.Cells = append(model.Root.Cells, p.cells...)
+		file.Diagrams = append(file.Diagrams, Diagram{ID: p.id, Name: p.name, Model: model, Compressed: g.compressed})
+	}
+	return file
+}
+
+// encode writes the mxfile with the given pages as XML
+func (g *Generator) encode(w io.Writer, pages []page) error {
+	if err := g.document(pages).Encode(w); err != nil {
+		return fmt.Errorf("failed to encode diagram: %w", err)
+	}
+	return nil
+}
+
+// cells returns the cells of a laid out diagram: layers, namespace groups, nodes and connections
+func (g *Generator) cells(diagram *models.Diagram) ([]Cell, error) {
+	cells := g.layerCells(diagram)
+
+	namespaceCells, containers := g.namespaceCells(diagram)
+	cells = append(cells, namespaceCells...)
 
 	// Generate nodes
 	for _, node := range diagram.Nodes {
-		template := GetShapeTemplate(node.Kind)
+		style, themed := g.nodeStyle(node)
+		_, hasIcon := GetKubernetesIconStyle(node.Kind)
 
 		// Format node label based on kind
 		var nodeLabel string
-		if node.Kind == "VaultSecret" {
-			// For VaultSecret, just show the path since the shape indicates it's a vault secret
+		if node.Kind == "VaultSecret" || (g.icons == IconsKubernetes && hasIcon) {
+			// For VaultSecret and kinds with their own icon, just show the name since the shape indicates the kind
 			nodeLabel = node.Label
 		} else {
 			// For other resources, show both kind and name
 			nodeLabel = fmt.Sprintf("%s\n%s", node.Kind, node.Label)
 		}
+		if themed.label != nil {
+			label, err := render(themed.label, node)
+			if err != nil {
+				return nil, err
+			}
+			nodeLabel = label
+		}
+
+		// Nodes inside a namespace are children of its container with coordinates
+		// relative to it, nodes on a layer are children of the layer
+		parent, x, y := "1", node.X, node.Y
+		if container, exists := containers[node.ID]; exists {
+			parent, x, y = container.id, x-container.x, y-container.y
+		} else if layer := g.nodeLayer(node); layer != "" {
+			parent = layerCellID(layer)
+		}
 
-		nodeXML := FormatShape(
-			template,
+		nodeCell := NewShape(
 			node.ID,
-			EscapeXML(nodeLabel),
-			node.X,
-			node.Y,
+			nodeLabel,
+			style,
+			parent,
+			x,
+			y,
 			node.Width,
 			node.Height,
 		)
-		xmlParts = append(xmlParts, "        "+nodeXML)
+		properties, err := nodeProperties(node, themed)
+		if err != nil {
+			return nil, err
+		}
+		if len(properties) > 0 {
+			nodeCell.Object = "UserObject"
+			nodeCell.Properties = properties
+		}
+		cells = append(cells, nodeCell)
 	}
 
 	// Generate connections
+	nodes := make(map[string]models.DiagramNode, len(diagram.Nodes))
+	for _, node := range diagram.Nodes {
+		nodes[node.ID] = node
+	}
 	for i, connection := range diagram.Connections {
-		connectionID := fmt.Sprintf("conn-%d", i)
-		connectionXML := FormatConnection(
-			connectionID,
-			EscapeXML(connection.Label),
-			connection.SourceID,
-			connection.TargetID,
-			0, 0, 0, 0, // Points will be calculated by Draw.io
-		)
-		xmlParts = append(xmlParts, "        "+connectionXML)
+		connectionID := connection.ID
+		if connectionID == "" {
+			connectionID = fmt.Sprintf("conn-%d", i)
+		}
+		label, style := g.connectionStyle(connection)
+		connectionCell := NewConnection(connectionID, label, style, connection.SourceID, connection.TargetID)
+		if geometry, exists := g.existingGeometry(connectionID); exists {
+			connectionCell = NewConnectionWithGeometry(connectionID, label, style, connection.SourceID, connection.TargetID, geometry)
+		}
+		if layer := g.connectionLayer(connection, nodes); layer != "" {
+			connectionCell.Parent = layerCellID(layer)
+		}
+		cells = append(cells, connectionCell)
 	}
 
-	// End XML document
-	xmlParts = append(xmlParts, `      </root>`)
-	xmlParts = append(xmlParts, `    </mxGraphModel>`)
-	xmlParts = append(xmlParts, `  </diagram>`)
-	xmlParts = append(xmlParts, `</mxfile>`)
+	if g.legend {
+		cells = append(cells, g.legendCells(diagram)...)
+	}
+
+	// Keep user cells and deleted cells of the diagram being updated
+	cells = append(tagGenerated(cells), g.retainedCells(cells)...)
+
+	return cells, nil
+}
+
+// container is a namespace container cell, the coordinates of its child cells are relative to it
+type container struct {
+	id   string
+	x, y float64
+}
+
+// namespaceCells returns the namespace containers of a laid out diagram and the
+// container of every resource nested in one. Containers are on the default layer.
+// Since a cell belongs to the layer of its top-level ancestor, resources on a
+// category layer are not nested but placed over their namespace.
+func (g *Generator) namespaceCells(diagram *models.Diagram) ([]Cell, map[string]container) {
+	nodes := make(map[string]models.DiagramNode, len(diagram.Nodes))
+	for _, node := range diagram.Nodes {
+		nodes[node.ID] = node
+	}
+
+	// Generate namespace groups in a stable order
+	namespaceNames := make([]string, 0, len(diagram.Namespaces))
+	for name := range diagram.Namespaces {
+		namespaceNames = append(namespaceNames, name)
+	}
+	sort.Strings(namespaceNames)
+
+	var cells []Cell
+	containers := make(map[string]container)
+	for _, name := range namespaceNames {
+		namespace := diagram.Namespaces[name]
+		id := namespaceCellID(namespace.Name)
+		cells = append(cells, NewNamespaceGroup(
+			id,
+			models.NamespaceLabel(namespace.Name),
+			mergeStyle(NamespaceStyle, g.theme.Namespace),
+			namespace.X,
+			namespace.Y,
+			namespace.Width,
+			namespace.Height,
+		))
+
+		for _, nodeID := range namespace.NodeIDs {
+			if g.nodeLayer(nodes[nodeID]) == "" {
+				containers[nodeID] = container{id: id, x: namespace.X, y: namespace.Y}
+			}
+		}
+	}
+	return cells, containers
+}
+
+// nodeStyle returns the style of a resource shape and the theme entries applied to it
+func (g *Generator) nodeStyle(node models.DiagramNode) (string, themedNode) {
+	themed := g.theme.node(node)
+	style := GetShapeStyle(node.Kind)
+	if g.icons == IconsKubernetes {
+		style, _ = GetKubernetesIconStyle(node.Kind)
+	}
+	return mergeStyle(style, themed.style), themed
+}
+
+// connectionStyle returns the label and the style of a connection
+func (g *Generator) connectionStyle(connection models.Connection) (string, string) {
+	label := connection.Label
+	style := g.theme.edgeStyle(string(connection.Relation))
+	if connection.Optional {
+		label += " (optional)"
+		style += OptionalEdgeStyle
+	}
+	return label, style
+}
+
+// nodeProperties returns the properties of a resource cell: the node properties
+// followed by the tooltip
+func nodeProperties(node models.DiagramNode, themed themedNode) ([]xml.Attr, error) {
+	var properties []xml.Attr
+	for _, property := range node.Properties {
+		properties = append(properties, xml.Attr{Name: xml.Name{Local: property.Name}, Value: property.Value})
+	}
+
+	if themed.tooltip != nil {
+		tooltip, err := render(themed.tooltip, node)
+		if err != nil {
+			return nil, err
+		}
+		if tooltip != "" {
+			properties = append(properties, xml.Attr{Name: xml.Name{Local: "tooltip"}, Value: tooltip})
+		}
+	}
+	return properties, nil
+}
 
-	return strings.Join(xmlParts, "\n"), nil
+// namespaceCellID returns the ID of the container cell of a namespace
+func namespaceCellID(namespace string) string {
+	return fmt.Sprintf("ns-%s", namespace)
 }
@@ -0,0 +1,150 @@
+package drawio
+
+import (
+	"strings"
+	"testing"
+
+	"k8s-to-drawio/pkg/models"
+)
+
+func TestNamespaceContainers(t *testing.T) {
+	for _, layout := range []string{"hierarchical", "vertical", "force"} {
+		t.Run(layout, func(t *testing.T) {
+			diagram := shopDiagram()
+			diagram.Nodes = append(diagram.Nodes, testNode(models.VaultSecretNamespace, "VaultSecret", "secret/data/db"))
+			byID := cellsByID(generateCells(t, Options{Layout: layout}, diagram))
+
+			for name, namespace := range diagram.Namespaces {
+				cell := byID[namespaceCellID(name)]
+				if cell.Parent != "1" || !strings.HasPrefix(cell.Style, "swimlane;") || cell.Value != models.NamespaceLabel(name) {
+					t.Errorf("namespace %s: parent %s, style %q, label %q", name, cell.Parent, cell.Style, cell.Value)
+				}
+				if bounds := cell.Bounds(); bounds.X != namespace.X || bounds.Y != namespace.Y ||
+					bounds.Width != namespace.Width || bounds.Height != namespace.Height {
+					t.Errorf("namespace %s at %+v, want %+v", name, bounds, namespace)
+				}
+			}
+
+			// Resources are children of their namespace with coordinates relative to it,
+			// so moving the container in Draw.io moves its resources
+			for _, node := range diagram.Nodes {
+				namespace := diagram.Namespaces[node.Namespace]
+				cell := byID[node.ID]
+				bounds := cell.Bounds()
+				if cell.Parent != namespaceCellID(node.Namespace) || bounds.X != node.X-namespace.X || bounds.Y != node.Y-namespace.Y {
+					t.Errorf("%s: parent %s at (%v, %v), want %s at (%v, %v)", node.ID, cell.Parent, bounds.X, bounds.Y,
+						namespaceCellID(node.Namespace), node.X-namespace.X, node.Y-namespace.Y)
+				}
+				if bounds.X < 0 || bounds.Y < 0 || bounds.X+bounds.Width > namespace.Width || bounds.Y+bounds.Height > namespace.Height {
+					t.Errorf("%s at %+v is outside its namespace of %vx%v", node.ID, bounds, namespace.Width, namespace.Height)
+				}
+			}
+
+			// Connections may cross namespaces, so they stay on the default layer
+			for _, connection := range diagram.Connections {
+				if parent := byID[connection.ID].Parent; parent != "1" {
+					t.Errorf("connection %s has parent %s, want 1", connection.ID, parent)
+				}
+			}
+		})
+	}
+}
+
+func TestNoNamespaceContainers(t *testing.T) {
+	// The grid layout never groups resources by namespace
+	for _, opts := range []Options{{Layout: "hierarchical", NoNamespaces: true}, {Layout: "grid"}} {
+		t.Run(opts.Layout, func(t *testing.T) {
+			diagram := shopDiagram()
+			cells := generateCells(t, opts, diagram)
+
+			for _, cell := range cells {
+				if strings.HasPrefix(cell.Style, "swimlane;") {
+					t.Errorf("flat diagram has namespace container %s", cell.ID)
+				}
+			}
+			byID := cellsByID(cells)
+			for _, node := range diagram.Nodes {
+				cell := byID[node.ID]
+				if bounds := cell.Bounds(); cell.Parent != "1" || bounds.X != node.X || bounds.Y != node.Y {
+					t.Errorf("%s: parent %s at (%v, %v), want 1 at (%v, %v)", node.ID, cell.Parent, bounds.X, bounds.Y, node.X, node.Y)
+				}
+			}
+		})
+	}
+}
+
+func TestNodePropertiesAndTooltips(t *testing.T) {
+	tests := []struct {
+		name    string
+		theme   *Theme
+		node    models.DiagramNode
+		tooltip string // empty for none
+	}{
+		{
+			name:    "default tooltip",
+			node:    propertiesNode("web", "shop", models.Property{Name: "replicas", Value: "3"}, models.Property{Name: "images", Value: "shop/web:1.2"}),
+			tooltip: "Deployment web\nNamespace: shop\nReplicas: 3\nImages: shop/web:1.2",
+		},
+		{
+			name:    "default tooltip without optional properties",
+			node:    propertiesNode("view", ""),
+			tooltip: "Deployment view",
+		},
+		{
+			name:    "theme tooltip",
+			theme:   &Theme{Tooltip: stringPointer("{{.Category}}: {{.Properties.images}} {{index .Labels \"app\"}}")},
+			node:    propertiesNode("web", "shop", models.Property{Name: "images", Value: "shop/web:1.2"}),
+			tooltip: "workload: shop/web:1.2 web",
+		},
+		{
+			name:    "node style tooltip",
+			theme:   &Theme{Nodes: []NodeStyle{{Kinds: []string{"Deployment"}, Tooltip: "{{.Name}} in {{.Namespace}}"}}},
+			node:    propertiesNode("web", "shop"),
+			tooltip: "web in shop",
+		},
+		{
+			name:  "no tooltip",
+			theme: &Theme{Tooltip: stringPointer("")},
+			node:  propertiesNode("web", "shop"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diagram := testDiagram([]models.DiagramNode{tt.node})
+			cell := cellsByID(generateCells(t, Options{Layout: "hierarchical", Theme: tt.theme}, diagram))[tt.node.ID]
+
+			if cell.Object != "UserObject" {
+				t.Errorf("cell is wrapped in %q, want UserObject", cell.Object)
+			}
+			attrs := make(map[string]string)
+			for _, attr := range cell.Properties {
+				attrs[attr.Name.Local] = attr.Value
+			}
+			for _, property := range tt.node.Properties {
+				if attrs[property.Name] != property.Value {
+					t.Errorf("property %s = %q, want %q", property.Name, attrs[property.Name], property.Value)
+				}
+			}
+			if tooltip, exists := attrs["tooltip"]; tooltip != tt.tooltip || exists != (tt.tooltip != "") {
+				t.Errorf("tooltip = %q, want %q", tooltip, tt.tooltip)
+			}
+		})
+	}
+}
+
+// propertiesNode returns a Deployment node with the properties set by the converter
+func propertiesNode(name, namespace string, properties ...models.Property) models.DiagramNode {
+	node := testNode(namespace, "Deployment", name)
+	node.Labels = map[string]string{"app": name}
+	node.Properties = append([]models.Property{
+		{Name: "kind", Value: "Deployment"},
+		{Name: "name", Value: name},
+		{Name: "labels", Value: "app=" + name},
+	}, properties...)
+	return node
+}
+
+func stringPointer(s string) *string {
+	return &s
+}
@@ -0,0 +1,81 @@
+package drawio
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+
+	"k8s-to-drawio/pkg/models"
+)
+
+// ViewerScript is the Draw.io viewer that renders the diagrams of HTML pages
+const ViewerScript = "https://viewer.diagrams.net/js/viewer-static.min.js"
+
+// htmlPage is an HTML page embedding a diagram like Draw.io's "Export as HTML"
+var htmlPage = template.Must(template.New("page").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+</head>
+<body>
+<div class="mxgraph" style="max-width:100%;border:1px solid transparent;" data-mxgraph="{{.Config}}"></div>
+<script type="text/javascript" src="{{.Script}}"></script>
+</body>
+</html>
+`))
+
+// viewerConfig is the configuration of the Draw.io viewer, see
+// https://www.drawio.com/doc/faq/embed-html-options
+type viewerConfig struct {
+	Highlight string `json:"highlight"`
+	Nav       bool   `json:"nav"`
+	Resize    bool   `json:"resize"`
+	Toolbar   string `json:"toolbar"`
+	Edit      string `json:"edit"`
+	XML       string `json:"xml"`
+}
+
+// HTMLGenerator writes diagrams as HTML pages rendering the Draw.io file with the
+// Draw.io viewer, with zoom, layers and page controls. Viewing the page requires
+// access to ViewerScript.
+type HTMLGenerator struct {
+	generator *Generator
+}
+
+// NewHTMLGenerator creates a generator embedding the files of a Draw.io generator
+func NewHTMLGenerator(generator *Generator) *HTMLGenerator {
+	return &HTMLGenerator{generator: generator}
+}
+
+// Generate writes a diagram as an HTML page
+func (h *HTMLGenerator) Generate(w io.Writer, diagram *models.Diagram) error {
+	var file bytes.Buffer
+	if err := h.generator.Generate(&file, diagram); err != nil {
+		return err
+	}
+
+	config, err := json.Marshal(viewerConfig{
+		Highlight: "#0000ff",
+		Nav:       true,
+		Resize:    true,
+		Toolbar:   "zoom layers pages lightbox",
+		Edit:      "_blank",
+		XML:       file.String(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode viewer configuration: %w", err)
+	}
+
+	return htmlPage.Execute(w, struct {
+		Title  string
+		Config string
+		Script string
+	}{
+		Title:  "Kubernetes Architecture",
+		Config: string(config),
+		Script: ViewerScript,
+	})
+}
@@ -0,0 +1,67 @@
+package drawio
+
+import "fmt"
+
+// Icon sets selectable with Options.Icons
+const (
+	IconsDefault    = "default"
+	IconsKubernetes = "kubernetes"
+)
+
+// Size of the Kubernetes icons, the label is placed below the icon
+const (
+	iconWidth  = 50.0
+	iconHeight = 48.0
+)
+
+// KubernetesIcons maps resource kinds to the prIcon of Draw.io's mxgraph.kubernetes.icon stencil
+var KubernetesIcons = map[string]string{
+	"Deployment":               "deploy",
+	"StatefulSet":              "sts",
+	"DaemonSet":                "ds",
+	"ReplicaSet":               "rs",
+	"Pod":                      "pod",
+	"Job":                      "job",
+	"CronJob":                  "cronjob",
+	"Service":                  "svc",
+	"Ingress":                  "ing",
+	"Route":                    "ing",
+	"NetworkPolicy":            "netpol",
+	"ConfigMap":                "cm",
+	"Secret":                   "secret",
+	"VaultSecret":              "secret",
+	"PersistentVolume":         "pv",
+	"PersistentVolumeClaim":    "pvc",
+	"Namespace":                "ns",
+	"ServiceAccount":           "sa",
+	"Role":                     "role",
+	"RoleBinding":              "rb",
+	"ClusterRole":              "c_role",
+	"ClusterRoleBinding":       "crb",
+	"HorizontalPodAutoscaler":  "hpa",
+	"Endpoints":                "ep",
+	"ResourceQuota":            "quota",
+	"LimitRange":               "limits",
+	"CustomResourceDefinition": "crd",
+}
+
+// fallbackIcon is used for kinds without an icon of their own, such as custom resources
+const fallbackIcon = "crd"
+
+// KubernetesIconStyle is the style of a Kubernetes icon with the label below it
+const KubernetesIconStyle = "sketch=0;html=1;dashed=0;whiteSpace=wrap;fillColor=%s;strokeColor=#ffffff;points=[[0.005,0.63,0],[0.1,0.2,0],[0.9,0.2,0],[0.5,0,0],[0.995,0.63,0],[0.72,0.99,0],[0.5,1,0],[0.28,0.99,0]];verticalLabelPosition=bottom;align=center;verticalAlign=top;shape=mxgraph.kubernetes.icon;prIcon=%s;"
+
+// GetKubernetesIconStyle returns the icon style of a kind and whether the kind has an
+// icon of its own. Vault secrets use the secret icon in the colour of Vault.
+func GetKubernetesIconStyle(kind string) (string, bool) {
+	fillColor := "#326CE5"
+	if kind == "VaultSecret" {
+		fillColor = "#d79b00"
+	}
+
+	icon, exists := KubernetesIcons[kind]
+	if !exists {
+		icon = fallbackIcon
+	}
+	return fmt.Sprintf(KubernetesIconStyle, fillColor, icon), exists
+}
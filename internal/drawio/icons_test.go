@@ -0,0 +1,68 @@
+package drawio
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"k8s-to-drawio/pkg/models"
+)
+
+func TestGetKubernetesIconStyle(t *testing.T) {
+	tests := []struct {
+		kind      string
+		icon      string
+		fillColor string
+		exists    bool
+	}{
+		{"Deployment", "deploy", "#326CE5", true},
+		{"ClusterRole", "c_role", "#326CE5", true},
+		{"Route", "ing", "#326CE5", true},
+		{"VaultSecret", "secret", "#d79b00", true},
+		{"ServiceMonitor", "crd", "#326CE5", false},
+	}
+
+	for _, tt := range tests {
+		style, exists := GetKubernetesIconStyle(tt.kind)
+		shape, attributes := ParseStyle(style)
+		if exists != tt.exists || shape != "mxgraph.kubernetes.icon" || attributes["prIcon"] != tt.icon || attributes["fillColor"] != tt.fillColor {
+			t.Errorf("GetKubernetesIconStyle(%q) = %q, %v, want icon %s in %s, %v", tt.kind, style, exists, tt.icon, tt.fillColor, tt.exists)
+		}
+	}
+}
+
+func TestKubernetesIcons(t *testing.T) {
+	diagram := testDiagram([]models.DiagramNode{
+		testNode("shop", "Deployment", "web"),
+		testNode("shop", "ServiceMonitor", "web"),
+		testNode(models.VaultSecretNamespace, "VaultSecret", "secret/data/db"),
+	})
+	cells := cellsByID(generateCells(t, Options{Layout: "hierarchical", Icons: IconsKubernetes}, diagram))
+
+	// Kinds with an icon only show their name, the fallback icon also shows the kind
+	labels := map[string]string{
+		"shop-Deployment-web":                   "web",
+		"shop-ServiceMonitor-web":               "ServiceMonitor\nweb",
+		"vaultstore-VaultSecret-secret/data/db": "secret/data/db",
+	}
+	for _, node := range diagram.Nodes {
+		cell := cells[node.ID]
+		if !strings.Contains(cell.Style, "shape=mxgraph.kubernetes.icon;") {
+			t.Errorf("%s has style %q, want a Kubernetes icon", node.ID, cell.Style)
+		}
+		if cell.Value != labels[node.ID] {
+			t.Errorf("%s has label %q, want %q", node.ID, cell.Value, labels[node.ID])
+		}
+		// Icons are centred in the space the layout reserved for the node
+		if bounds := cell.Bounds(); bounds.Width != iconWidth || bounds.Height != iconHeight {
+			t.Errorf("%s is %vx%v, want %vx%v", node.ID, bounds.Width, bounds.Height, iconWidth, iconHeight)
+		}
+	}
+}
+
+func TestUnknownIconSet(t *testing.T) {
+	err := NewGeneratorWithOptions(Options{Icons: "aws"}).Generate(&bytes.Buffer{}, shopDiagram())
+	if err == nil || !strings.Contains(err.Error(), `unknown icon set "aws"`) {
+		t.Errorf("Generate() error = %v, want an unknown icon set error", err)
+	}
+}
@@ -0,0 +1,431 @@
+package drawio
+
+import (
+	"math"
+	"sort"
+
+	"k8s-to-drawio/pkg/models"
+)
+
+const (
+	layeredGapX       = 80.0 // horizontal gap between neighbouring nodes of a layer
+	layeredGapY       = 80.0 // vertical gap between layers
+	layeredDummyWidth = 20.0 // width reserved for edges passing through a layer
+	crossingSweeps    = 12   // barycenter sweeps of the crossing minimization
+	placementSweeps   = 8    // sweeps of the coordinate assignment
+)
+
+// kindTiers is the minimum layer of some kinds, so Ingress -> Service -> workload ->
+// configuration and storage reads top to bottom even where no edge enforces it.
+// Kinds not listed have no minimum layer. Unused layers are dropped afterwards.
+var kindTiers = map[string]int{
+	"Ingress":               0,
+	"Route":                 0,
+	"Service":               1,
+	"Deployment":            2,
+	"StatefulSet":           2,
+	"DaemonSet":             2,
+	"ReplicaSet":            2,
+	"Job":                   2,
+	"CronJob":               2,
+	"Pod":                   2,
+	"ConfigMap":             3,
+	"Secret":                3,
+	"PersistentVolumeClaim": 3,
+	"VaultSecret":           3,
+	"PersistentVolume":      4,
+}
+
+// layeredGraph is the graph the layered layout works on. The first vertices are
+// diagram nodes, the rest are dummies that split edges spanning several layers.
+type layeredGraph struct {
+	rank   []int
+	width  []float64
+	up     [][]int // neighbours in the layer above
+	down   [][]int // neighbours in the layer below
+	layers [][]int
+}
+
+// layeredLayout computes a Sugiyama-style layered layout of the given nodes. Edges
+// point downwards: cycles are broken by reversing edges, every node is ranked below
+// the nodes referencing it, the order within layers is chosen to reduce edge
+// crossings and nodes are finally placed close to their neighbours. It returns the
+// position of every node index and the size of the bounding box.
+func layeredLayout(nodeIndices []int, diagram *models.Diagram) (map[int]point, float64, float64) {
+	if len(nodeIndices) == 0 {
+		return nil, 0, 0
+	}
+
+	// Start from a readable order: by tier, kind and name
+	order := append([]int(nil), nodeIndices...)
+	sort.SliceStable(order, func(a, b int) bool {
+		na, nb := diagram.Nodes[order[a]], diagram.Nodes[order[b]]
+		if ta, tb := kindTiers[na.Kind], kindTiers[nb.Kind]; ta != tb {
+			return ta < tb
+		}
+		if na.Kind != nb.Kind {
+			return na.Kind < nb.Kind
+		}
+		return na.Label < nb.Label
+	})
+
+	vertexOf := make(map[string]int, len(order))
+	for v, idx := range order {
+		vertexOf[diagram.Nodes[idx].ID] = v
+	}
+
+	var edges [][2]int
+	seen := make(map[[2]int]bool)
+	for _, connection := range diagram.Connections {
+		source, sourceFound := vertexOf[connection.SourceID]
+		target, targetFound := vertexOf[connection.TargetID]
+		edge := [2]int{source, target}
+		if !sourceFound || !targetFound || source == target || seen[edge] {
+			continue
+		}
+		seen[edge] = true
+		edges = append(edges, edge)
+	}
+
+	tiers := make([]int, len(order))
+	for v, idx := range order {
+		tiers[v] = kindTiers[diagram.Nodes[idx].Kind]
+	}
+
+	widths := make([]float64, len(order))
+	for v, idx := range order {
+		widths[v] = diagram.Nodes[idx].Width
+	}
+
+	edges = breakCycles(len(order), edges)
+	graph := newLayeredGraph(assignRanks(tiers, edges), widths, edges)
+	graph.minimizeCrossings()
+	xs := graph.assignCoordinates()
+
+	// Every layer is as high as its highest node
+	layerHeights := make([]float64, len(graph.layers))
+	for v, idx := range order {
+		layerHeights[graph.rank[v]] = math.Max(layerHeights[graph.rank[v]], diagram.Nodes[idx].Height)
+	}
+	layerTops := make([]float64, len(graph.layers))
+	height := -layeredGapY
+	for r, layerHeight := range layerHeights {
+		layerTops[r] = height + layeredGapY
+		height += layerHeight + layeredGapY
+	}
+
+	positions := make(map[int]point, len(order))
+	width := 0.0
+	for v, idx := range order {
+		positions[idx] = point{x: xs[v], y: layerTops[graph.rank[v]]}
+		width = math.Max(width, xs[v]+widths[v])
+	}
+
+	return positions, width, height
+}
+
+// breakCycles makes the graph acyclic by reversing the edges that close a cycle
+// during a depth-first search in vertex order
+func breakCycles(n int, edges [][2]int) [][2]int {
+	out := make([][]int, n)
+	for _, edge := range edges {
+		out[edge[0]] = append(out[edge[0]], edge[1])
+	}
+
+	const (
+		unvisited = iota
+		onStack
+		done
+	)
+	state := make([]int, n)
+	reversed := make(map[[2]int]bool)
+
+	var visit func(v int)
+	visit = func(v int) {
+		state[v] = onStack
+		for _, w := range out[v] {
+			switch state[w] {
+			case unvisited:
+				visit(w)
+			case onStack:
+				reversed[[2]int{v, w}] = true
+			}
+		}
+		state[v] = done
+	}
+	for v := 0; v < n; v++ {
+		if state[v] == unvisited {
+			visit(v)
+		}
+	}
+
+	acyclic := make([][2]int, 0, len(edges))
+	seen := make(map[[2]int]bool)
+	for _, edge := range edges {
+		if reversed[edge] {
+			edge = [2]int{edge[1], edge[0]}
+		}
+		if !seen[edge] {
+			seen[edge] = true
+			acyclic = append(acyclic, edge)
+		}
+	}
+	return acyclic
+}
+
+// assignRanks ranks every vertex below its predecessors and not above its tier,
+// then drops unused ranks
+func assignRanks(tiers []int, edges [][2]int) []int {
+	n := len(tiers)
+	out := make([][]int, n)
+	inDegree := make([]int, n)
+	for _, edge := range edges {
+		out[edge[0]] = append(out[edge[0]], edge[1])
+		inDegree[edge[1]]++
+	}
+
+	rank := append([]int(nil), tiers...)
+	var queue []int
+	for v := 0; v < n; v++ {
+		if inDegree[v] == 0 {
+			queue = append(queue, v)
+		}
+	}
+	for len(queue) > 0 {
+		v := queue[0]
+		queue = queue[1:]
+		for _, w := range out[v] {
+			if rank[v]+1 > rank[w] {
+				rank[w] = rank[v] + 1
+			}
+			inDegree[w]--
+			if inDegree[w] == 0 {
+				queue = append(queue, w)
+			}
+		}
+	}
+
+	used := make(map[int]bool)
+	for _, r := range rank {
+		used[r] = true
+	}
+	distinct := make([]int, 0, len(used))
+	for r := range used {
+		distinct = append(distinct, r)
+	}
+	sort.Ints(distinct)
+	compact := make(map[int]int, len(distinct))
+	for i, r := range distinct {
+		compact[r] = i
+	}
+	for v := range rank {
+		rank[v] = compact[rank[v]]
+	}
+	return rank
+}
+
+// newLayeredGraph builds the layers from the ranks and widths of the nodes,
+// splitting edges that span several layers into chains of dummy vertices
+func newLayeredGraph(rank []int, widths []float64, edges [][2]int) *layeredGraph {
+	g := &layeredGraph{}
+	addVertex := func(r int, width float64) int {
+		g.rank = append(g.rank, r)
+		g.width = append(g.width, width)
+		g.up = append(g.up, nil)
+		g.down = append(g.down, nil)
+		return len(g.rank) - 1
+	}
+	for v, r := range rank {
+		addVertex(r, widths[v])
+	}
+
+	for _, edge := range edges {
+		upper := edge[0]
+		for r := rank[edge[0]] + 1; r < rank[edge[1]]; r++ {
+			dummy := addVertex(r, layeredDummyWidth)
+			g.down[upper] = append(g.down[upper], dummy)
+			g.up[dummy] = append(g.up[dummy], upper)
+			upper = dummy
+		}
+		g.down[upper] = append(g.down[upper], edge[1])
+		g.up[edge[1]] = append(g.up[edge[1]], upper)
+	}
+
+	layerCount := 0
+	for _, r := range g.rank {
+		if r+1 > layerCount {
+			layerCount = r + 1
+		}
+	}
+	g.layers = make([][]int, layerCount)
+	for v, r := range g.rank {
+		g.layers[r] = append(g.layers[r], v)
+	}
+	return g
+}
+
+// minimizeCrossings reorders the layers with alternating downward and upward
+// barycenter sweeps and keeps the order with the fewest crossings
+func (g *layeredGraph) minimizeCrossings() {
+	best := copyLayers(g.layers)
+	bestCrossings := g.crossings()
+
+	for sweep := 0; sweep < crossingSweeps && bestCrossings > 0; sweep++ {
+		if sweep%2 == 0 {
+			for r := 1; r < len(g.layers); r++ {
+				orderByBarycenter(g.layers[r], g.layers[r-1], g.up)
+			}
+		} else {
+			for r := len(g.layers) - 2; r >= 0; r-- {
+				orderByBarycenter(g.layers[r], g.layers[r+1], g.down)
+			}
+		}
+
+		if crossings := g.crossings(); crossings < bestCrossings {
+			best = copyLayers(g.layers)
+			bestCrossings = crossings
+		}
+	}
+
+	g.layers = best
+}
+
+// orderByBarycenter sorts layer by the average position of each vertex's neighbours
+// in the fixed layer. Vertices without neighbours there keep their position.
+func orderByBarycenter(layer, fixed []int, neighbours [][]int) {
+	position := make(map[int]int, len(fixed))
+	for i, v := range fixed {
+		position[v] = i
+	}
+
+	barycenter := make(map[int]float64, len(layer))
+	for i, v := range layer {
+		if len(neighbours[v]) == 0 {
+			barycenter[v] = float64(i)
+			continue
+		}
+		sum := 0.0
+		for _, w := range neighbours[v] {
+			sum += float64(position[w])
+		}
+		barycenter[v] = sum / float64(len(neighbours[v]))
+	}
+
+	sort.SliceStable(layer, func(a, b int) bool {
+		return barycenter[layer[a]] < barycenter[layer[b]]
+	})
+}
+
+// crossings counts the edge crossings between all adjacent layers
+func (g *layeredGraph) crossings() int {
+	total := 0
+	for r := 0; r+1 < len(g.layers); r++ {
+		position := make(map[int]int)
+		for i, v := range g.layers[r+1] {
+			position[v] = i
+		}
+
+		var edges [][2]int
+		for i, v := range g.layers[r] {
+			for _, w := range g.down[v] {
+				edges = append(edges, [2]int{i, position[w]})
+			}
+		}
+		for i := range edges {
+			for j := i + 1; j < len(edges); j++ {
+				a, b := edges[i], edges[j]
+				if (a[0]-b[0])*(a[1]-b[1]) < 0 {
+					total++
+				}
+			}
+		}
+	}
+	return total
+}
+
+// assignCoordinates places the vertices of every layer in order, pulling each
+// towards the average centre of its neighbours in alternating downward and upward
+// sweeps. It returns the left x coordinate of every vertex, rounded to whole units.
+func (g *layeredGraph) assignCoordinates() []float64 {
+	x := make([]float64, len(g.rank))
+	for _, layer := range g.layers {
+		next := 0.0
+		for _, v := range layer {
+			x[v] = next
+			next += g.width[v] + layeredGapX
+		}
+	}
+
+	for sweep := 0; sweep < placementSweeps; sweep++ {
+		if sweep%2 == 0 {
+			for r := 1; r < len(g.layers); r++ {
+				g.placeLayer(g.layers[r], g.up, x)
+			}
+		} else {
+			for r := len(g.layers) - 2; r >= 0; r-- {
+				g.placeLayer(g.layers[r], g.down, x)
+			}
+		}
+	}
+
+	minX := x[0]
+	for _, value := range x {
+		if value < minX {
+			minX = value
+		}
+	}
+	for v := range x {
+		x[v] = math.Round(x[v] - minX)
+	}
+	return x
+}
+
+// placeLayer moves the vertices of layer towards the centre of their neighbours
+// while keeping their order and the minimum gap. Packing from the left and from
+// the right both respect the gaps, and so does their average.
+func (g *layeredGraph) placeLayer(layer []int, neighbours [][]int, x []float64) {
+	desired := make([]float64, len(layer))
+	for i, v := range layer {
+		if len(neighbours[v]) == 0 {
+			desired[i] = x[v]
+			continue
+		}
+		sum := 0.0
+		for _, w := range neighbours[v] {
+			sum += x[w] + g.width[w]/2
+		}
+		desired[i] = sum/float64(len(neighbours[v])) - g.width[v]/2
+	}
+
+	fromLeft := make([]float64, len(layer))
+	for i := range layer {
+		fromLeft[i] = desired[i]
+		if i > 0 {
+			if limit := fromLeft[i-1] + g.width[layer[i-1]] + layeredGapX; fromLeft[i] < limit {
+				fromLeft[i] = limit
+			}
+		}
+	}
+
+	fromRight := make([]float64, len(layer))
+	for i := len(layer) - 1; i >= 0; i-- {
+		fromRight[i] = desired[i]
+		if i < len(layer)-1 {
+			if limit := fromRight[i+1] - g.width[layer[i]] - layeredGapX; fromRight[i] > limit {
+				fromRight[i] = limit
+			}
+		}
+	}
+
+	for i, v := range layer {
+		x[v] = (fromLeft[i] + fromRight[i]) / 2
+	}
+}
+
+func copyLayers(layers [][]int) [][]int {
+	copied := make([][]int, len(layers))
+	for i, layer := range layers {
+		copied[i] = append([]int(nil), layer...)
+	}
+	return copied
+}
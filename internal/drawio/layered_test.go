@@ -0,0 +1,246 @@
+package drawio
+
+import (
+	"fmt"
+	"math"
+	"testing"
+
+	"k8s-to-drawio/pkg/models"
+)
+
+func TestLayeredLayoutDeterministic(t *testing.T) {
+	first := shopDiagram()
+	if err := NewLayout("hierarchical", false).ApplyLayout(first); err != nil {
+		t.Fatalf("ApplyLayout: %v", err)
+	}
+
+	second := shopDiagram()
+	reverseNodes(second)
+	if err := NewLayout("hierarchical", false).ApplyLayout(second); err != nil {
+		t.Fatalf("ApplyLayout: %v", err)
+	}
+
+	comparePositions(t, first, second)
+}
+
+func TestLayeredLayoutNoOverlap(t *testing.T) {
+	for _, noNamespaces := range []bool{false, true} {
+		t.Run(fmt.Sprintf("noNamespaces=%v", noNamespaces), func(t *testing.T) {
+			diagram := shopDiagram()
+			layout := NewLayout("hierarchical", noNamespaces)
+			layout.nodeSize = variedNodeSize
+			if err := layout.ApplyLayout(diagram); err != nil {
+				t.Fatalf("ApplyLayout: %v", err)
+			}
+
+			checkNoOverlap(t, diagram)
+			checkWithinNamespaces(t, diagram)
+		})
+	}
+}
+
+func TestLayeredLayoutEdgesPointDown(t *testing.T) {
+	diagram := shopDiagram()
+	if err := NewLayout("hierarchical", true).ApplyLayout(diagram); err != nil {
+		t.Fatalf("ApplyLayout: %v", err)
+	}
+
+	nodes := nodesByID(diagram)
+	for _, connection := range diagram.Connections {
+		source, target := nodes[connection.SourceID], nodes[connection.TargetID]
+		if target.Y <= source.Y {
+			t.Errorf("%s (y=%v) is not below %s (y=%v)", target.ID, target.Y, source.ID, source.Y)
+		}
+	}
+}
+
+func TestLayeredLayoutCycle(t *testing.T) {
+	diagram := testDiagram(
+		[]models.DiagramNode{
+			testNode("default", "ConfigMap", "a"),
+			testNode("default", "ConfigMap", "b"),
+			testNode("default", "ConfigMap", "c"),
+			testNode("default", "ConfigMap", "d"),
+		},
+		"default-ConfigMap-a", "default-ConfigMap-b",
+		"default-ConfigMap-b", "default-ConfigMap-c",
+		"default-ConfigMap-c", "default-ConfigMap-a",
+		"default-ConfigMap-c", "default-ConfigMap-d",
+		"default-ConfigMap-d", "default-ConfigMap-d",
+	)
+	if err := NewLayout("hierarchical", false).ApplyLayout(diagram); err != nil {
+		t.Fatalf("ApplyLayout: %v", err)
+	}
+
+	checkFinite(t, diagram)
+	checkNoOverlap(t, diagram)
+	checkWithinNamespaces(t, diagram)
+
+	// Breaking the cycle leaves a chain, so a, b and c end up in different layers
+	nodes := nodesByID(diagram)
+	rows := map[float64]bool{}
+	for _, name := range []string{"a", "b", "c"} {
+		rows[nodes["default-ConfigMap-"+name].Y] = true
+	}
+	if len(rows) != 3 {
+		t.Errorf("a, b and c share layers: %v", rows)
+	}
+}
+
+// testNode returns a diagram node of the given namespace, kind and name
+func testNode(namespace, kind, name string) models.DiagramNode {
+	return models.DiagramNode{
+		ID:        namespace + "-" + kind + "-" + name,
+		Label:     name,
+		Kind:      kind,
+		Namespace: namespace,
+	}
+}
+
+// testDiagram returns a diagram of nodes connected by pairs of source and target IDs
+func testDiagram(nodes []models.DiagramNode, connections ...string) *models.Diagram {
+	diagram := &models.Diagram{
+		Nodes:      nodes,
+		Namespaces: make(map[string]models.NamespaceGroup),
+	}
+	for i := 0; i+1 < len(connections); i += 2 {
+		diagram.Connections = append(diagram.Connections, models.Connection{
+			ID:       fmt.Sprintf("edge-%d", i/2),
+			SourceID: connections[i],
+			TargetID: connections[i+1],
+		})
+	}
+	return diagram
+}
+
+// shopDiagram returns two namespaces of typical resources, with a shared ConfigMap,
+// an edge skipping layers and a connection across namespaces
+func shopDiagram() *models.Diagram {
+	return testDiagram(
+		[]models.DiagramNode{
+			testNode("shop", "Ingress", "web"),
+			testNode("shop", "Service", "web"),
+			testNode("shop", "Service", "api"),
+			testNode("shop", "Deployment", "web"),
+			testNode("shop", "Deployment", "api"),
+			testNode("shop", "StatefulSet", "db"),
+			testNode("shop", "ConfigMap", "settings"),
+			testNode("shop", "Secret", "db-creds"),
+			testNode("shop", "PersistentVolumeClaim", "data"),
+			testNode("shop", "ServiceAccount", "web"),
+			testNode("monitoring", "ServiceMonitor", "web"),
+			testNode("monitoring", "Deployment", "prometheus"),
+			testNode("monitoring", "ConfigMap", "prometheus"),
+		},
+		"shop-Ingress-web", "shop-Service-web",
+		"shop-Service-web", "shop-Deployment-web",
+		"shop-Service-api", "shop-Deployment-api",
+		"shop-Deployment-web", "shop-ConfigMap-settings",
+		"shop-Deployment-api", "shop-ConfigMap-settings",
+		"shop-Deployment-api", "shop-Secret-db-creds",
+		"shop-StatefulSet-db", "shop-Secret-db-creds",
+		"shop-StatefulSet-db", "shop-PersistentVolumeClaim-data",
+		"shop-Deployment-web", "shop-ServiceAccount-web",
+		"shop-Ingress-web", "shop-ConfigMap-settings",
+		"monitoring-Deployment-prometheus", "monitoring-ConfigMap-prometheus",
+		"monitoring-ServiceMonitor-web", "shop-Service-web",
+	)
+}
+
+// variedNodeSize gives nodes of some kinds a different size than the default
+func variedNodeSize(node models.DiagramNode) (float64, float64) {
+	switch node.Kind {
+	case "Deployment", "StatefulSet":
+		return 220, 120
+	case "Secret":
+		return 60, 40
+	}
+	return nodeWidth, nodeHeight
+}
+
+// reverseNodes reverses the input order of the nodes of diagram
+func reverseNodes(diagram *models.Diagram) {
+	nodes := diagram.Nodes
+	for i, j := 0, len(nodes)-1; i < j; i, j = i+1, j-1 {
+		nodes[i], nodes[j] = nodes[j], nodes[i]
+	}
+}
+
+func nodesByID(diagram *models.Diagram) map[string]models.DiagramNode {
+	nodes := make(map[string]models.DiagramNode, len(diagram.Nodes))
+	for _, node := range diagram.Nodes {
+		nodes[node.ID] = node
+	}
+	return nodes
+}
+
+// comparePositions checks that every node and namespace has the same bounds in both diagrams
+func comparePositions(t *testing.T, want, got *models.Diagram) {
+	t.Helper()
+
+	gotNodes := nodesByID(got)
+	for _, node := range want.Nodes {
+		other := gotNodes[node.ID]
+		if node.X != other.X || node.Y != other.Y || node.Width != other.Width || node.Height != other.Height {
+			t.Errorf("%s at (%v, %v) %vx%v, want (%v, %v) %vx%v", node.ID,
+				other.X, other.Y, other.Width, other.Height, node.X, node.Y, node.Width, node.Height)
+		}
+	}
+
+	for name, ns := range want.Namespaces {
+		other := got.Namespaces[name]
+		if ns.X != other.X || ns.Y != other.Y || ns.Width != other.Width || ns.Height != other.Height {
+			t.Errorf("namespace %s at (%v, %v) %vx%v, want (%v, %v) %vx%v", name,
+				other.X, other.Y, other.Width, other.Height, ns.X, ns.Y, ns.Width, ns.Height)
+		}
+	}
+}
+
+// checkNoOverlap checks that no two nodes overlap
+func checkNoOverlap(t *testing.T, diagram *models.Diagram) {
+	t.Helper()
+
+	for i, a := range diagram.Nodes {
+		for _, b := range diagram.Nodes[i+1:] {
+			if a.X < b.X+b.Width && b.X < a.X+a.Width && a.Y < b.Y+b.Height && b.Y < a.Y+a.Height {
+				t.Errorf("%s at (%v, %v) %vx%v overlaps %s at (%v, %v) %vx%v",
+					a.ID, a.X, a.Y, a.Width, a.Height, b.ID, b.X, b.Y, b.Width, b.Height)
+			}
+		}
+	}
+}
+
+// checkWithinNamespaces checks that every node lies within its namespace and that
+// namespaces do not overlap
+func checkWithinNamespaces(t *testing.T, diagram *models.Diagram) {
+	t.Helper()
+
+	nodes := nodesByID(diagram)
+	for name, ns := range diagram.Namespaces {
+		for _, id := range ns.NodeIDs {
+			node := nodes[id]
+			if node.X < ns.X || node.Y < ns.Y || node.X+node.Width > ns.X+ns.Width || node.Y+node.Height > ns.Y+ns.Height {
+				t.Errorf("%s at (%v, %v) %vx%v lies outside namespace %s at (%v, %v) %vx%v",
+					id, node.X, node.Y, node.Width, node.Height, name, ns.X, ns.Y, ns.Width, ns.Height)
+			}
+		}
+
+		for otherName, other := range diagram.Namespaces {
+			if name < otherName && ns.X < other.X+other.Width && other.X < ns.X+ns.Width &&
+				ns.Y < other.Y+other.Height && other.Y < ns.Y+ns.Height {
+				t.Errorf("namespaces %s and %s overlap", name, otherName)
+			}
+		}
+	}
+}
+
+// checkFinite checks that every node has a finite position
+func checkFinite(t *testing.T, diagram *models.Diagram) {
+	t.Helper()
+
+	for _, node := range diagram.Nodes {
+		if math.IsNaN(node.X) || math.IsNaN(node.Y) || math.IsInf(node.X, 0) || math.IsInf(node.Y, 0) {
+			t.Errorf("%s has no finite position: (%v, %v)", node.ID, node.X, node.Y)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package drawio
+
+import (
+	"k8s-to-drawio/internal/k8s"
+	"k8s-to-drawio/pkg/models"
+)
+
+// layerPrefix is the prefix of the IDs of layer cells
+const layerPrefix = "layer-"
+
+// Layer is a Draw.io layer holding the resources of a category of k8s.ResourceCategories
+type Layer struct {
+	Category string
+	Name     string
+}
+
+// Layers lists the layers of diagrams generated with Options.Layers, bottom to top.
+// Resources of other categories stay on the default layer with the namespaces.
+var Layers = []Layer{
+	{Category: "workload", Name: "Workloads"},
+	{Category: "networking", Name: "Networking"},
+	{Category: "config", Name: "Config"},
+	{Category: "storage", Name: "Storage"},
+	{Category: "rbac", Name: "RBAC"},
+	{Category: "monitoring", Name: "Monitoring"},
+	{Category: "vault", Name: "Vault"},
+}
+
+// RelationLayers maps relations to the category of the layer of their connections.
+// Connections with other relations go on the layer of their target.
+var RelationLayers = map[models.Relation]string{
+	models.RelationMounts:             "config",
+	models.RelationEnvFrom:            "config",
+	models.RelationSelects:            "networking",
+	models.RelationRoutesTo:           "networking",
+	models.RelationBinds:              "rbac",
+	models.RelationRunsAs:             "rbac",
+	models.RelationImagePull:          "config",
+	models.RelationScrapes:            "monitoring",
+	models.RelationInjectsVaultSecret: "vault",
+	models.RelationVaultAuth:          "vault",
+}
+
+// layerCellID returns the ID of the cell of a layer
+func layerCellID(category string) string {
+	return layerPrefix + category
+}
+
+// isLayer reports whether a category has its own layer
+func isLayer(category string) bool {
+	for _, layer := range Layers {
+		if layer.Category == category {
+			return true
+		}
+	}
+	return false
+}
+
+// nodeLayer returns the layer of a resource, empty for the default layer
+func (g *Generator) nodeLayer(node models.DiagramNode) string {
+	if !g.layers {
+		return ""
+	}
+	if category := k8s.GetResourceCategory(node.Kind); isLayer(category) {
+		return category
+	}
+	return ""
+}
+
+// connectionLayer returns the layer of a connection, empty for the default layer
+func (g *Generator) connectionLayer(connection models.Connection, nodes map[string]models.DiagramNode) string {
+	if !g.layers {
+		return ""
+	}
+	if category, exists := RelationLayers[connection.Relation]; exists {
+		return category
+	}
+	return g.nodeLayer(nodes[connection.TargetID])
+}
+
+// layerCells returns the cells of the layers used by the resources and connections
+// of a diagram, in the order of Layers
+func (g *Generator) layerCells(diagram *models.Diagram) []Cell {
+	used := g.usedLayers(diagram)
+
+	var cells []Cell
+	for _, layer := range Layers {
+		if used[layer.Category] {
+			cells = append(cells, Cell{ID: layerCellID(layer.Category), Value: layer.Name, Parent: "0"})
+		}
+	}
+	return cells
+}
+
+// usedLayers returns the categories of the layers holding cells of a diagram
+func (g *Generator) usedLayers(diagram *models.Diagram) map[string]bool {
+	used := make(map[string]bool)
+	if !g.layers {
+		return used
+	}
+
+	nodes := make(map[string]models.DiagramNode, len(diagram.Nodes))
+	for _, node := range diagram.Nodes {
+		nodes[node.ID] = node
+		if layer := g.nodeLayer(node); layer != "" {
+			used[layer] = true
+		}
+	}
+	for _, connection := range diagram.Connections {
+		if layer := g.connectionLayer(connection, nodes); layer != "" {
+			used[layer] = true
+		}
+	}
+	return used
+}
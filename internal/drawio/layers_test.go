@@ -0,0 +1,79 @@
+package drawio
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"k8s-to-drawio/internal/k8s"
+	"k8s-to-drawio/pkg/models"
+)
+
+func TestLayersKeepOneNamespaceContainer(t *testing.T) {
+	diagram := shopDiagram()
+	diagram.Nodes = append(diagram.Nodes, testNode("shop", "Namespace", "shop"))
+	cells := generateCells(t, Options{Layout: "hierarchical", Layers: true}, diagram)
+
+	var containers []string
+	for _, cell := range cells {
+		if strings.HasPrefix(cell.Style, "swimlane;") {
+			containers = append(containers, cell.ID)
+			if cell.Parent != "1" {
+				t.Errorf("container %s has parent %s, want the default layer", cell.ID, cell.Parent)
+			}
+		}
+	}
+	if want := []string{"ns-monitoring", "ns-shop"}; strings.Join(containers, ",") != strings.Join(want, ",") {
+		t.Errorf("namespace containers = %v, want %v", containers, want)
+	}
+
+	byID := cellsByID(cells)
+	for _, node := range diagram.Nodes {
+		cell := byID[node.ID]
+		bounds := cell.Bounds()
+		if category := k8s.GetResourceCategory(node.Kind); isLayer(category) {
+			// Placed over the namespace with absolute coordinates
+			if cell.Parent != layerCellID(category) || bounds.X != node.X || bounds.Y != node.Y {
+				t.Errorf("%s: parent %s at (%v, %v), want %s at (%v, %v)",
+					node.ID, cell.Parent, bounds.X, bounds.Y, layerCellID(category), node.X, node.Y)
+			}
+			continue
+		}
+
+		// Nested in the namespace with relative coordinates
+		namespace := diagram.Namespaces[node.Namespace]
+		if cell.Parent != "ns-shop" || bounds.X != node.X-namespace.X || bounds.Y != node.Y-namespace.Y {
+			t.Errorf("%s: parent %s at (%v, %v), want ns-shop at (%v, %v)",
+				node.ID, cell.Parent, bounds.X, bounds.Y, node.X-namespace.X, node.Y-namespace.Y)
+		}
+	}
+
+	for _, connection := range diagram.Connections {
+		if parent := byID[connection.ID].Parent; !strings.HasPrefix(parent, layerPrefix) {
+			t.Errorf("connection %s has parent %s, want a layer", connection.ID, parent)
+		}
+	}
+}
+
+// generateCells generates a Draw.io file of diagram and returns the cells of its first page
+func generateCells(t *testing.T, opts Options, diagram *models.Diagram) []Cell {
+	t.Helper()
+
+	var out bytes.Buffer
+	if err := NewGeneratorWithOptions(opts).Generate(&out, diagram); err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	file, err := ParseFile(out.Bytes())
+	if err != nil {
+		t.Fatalf("ParseFile: %v", err)
+	}
+	return file.Diagrams[0].Model.Root.Cells
+}
+
+func cellsByID(cells []Cell) map[string]Cell {
+	byID := make(map[string]Cell, len(cells))
+	for _, cell := range cells {
+		byID[cell.ID] = cell
+	}
+	return byID
+}
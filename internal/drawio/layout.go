@@ -5,19 +5,36 @@ import (
 	"math"
 )
 
+// Default size of resource nodes
+const (
+	nodeWidth  = 140.0
+	nodeHeight = 80.0
+)
+
 type Layout struct {
 	algorithm    string
 	noNamespaces bool
+	nodeSize     func(models.DiagramNode) (float64, float64)
 }
 
 func NewLayout(algorithm string, noNamespaces bool) *Layout {
 	return &Layout{
 		algorithm:    algorithm,
 		noNamespaces: noNamespaces,
+		nodeSize:     defaultNodeSize,
 	}
 }
 
+func defaultNodeSize(models.DiagramNode) (float64, float64) {
+	return nodeWidth, nodeHeight
+}
+
 func (l *Layout) ApplyLayout(diagram *models.Diagram) error {
+	// Layout algorithms place nodes of the size given by nodeSize
+	for i := range diagram.Nodes {
+		diagram.Nodes[i].Width, diagram.Nodes[i].Height = l.nodeSize(diagram.Nodes[i])
+	}
+
 	switch l.algorithm {
 	case "hierarchical":
 		return l.applyHierarchicalLayout(diagram)
@@ -25,70 +42,59 @@ func (l *Layout) ApplyLayout(diagram *models.Diagram) error {
 		return l.applyGridLayout(diagram)
 	case "vertical":
 		return l.applyVerticalLayout(diagram)
+	case "force":
+		return l.applyForceLayout(diagram)
 	default:
 		return l.applyHierarchicalLayout(diagram)
 	}
 }
 
 func (l *Layout) applyHierarchicalLayout(diagram *models.Diagram) error {
+	return l.applyPlacement(diagram, layeredLayout)
+}
+
+func (l *Layout) applyForceLayout(diagram *models.Diagram) error {
+	return l.applyPlacement(diagram, forceLayout)
+}
+
+// placement positions nodes relative to the top left corner of their bounds and
+// returns the position of every node index and the size of the bounds
+type placement func(nodeIndices []int, diagram *models.Diagram) (map[int]point, float64, float64)
+
+// point is the top left corner of a node
+type point struct {
+	x, y float64
+}
+
+// applyPlacement places the nodes of every namespace with place and stacks the
+// namespaces vertically, or places all nodes at once without namespace grouping
+func (l *Layout) applyPlacement(diagram *models.Diagram, place placement) error {
 	if l.noNamespaces {
 		// Flat layout without namespace grouping
-		return l.applyFlatHierarchicalLayout(diagram)
+		return l.applyFlatPlacement(diagram, place)
 	}
 
 	// Group nodes by namespace
-	namespaceNodes := make(map[string][]int)
-	for i, node := range diagram.Nodes {
-		ns := node.Namespace
-		if ns == "" {
-			ns = "default"
-		}
-		namespaceNodes[ns] = append(namespaceNodes[ns], i)
-	}
+	namespaces, namespaceNodes := models.GroupByNamespace(diagram.Nodes)
 
 	// Layout each namespace separately
 	currentY := 80.0
 
-	for ns, nodeIndices := range namespaceNodes {
+	for _, ns := range namespaces {
+		nodeIndices := namespaceNodes[ns]
 		nsX := 80.0
 		nsY := currentY
-		maxX := nsX
-		maxY := nsY
 
-		// Sort nodes by dependencies (topological sort)
-		sortedIndices := l.topologicalSort(nodeIndices, diagram)
-
-		// Layout nodes in levels
-		levels := l.groupIntoLevels(sortedIndices, diagram)
-
-		levelY := nsY + 80 // Space for namespace header
-		for _, level := range levels {
-			levelX := nsX + 80
-			maxLevelHeight := 0.0
-
-			for _, nodeIdx := range level {
-				diagram.Nodes[nodeIdx].X = levelX
-				diagram.Nodes[nodeIdx].Y = levelY
-				diagram.Nodes[nodeIdx].Width = 140
-				diagram.Nodes[nodeIdx].Height = 80
-
-				levelX += 220 // Horizontal spacing
-				if diagram.Nodes[nodeIdx].Height > maxLevelHeight {
-					maxLevelHeight = diagram.Nodes[nodeIdx].Height
-				}
-				if levelX > maxX {
-					maxX = levelX
-				}
-			}
-			levelY += maxLevelHeight + 80 // Vertical spacing
-			if levelY > maxY {
-				maxY = levelY
-			}
+		// Place the nodes below the namespace header
+		positions, width, height := place(nodeIndices, diagram)
+		for _, nodeIdx := range nodeIndices {
+			diagram.Nodes[nodeIdx].X = nsX + 80 + positions[nodeIdx].x
+			diagram.Nodes[nodeIdx].Y = nsY + 80 + positions[nodeIdx].y
 		}
 
 		// Create namespace group
-		nsWidth := maxX - nsX + 80
-		nsHeight := maxY - nsY + 80
+		nsWidth := width + 160
+		nsHeight := height + 160
 
 		diagram.Namespaces[ns] = models.NamespaceGroup{
 			Name:    ns,
@@ -99,14 +105,14 @@ func (l *Layout) applyHierarchicalLayout(diagram *models.Diagram) error {
 			NodeIDs: l.getNodeIDs(nodeIndices, diagram),
 		}
 
-		currentY = maxY + 150 // Space between namespaces
+		currentY = nsY + nsHeight + 70 // Space between namespaces
 	}
 
 	return nil
 }
 
-func (l *Layout) applyFlatHierarchicalLayout(diagram *models.Diagram) error {
-	// Layout all nodes in hierarchical fashion without namespace grouping
+func (l *Layout) applyFlatPlacement(diagram *models.Diagram, place placement) error {
+	// Layout all nodes without namespace grouping
 	if len(diagram.Nodes) == 0 {
 		return nil
 	}
@@ -117,32 +123,10 @@ func (l *Layout) applyFlatHierarchicalLayout(diagram *models.Diagram) error {
 		nodeIndices[i] = i
 	}
 
-	// Sort nodes by dependencies
-	sortedIndices := l.topologicalSort(nodeIndices, diagram)
-
-	// Layout nodes in levels
-	levels := l.groupIntoLevels(sortedIndices, diagram)
-
-	startX := 80.0
-	startY := 80.0
-	levelY := startY
-
-	for _, level := range levels {
-		levelX := startX
-		maxLevelHeight := 0.0
-
-		for _, nodeIdx := range level {
-			diagram.Nodes[nodeIdx].X = levelX
-			diagram.Nodes[nodeIdx].Y = levelY
-			diagram.Nodes[nodeIdx].Width = 140
-			diagram.Nodes[nodeIdx].Height = 80
-
-			levelX += 220 // Horizontal spacing
-			if diagram.Nodes[nodeIdx].Height > maxLevelHeight {
-				maxLevelHeight = diagram.Nodes[nodeIdx].Height
-			}
-		}
-		levelY += maxLevelHeight + 80 // Vertical spacing
+	positions, _, _ := place(nodeIndices, diagram)
+	for _, nodeIdx := range nodeIndices {
+		diagram.Nodes[nodeIdx].X = 80 + positions[nodeIdx].x
+		diagram.Nodes[nodeIdx].Y = 80 + positions[nodeIdx].y
 	}
 
 	return nil
@@ -150,16 +134,15 @@ func (l *Layout) applyFlatHierarchicalLayout(diagram *models.Diagram) error {
 
 func (l *Layout) applyGridLayout(diagram *models.Diagram) error {
 	cols := int(math.Ceil(math.Sqrt(float64(len(diagram.Nodes)))))
-	cellWidth := 220.0
-	cellHeight := 150.0
+	maxWidth, maxHeight := maxNodeSize(diagram.Nodes)
+	cellWidth := maxWidth + 80
+	cellHeight := maxHeight + 70
 
 	for i, _ := range diagram.Nodes {
 		row := i / cols
 		col := i % cols
 		diagram.Nodes[i].X = float64(col)*cellWidth + 80
 		diagram.Nodes[i].Y = float64(row)*cellHeight + 80
-		diagram.Nodes[i].Width = 140
-		diagram.Nodes[i].Height = 80
 	}
 
 	return nil
@@ -172,20 +155,16 @@ func (l *Layout) applyVerticalLayout(diagram *models.Diagram) error {
 	}
 
 	// Group nodes by namespace
-	namespaceNodes := make(map[string][]int)
-	for i, node := range diagram.Nodes {
-		ns := node.Namespace
-		if ns == "" {
-			ns = "default"
-		}
-		namespaceNodes[ns] = append(namespaceNodes[ns], i)
-	}
+	namespaces, namespaceNodes := models.GroupByNamespace(diagram.Nodes)
 
 	// Layout each namespace separately in vertical columns
 	currentX := 80.0
-	namespaceSpacing := 300.0 // Space between namespace columns
+	maxWidth, _ := maxNodeSize(diagram.Nodes)
+	nsWidth := math.Max(220, maxWidth+80) // Fixed width for vertical layout
+	namespaceSpacing := nsWidth + 80      // Space between namespace columns
 
-	for ns, nodeIndices := range namespaceNodes {
+	for _, ns := range namespaces {
+		nodeIndices := namespaceNodes[ns]
 		nsX := currentX
 		nsY := 80.0
 		maxY := nsY
@@ -195,17 +174,14 @@ func (l *Layout) applyVerticalLayout(diagram *models.Diagram) error {
 		for _, nodeIdx := range nodeIndices {
 			diagram.Nodes[nodeIdx].X = nsX + 80 // Offset from namespace border
 			diagram.Nodes[nodeIdx].Y = nodeY
-			diagram.Nodes[nodeIdx].Width = 140
-			diagram.Nodes[nodeIdx].Height = 80
 
-			nodeY += 160 // Vertical spacing between nodes
+			nodeY += diagram.Nodes[nodeIdx].Height + 80 // Vertical spacing between nodes
 			if nodeY > maxY {
 				maxY = nodeY
 			}
 		}
 
 		// Create namespace group
-		nsWidth := 220.0 // Fixed width for vertical layout
 		nsHeight := maxY - nsY + 80
 
 		diagram.Namespaces[ns] = models.NamespaceGroup{
@@ -236,33 +212,21 @@ func (l *Layout) applyFlatVerticalLayout(diagram *models.Diagram) error {
 	for i := range diagram.Nodes {
 		diagram.Nodes[i].X = startX
 		diagram.Nodes[i].Y = nodeY
-		diagram.Nodes[i].Width = 140
-		diagram.Nodes[i].Height = 80
 
-		nodeY += 160 // Vertical spacing between nodes
+		nodeY += diagram.Nodes[i].Height + 80 // Vertical spacing between nodes
 	}
 
 	return nil
 }
 
-func (l *Layout) topologicalSort(nodeIndices []int, diagram *models.Diagram) []int {
-	// Simple topological sort based on dependencies
-	// For simplicity, just return the original order
-	// In a real implementation, this would perform proper topological sorting
-	return nodeIndices
-}
-
-func (l *Layout) groupIntoLevels(nodeIndices []int, diagram *models.Diagram) [][]int {
-	// Group nodes into levels based on their dependencies
-	levels := make([][]int, 0)
-
-	// For simplicity, put all nodes in one level
-	// In a real implementation, this would group by dependency depth
-	if len(nodeIndices) > 0 {
-		levels = append(levels, nodeIndices)
+// maxNodeSize returns the largest width and height of the nodes
+func maxNodeSize(nodes []models.DiagramNode) (float64, float64) {
+	width, height := 0.0, 0.0
+	for _, node := range nodes {
+		width = math.Max(width, node.Width)
+		height = math.Max(height, node.Height)
 	}
-
-	return levels
+	return width, height
 }
 
 func (l *Layout) getNodeIDs(nodeIndices []int, diagram *models.Diagram) []string {
@@ -0,0 +1,153 @@
+package drawio
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"k8s-to-drawio/pkg/models"
+)
+
+// legendID is the ID of the legend container, its entries use it as prefix
+const legendID = "legend"
+
+// Layout of the legend: one row per entry with a sample and its label
+const (
+	legendMargin       = 20.0
+	legendTitleHeight  = 30.0
+	legendRowHeight    = 40.0
+	legendSampleWidth  = 40.0
+	legendSampleHeight = 30.0
+	legendLabelWidth   = 180.0
+)
+
+// LegendStyle is the style of the legend container
+const LegendStyle = "swimlane;fontStyle=1;horizontal=1;startSize=30;container=1;collapsible=1;marginBottom=0;fillColor=#ffffff;strokeColor=#666666;"
+
+// LegendLabelStyle is the style of the labels of legend entries
+const LegendLabelStyle = "text;html=1;align=left;verticalAlign=middle;whiteSpace=wrap;"
+
+// legendEntry is a shape or connection style with the kinds or relations drawn with it
+type legendEntry struct {
+	style  string
+	labels []string
+}
+
+// legendCells returns a legend of the shape and connection styles used in a laid out
+// diagram. It is placed to the right of the namespaces and resources, or where the
+// legend of the diagram being updated was moved to.
+func (g *Generator) legendCells(diagram *models.Diagram) []Cell {
+	var shapes []*legendEntry
+	shapeEntries := make(map[string]*legendEntry)
+	for _, node := range diagram.Nodes {
+		style, _ := g.nodeStyle(node)
+		shapes = addLegendEntry(shapes, shapeEntries, style, node.Kind)
+	}
+
+	var connections []*legendEntry
+	connectionEntries := make(map[string]*legendEntry)
+	for _, connection := range diagram.Connections {
+		label, style := g.connectionStyle(connection)
+		connections = addLegendEntry(connections, connectionEntries, style, label)
+	}
+	if len(shapes) == 0 && len(connections) == 0 {
+		return nil
+	}
+	sortLegendEntries(shapes)
+	sortLegendEntries(connections)
+
+	x, y := g.legendPosition(diagram)
+	width := legendMargin + legendSampleWidth + 10 + legendLabelWidth + legendMargin
+	height := legendTitleHeight + float64(len(shapes)+len(connections))*legendRowHeight + legendMargin/2
+	cells := []Cell{NewShape(legendID, "Legend", mergeStyle(LegendStyle, g.theme.Legend), "1", x, y, width, height)}
+
+	labelStyle := LegendLabelStyle
+	if fontColor, exists := g.theme.Legend["fontColor"]; exists {
+		labelStyle = mergeStyle(labelStyle, StyleAttributes{"fontColor": fontColor})
+	}
+	label := func(id string, entry *legendEntry, rowY float64) Cell {
+		return NewShape(id+"-label", strings.Join(entry.labels, ", "), labelStyle, legendID,
+			legendMargin+legendSampleWidth+10, rowY, legendLabelWidth, legendSampleHeight)
+	}
+
+	rowY := legendTitleHeight + (legendRowHeight-legendSampleHeight)/2
+	for i, entry := range shapes {
+		id := fmt.Sprintf("%s-shape-%d", legendID, i+1)
+		cells = append(cells,
+			NewShape(id, "", entry.style, legendID, legendMargin, rowY, legendSampleWidth, legendSampleHeight),
+			label(id, entry, rowY))
+		rowY += legendRowHeight
+	}
+	for i, entry := range connections {
+		id := fmt.Sprintf("%s-edge-%d", legendID, i+1)
+		lineY := rowY + legendSampleHeight/2
+		cells = append(cells,
+			Cell{
+				ID:     id,
+				Style:  entry.style,
+				Edge:   true,
+				Parent: legendID,
+				Geometry: &Geometry{Relative: "1", As: "geometry", Points: []Point{
+					{X: legendMargin, Y: lineY, As: "sourcePoint"},
+					{X: legendMargin + legendSampleWidth, Y: lineY, As: "targetPoint"},
+				}},
+			},
+			label(id, entry, rowY))
+		rowY += legendRowHeight
+	}
+
+	return cells
+}
+
+// legendPosition returns the position of the legend: where the user moved it when
+// updating a diagram, otherwise to the right of the diagram, aligned with its top
+func (g *Generator) legendPosition(diagram *models.Diagram) (float64, float64) {
+	if g.existing != nil {
+		if cell, exists := g.existing.Cell(legendID); exists && cell.Vertex {
+			return g.existing.AbsolutePosition(legendID)
+		}
+	}
+
+	right, top := 0.0, math.Inf(1)
+	for _, namespace := range diagram.Namespaces {
+		right = math.Max(right, namespace.X+namespace.Width)
+		top = math.Min(top, namespace.Y)
+	}
+	for _, node := range diagram.Nodes {
+		right = math.Max(right, node.X+node.Width)
+		top = math.Min(top, node.Y)
+	}
+	if math.IsInf(top, 1) {
+		top = 80
+	}
+	return right + 80, top
+}
+
+// addLegendEntry adds a label to the entry of a style, creating the entry when the
+// style is used for the first time
+func addLegendEntry(entries []*legendEntry, byStyle map[string]*legendEntry, style, label string) []*legendEntry {
+	entry, exists := byStyle[style]
+	if !exists {
+		entry = &legendEntry{style: style}
+		byStyle[style] = entry
+		entries = append(entries, entry)
+	}
+	for _, existing := range entry.labels {
+		if existing == label {
+			return entries
+		}
+	}
+	entry.labels = append(entry.labels, label)
+	return entries
+}
+
+// sortLegendEntries sorts the labels of every entry and the entries by their labels
+func sortLegendEntries(entries []*legendEntry) {
+	for _, entry := range entries {
+		sort.Strings(entry.labels)
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		return strings.Join(entries[i].labels, ", ") < strings.Join(entries[j].labels, ", ")
+	})
+}
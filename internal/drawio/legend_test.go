@@ -0,0 +1,101 @@
+package drawio
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"k8s-to-drawio/pkg/models"
+)
+
+func TestLegend(t *testing.T) {
+	diagram := legendDiagram()
+	cells := generateCells(t, Options{Layout: "hierarchical", Legend: true}, diagram)
+
+	// Kinds drawn with the same shape share an entry, as do connections of one style
+	var shapes, connections []string
+	for _, cell := range cells {
+		if cell.Parent != legendID || !strings.HasSuffix(cell.ID, "-label") {
+			continue
+		}
+		if strings.HasPrefix(cell.ID, legendID+"-shape-") {
+			shapes = append(shapes, cell.Value)
+		} else {
+			connections = append(connections, cell.Value)
+		}
+	}
+	if want := []string{"Deployment, Widget", "Service"}; !reflect.DeepEqual(shapes, want) {
+		t.Errorf("shape entries = %q, want %q", shapes, want)
+	}
+	if want := []string{"mounts", "mounts (optional)", "selects"}; !reflect.DeepEqual(connections, want) {
+		t.Errorf("connection entries = %q, want %q", connections, want)
+	}
+
+	// The legend is placed to the right of the diagram, aligned with its top
+	byID := cellsByID(cells)
+	legend := byID[legendID].Bounds()
+	for _, node := range diagram.Nodes {
+		if legend.X < node.X+node.Width {
+			t.Errorf("legend at x=%v overlaps %s ending at x=%v", legend.X, node.ID, node.X+node.Width)
+		}
+	}
+	if namespace := diagram.Namespaces["shop"]; legend.Y != namespace.Y {
+		t.Errorf("legend at y=%v, want %v", legend.Y, namespace.Y)
+	}
+	if want := legendTitleHeight + 5*legendRowHeight + legendMargin/2; legend.Height != want {
+		t.Errorf("legend height = %v, want %v for 5 entries", legend.Height, want)
+	}
+}
+
+func TestLegendOptions(t *testing.T) {
+	hasLegend := func(cells []Cell) bool {
+		_, exists := cellsByID(cells)[legendID]
+		return exists
+	}
+
+	if hasLegend(generateCells(t, Options{Layout: "hierarchical"}, legendDiagram())) {
+		t.Error("legend generated without the Legend option")
+	}
+	if hasLegend(generateCells(t, Options{Layout: "hierarchical", Legend: true}, testDiagram(nil))) {
+		t.Error("legend generated for an empty diagram")
+	}
+
+	// The legend keeps its position when the diagram is updated
+	cells := generateCells(t, Options{Layout: "hierarchical", Legend: true}, legendDiagram())
+	moveCell(cells, legendID, -400, 20)
+	updated := generateCells(t, Options{Layout: "hierarchical", Legend: true, Existing: existingDiagram(cells)}, legendDiagram())
+	if bounds := cellsByID(updated)[legendID].Bounds(); bounds.X != -400 || bounds.Y != 20 {
+		t.Errorf("moved legend at (%v, %v), want (-400, 20)", bounds.X, bounds.Y)
+	}
+
+	// Themes style the container and the labels of its entries
+	theme := &Theme{Legend: StyleAttributes{"fillColor": "#202020", "fontColor": "#eeeeee"}}
+	themed := cellsByID(generateCells(t, Options{Layout: "hierarchical", Legend: true, Theme: theme}, legendDiagram()))
+	if style := themed[legendID].Style; !strings.Contains(style, "fillColor=#202020;") {
+		t.Errorf("themed legend style = %q", style)
+	}
+	if style := themed[legendID+"-shape-1-label"].Style; !strings.Contains(style, "fontColor=#eeeeee;") {
+		t.Errorf("themed legend label style = %q", style)
+	}
+}
+
+// legendDiagram has an unknown kind drawn like a Deployment and an optional connection
+func legendDiagram() *models.Diagram {
+	diagram := testDiagram(
+		[]models.DiagramNode{
+			testNode("shop", "Service", "web"),
+			testNode("shop", "Deployment", "web"),
+			testNode("shop", "Widget", "web"),
+		},
+		"shop-Service-web", "shop-Deployment-web",
+		"shop-Deployment-web", "shop-Widget-web",
+		"shop-Service-web", "shop-Widget-web",
+	)
+	relations := []models.Relation{models.RelationSelects, models.RelationMounts, models.RelationMounts}
+	for i, relation := range relations {
+		diagram.Connections[i].Relation = relation
+		diagram.Connections[i].Label = string(relation)
+	}
+	diagram.Connections[2].Optional = true
+	return diagram
+}
@@ -0,0 +1,445 @@
+package drawio
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+)
+
+// MxFile is the root element of a Draw.io file, holding one diagram per page
+type MxFile struct {
+	XMLName  xml.Name   `xml:"mxfile"`
+	Host     string     `xml:"host,attr,omitempty"`
+	Modified string     `xml:"modified,attr,omitempty"`
+	Agent    string     `xml:"agent,attr,omitempty"`
+	Version  string     `xml:"version,attr,omitempty"`
+	Etag     string     `xml:"etag,attr,omitempty"`
+	Type     string     `xml:"type,attr,omitempty"`
+	Attrs    []xml.Attr `xml:",any,attr"`
+	Diagrams []Diagram  `xml:"diagram"`
+}
+
+// Diagram is a page of a Draw.io file. Draw.io stores the graph model either as an
+// mxGraphModel element or compressed: deflated, base64 encoded text.
+type Diagram struct {
+	ID         string
+	Name       string
+	Model      GraphModel
+	Compressed bool // write the model compressed, set when reading a compressed page
+}
+
+// GraphModel is the mxGraphModel of a page
+type GraphModel struct {
+	XMLName    xml.Name   `xml:"mxGraphModel"`
+	Dx         float64    `xml:"dx,attr,omitempty"`
+	Dy         float64    `xml:"dy,attr,omitempty"`
+	Grid       string     `xml:"grid,attr,omitempty"`
+	GridSize   string     `xml:"gridSize,attr,omitempty"`
+	Guides     string     `xml:"guides,attr,omitempty"`
+	Tooltips   string     `xml:"tooltips,attr,omitempty"`
+	Connect    string     `xml:"connect,attr,omitempty"`
+	Arrows     string     `xml:"arrows,attr,omitempty"`
+	Fold       string     `xml:"fold,attr,omitempty"`
+	Page       string     `xml:"page,attr,omitempty"`
+	PageScale  string     `xml:"pageScale,attr,omitempty"`
+	PageWidth  string     `xml:"pageWidth,attr,omitempty"`
+	PageHeight string     `xml:"pageHeight,attr,omitempty"`
+	Math       string     `xml:"math,attr,omitempty"`
+	Shadow     string     `xml:"shadow,attr,omitempty"`
+	Background string     `xml:"background,attr,omitempty"`
+	Attrs      []xml.Attr `xml:",any,attr"`
+	Root       Root       `xml:"root"`
+}
+
+// Root holds the cells of a graph model in drawing order
+type Root struct {
+	Cells []Cell
+}
+
+// Cell is an mxCell: a vertex, an edge, or one of the two root cells. Cells with an
+// Object are wrapped in a UserObject or object element, which holds the ID, the
+// label and custom properties of the cell.
+type Cell struct {
+	ID         string
+	Value      string // label, the label attribute of the wrapping object
+	Style      string
+	Vertex     bool
+	Edge       bool
+	Parent     string
+	Source     string
+	Target     string
+	Attrs      []xml.Attr // other mxCell attributes, such as collapsed or connectable
+	Geometry   *Geometry
+	Object     string     // "UserObject" or "object" if the cell is wrapped
+	Properties []xml.Attr // attributes of the wrapping object besides id and label
+}
+
+// Geometry is the mxGeometry of a cell. Vertices have a position relative to their
+// parent and a size, edges a relative geometry with optional waypoints.
+type Geometry struct {
+	X        float64    `xml:"x,attr,omitempty"`
+	Y        float64    `xml:"y,attr,omitempty"`
+	Width    float64    `xml:"width,attr,omitempty"`
+	Height   float64    `xml:"height,attr,omitempty"`
+	Relative string     `xml:"relative,attr,omitempty"`
+	As       string     `xml:"as,attr"`
+	Attrs    []xml.Attr `xml:",any,attr"`
+	Points   []Point    `xml:"mxPoint"`
+	Array    *Array     `xml:"Array"`
+	Extra    []Element  `xml:",any"`
+}
+
+// Point is an mxPoint, such as the source or target point of an edge
+type Point struct {
+	X  float64 `xml:"x,attr,omitempty"`
+	Y  float64 `xml:"y,attr,omitempty"`
+	As string  `xml:"as,attr,omitempty"`
+}
+
+// Array is a list of points, the waypoints of an edge
+type Array struct {
+	As     string  `xml:"as,attr"`
+	Points []Point `xml:"mxPoint"`
+}
+
+// Element is an element the model has no type for, kept so it can be written back unchanged
+type Element struct {
+	XMLName xml.Name
+	Attrs   []xml.Attr `xml:",any,attr"`
+	Inner   []byte     `xml:",innerxml"`
+}
+
+// mxCell is the XML form of a Cell
+type mxCell struct {
+	XMLName  xml.Name   `xml:"mxCell"`
+	ID       string     `xml:"id,attr,omitempty"`
+	Value    *string    `xml:"value,attr"`
+	Style    string     `xml:"style,attr,omitempty"`
+	Vertex   string     `xml:"vertex,attr,omitempty"`
+	Edge     string     `xml:"edge,attr,omitempty"`
+	Parent   string     `xml:"parent,attr,omitempty"`
+	Source   string     `xml:"source,attr,omitempty"`
+	Target   string     `xml:"target,attr,omitempty"`
+	Attrs    []xml.Attr `xml:",any,attr"`
+	Geometry *geometry  `xml:"mxGeometry"`
+}
+
+// geometry is the XML form of a Geometry
+type geometry struct {
+	XMLName xml.Name `xml:"mxGeometry"`
+	Geometry
+}
+
+// object is the XML form of the UserObject or object wrapping a cell
+type object struct {
+	XMLName xml.Name
+	Label   string     `xml:"label,attr"`
+	ID      string     `xml:"id,attr"`
+	Attrs   []xml.Attr `xml:",any,attr"`
+	Cell    mxCell     `xml:"mxCell"`
+}
+
+// NewFile returns an empty Draw.io file written by this tool
+func NewFile() *MxFile {
+	return &MxFile{
+		Host:    "Electron",
+		Agent:   "k8s-to-drawio",
+		Version: "1.0.0",
+		Etag:    "k8s-diagram",
+		Type:    "device",
+	}
+}
+
+// NewGraphModel returns a graph model with the default page settings and the two root cells
+func NewGraphModel() GraphModel {
+	return GraphModel{
+		Dx: 1422, Dy: 794,
+		Grid: "1", GridSize: "10", Guides: "1", Tooltips: "1", Connect: "1", Arrows: "1", Fold: "1",
+		Page: "1", PageScale: "1", PageWidth: "827", PageHeight: "1169", Math: "0", Shadow: "0",
+		Root: Root{Cells: []Cell{{ID: "0"}, {ID: "1", Parent: "0"}}},
+	}
+}
+
+// Encode writes the file as indented XML
+func (f *MxFile) Encode(w io.Writer) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(f); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// ParseFile reads a Draw.io file, decompressing compressed pages
+func ParseFile(data []byte) (*MxFile, error) {
+	var file MxFile
+	if err := xml.Unmarshal(data, &file); err != nil {
+		return nil, err
+	}
+	return &file, nil
+}
+
+// MarshalXML writes the page with its model as an element or compressed
+func (d Diagram) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	start.Attr = []xml.Attr{
+		{Name: xml.Name{Local: "id"}, Value: d.ID},
+		{Name: xml.Name{Local: "name"}, Value: d.Name},
+	}
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+
+	if d.Compressed {
+		model, err := xml.Marshal(d.Model)
+		if err != nil {
+			return err
+		}
+		compressed, err := compress(model)
+		if err != nil {
+			return err
+		}
+		if err := e.EncodeToken(xml.CharData(compressed)); err != nil {
+			return err
+		}
+	} else if err := e.Encode(d.Model); err != nil {
+		return err
+	}
+
+	return e.EncodeToken(start.End())
+}
+
+// UnmarshalXML reads a page holding either an mxGraphModel element or compressed text
+func (d *Diagram) UnmarshalXML(decoder *xml.Decoder, start xml.StartElement) error {
+	for _, attr := range start.Attr {
+		switch attr.Name.Local {
+		case "id":
+			d.ID = attr.Value
+		case "name":
+			d.Name = attr.Value
+		}
+	}
+
+	var text strings.Builder
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return err
+		}
+
+		switch token := token.(type) {
+		case xml.StartElement:
+			if token.Name.Local != "mxGraphModel" {
+				if err := decoder.Skip(); err != nil {
+					return err
+				}
+				continue
+			}
+			if err := decoder.DecodeElement(&d.Model, &token); err != nil {
+				return err
+			}
+		case xml.CharData:
+			text.Write(token)
+		case xml.EndElement:
+			compressed := strings.TrimSpace(text.String())
+			if compressed == "" {
+				return nil
+			}
+			model, err := decompress(compressed)
+			if err != nil {
+				return fmt.Errorf("failed to decompress page %q: %w", d.Name, err)
+			}
+			if err := xml.Unmarshal(model, &d.Model); err != nil {
+				return fmt.Errorf("failed to parse page %q: %w", d.Name, err)
+			}
+			d.Compressed = true
+			return nil
+		}
+	}
+}
+
+// MarshalXML writes the cells of the root in order
+func (r Root) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+	for _, cell := range r.Cells {
+		if err := e.Encode(cell); err != nil {
+			return err
+		}
+	}
+	return e.EncodeToken(start.End())
+}
+
+// UnmarshalXML reads mxCell, UserObject and object elements in order
+func (r *Root) UnmarshalXML(decoder *xml.Decoder, start xml.StartElement) error {
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return err
+		}
+
+		switch token := token.(type) {
+		case xml.StartElement:
+			var cell Cell
+			if err := decoder.DecodeElement(&cell, &token); err != nil {
+				return err
+			}
+			r.Cells = append(r.Cells, cell)
+		case xml.EndElement:
+			return nil
+		}
+	}
+}
+
+// MarshalXML writes the cell as an mxCell, wrapped in its object if it has one
+func (c Cell) MarshalXML(e *xml.Encoder, _ xml.StartElement) error {
+	cell := mxCell{
+		Style:  c.Style,
+		Parent: c.Parent,
+		Source: c.Source,
+		Target: c.Target,
+		Attrs:  c.Attrs,
+	}
+	if c.Vertex {
+		cell.Vertex = "1"
+	}
+	if c.Edge {
+		cell.Edge = "1"
+	}
+	if c.Geometry != nil {
+		cell.Geometry = &geometry{Geometry: *c.Geometry}
+	}
+
+	if c.Object == "" {
+		cell.ID = c.ID
+		if c.Vertex || c.Edge || c.Value != "" {
+			cell.Value = &c.Value
+		}
+		return e.Encode(cell)
+	}
+
+	return e.Encode(object{
+		XMLName: xml.Name{Local: c.Object},
+		Label:   c.Value,
+		ID:      c.ID,
+		Attrs:   c.Properties,
+		Cell:    cell,
+	})
+}
+
+// UnmarshalXML reads an mxCell, or a UserObject or object wrapping one
+func (c *Cell) UnmarshalXML(decoder *xml.Decoder, start xml.StartElement) error {
+	var cell mxCell
+	if start.Name.Local == "mxCell" {
+		if err := decoder.DecodeElement(&cell, &start); err != nil {
+			return err
+		}
+		c.ID = cell.ID
+		if cell.Value != nil {
+			c.Value = *cell.Value
+		}
+	} else {
+		var wrapper object
+		if err := decoder.DecodeElement(&wrapper, &start); err != nil {
+			return err
+		}
+		cell = wrapper.Cell
+		c.ID = wrapper.ID
+		c.Value = wrapper.Label
+		c.Object = start.Name.Local
+		c.Properties = wrapper.Attrs
+	}
+
+	c.Style = cell.Style
+	c.Vertex = cell.Vertex == "1"
+	c.Edge = cell.Edge == "1"
+	c.Parent = cell.Parent
+	c.Source = cell.Source
+	c.Target = cell.Target
+	c.Attrs = cell.Attrs
+	if cell.Geometry != nil {
+		c.Geometry = &cell.Geometry.Geometry
+	}
+	return nil
+}
+
+// Bounds returns the position relative to the parent and the size of a cell, zero
+// for cells without geometry
+func (c Cell) Bounds() Geometry {
+	if c.Geometry == nil {
+		return Geometry{}
+	}
+	return Geometry{X: c.Geometry.X, Y: c.Geometry.Y, Width: c.Geometry.Width, Height: c.Geometry.Height}
+}
+
+// Property returns a property of the object wrapping the cell
+func (c Cell) Property(name string) (string, bool) {
+	for _, attr := range c.Properties {
+		if attr.Name.Local == name {
+			return attr.Value, true
+		}
+	}
+	return "", false
+}
+
+// compress encodes a graph model like Draw.io: URI component encoded, deflated and base64 encoded
+func compress(model []byte) (string, error) {
+	var deflated bytes.Buffer
+	writer, err := flate.NewWriter(&deflated, flate.BestCompression)
+	if err != nil {
+		return "", err
+	}
+	if _, err := writer.Write([]byte(encodeURIComponent(string(model)))); err != nil {
+		return "", err
+	}
+	if err := writer.Close(); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(deflated.Bytes()), nil
+}
+
+// decompress decodes a compressed graph model
+func decompress(text string) ([]byte, error) {
+	deflated, err := base64.StdEncoding.DecodeString(text)
+	if err != nil {
+		return nil, err
+	}
+	model, err := io.ReadAll(flate.NewReader(bytes.NewReader(deflated)))
+	if err != nil {
+		return nil, err
+	}
+	// Old versions of Draw.io did not URI encode the model before deflating it, their
+	// models start with the XML itself and may contain a literal '%' in labels
+	if bytes.HasPrefix(model, []byte("<")) {
+		return model, nil
+	}
+	decoded, err := url.PathUnescape(string(model))
+	if err != nil {
+		return nil, err
+	}
+	return []byte(decoded), nil
+}
+
+// encodeURIComponent escapes a string like JavaScript's encodeURIComponent
+func encodeURIComponent(s string) string {
+	const unreserved = "-_.!~*'()"
+
+	var b strings.Builder
+	for _, c := range []byte(s) {
+		if 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' || strings.IndexByte(unreserved, c) >= 0 {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
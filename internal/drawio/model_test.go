@@ -0,0 +1,211 @@
+package drawio
+
+import (
+	"bytes"
+	"encoding/xml"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFileRoundTrip(t *testing.T) {
+	for _, compressed := range []bool{false, true} {
+		name := "plain"
+		if compressed {
+			name = "compressed"
+		}
+
+		t.Run(name, func(t *testing.T) {
+			file := NewFile()
+			file.Attrs = []xml.Attr{{Name: xml.Name{Local: "compressed"}, Value: "false"}}
+			file.Diagrams = []Diagram{
+				{ID: "page-1", Name: "Overview & details", Model: testGraphModel(), Compressed: compressed},
+				{ID: "page-2", Name: "Empty", Model: NewGraphModel(), Compressed: compressed},
+			}
+
+			var encoded bytes.Buffer
+			if err := file.Encode(&encoded); err != nil {
+				t.Fatalf("Encode: %v", err)
+			}
+			if got := strings.Contains(encoded.String(), "<mxGraphModel"); got == compressed {
+				t.Errorf("encoded file contains an mxGraphModel element: %v, want %v", got, !compressed)
+			}
+
+			decoded, err := ParseFile(encoded.Bytes())
+			if err != nil {
+				t.Fatalf("ParseFile: %v", err)
+			}
+
+			if decoded.Agent != file.Agent || decoded.Host != file.Host || !reflect.DeepEqual(decoded.Attrs, file.Attrs) {
+				t.Errorf("file attributes = %+v, want %+v", decoded, file)
+			}
+			if len(decoded.Diagrams) != len(file.Diagrams) {
+				t.Fatalf("decoded %d pages, want %d", len(decoded.Diagrams), len(file.Diagrams))
+			}
+			for i, want := range file.Diagrams {
+				got := decoded.Diagrams[i]
+				if got.ID != want.ID || got.Name != want.Name || got.Compressed != want.Compressed {
+					t.Errorf("page %d = %q %q compressed=%v, want %q %q compressed=%v",
+						i, got.ID, got.Name, got.Compressed, want.ID, want.Name, want.Compressed)
+				}
+				if got.Model.Dx != want.Model.Dx || got.Model.Background != want.Model.Background || got.Model.PageWidth != want.Model.PageWidth {
+					t.Errorf("page %d model attributes = %+v, want %+v", i, got.Model, want.Model)
+				}
+				compareCells(t, got.Model.Root.Cells, want.Model.Root.Cells)
+			}
+
+			// Writing the decoded file again gives the same document
+			var reencoded bytes.Buffer
+			if err := decoded.Encode(&reencoded); err != nil {
+				t.Fatalf("Encode: %v", err)
+			}
+			if reencoded.String() != encoded.String() {
+				t.Errorf("re-encoded file differs:\n%s\nwant:\n%s", reencoded.String(), encoded.String())
+			}
+		})
+	}
+}
+
+func TestParseFileCompressedByDrawio(t *testing.T) {
+	// Pages deflated by Draw.io, with and without the URI encoding of old versions
+	tests := []struct {
+		name  string
+		page  string
+		label string
+	}{
+		{
+			name:  "URI encoded",
+			page:  "dVHLDoIwEPyavZdWjV4F5WQ8+QFFVkALJaUK/L0tLQKJHJrszszOPgosLLtY8Tq/yBQFsBOwUEmpXVR2IQoBlBQpsAgoJeYBPa+wwcCSmius9J+CW4Pqmjzxro1K8MQ2tKKQP4DugLIOD8COhgyIKdw6u9G9xcQBr6LyUIS1kH1puxlmOVSje4FOpuS7StHWBIO9Nfmg0titzh3MDGOUJWrVG8lY4NYivUv9lqQtUp17xcZjORZZ7k33HuONy7Of8XQjE/gVxnR2tRGafmiQLz7wCw==",
+			label: "Café 100%",
+		},
+		{
+			name:  "not URI encoded",
+			page:  "VVDLDoIwEPyVZk28AsaDBuoFE07Gkx9Q7ApoS0mp0v69yyMaTjuzk5l9ZNoXVnT1xUhUp8wa406Z9jkqxRrJIYZoxRNgnbDYukW69Wiv5RPvjilRouKQi8d24/GYsiSOYTINWAJ7NS3BM3bKBE0B8MvtXVDIwZp3K1HyJAX2QevQr8Ylk6FAo9HZwEaR4gOHHZWhka6mzp5wjU1Vk+FAWPQcqsUyrhvNIwn8Fycynx2tfvEF",
+			label: "Café 100",
+		},
+		{
+			name:  "not URI encoded with a percent sign",
+			page:  "VZDJCsIwEIZfJQx47oKCYNOLQk/iyQdIzdBUs5Q02ubtnS4oPc0/yzdbYcbKi05dnURdFt65UBZmPKPWrJUcUkg2fgasEx5tWFP3Hv2tfuIjMC1q1FSRprv8wKSzCDMyYA3s1VqSF+y0i4Zw+HXtQ9TIwbu3lSh5dgL2QR9w3AzLZqBCZzD4yKZkCixyyMkMrQyKInvSCttGEXAkLXoOzYpMyybLSBL/tclZjk42n/gC",
+			label: "100%25 done",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file, err := ParseFile([]byte(`<mxfile><diagram id="p" name="Page-1">` + tt.page + `</diagram></mxfile>`))
+			if err != nil {
+				t.Fatalf("ParseFile: %v", err)
+			}
+
+			page := file.Diagrams[0]
+			if !page.Compressed {
+				t.Errorf("page is not marked compressed")
+			}
+			cells := page.Model.Root.Cells
+			if len(cells) != 3 {
+				t.Fatalf("decoded %d cells, want 3", len(cells))
+			}
+
+			cell := cells[2]
+			if cell.ID != "web" || cell.Object != "UserObject" || cell.Value != tt.label || !cell.Vertex || cell.Parent != "1" {
+				t.Errorf("cell = %+v", cell)
+			}
+			if kind, _ := cell.Property("kind"); kind != "Deployment" {
+				t.Errorf("kind property = %q, want Deployment", kind)
+			}
+			if bounds := cell.Bounds(); !reflect.DeepEqual(bounds, Geometry{X: 10, Y: 20, Width: 140, Height: 80}) {
+				t.Errorf("bounds = %+v", bounds)
+			}
+		})
+	}
+}
+
+func TestCompressRoundTrip(t *testing.T) {
+	for _, model := range []string{
+		"",
+		`<mxGraphModel><root><mxCell id="0"/></root></mxGraphModel>`,
+		"100% <b>&amp;</b> ünïcode; a+b=c?d#e",
+	} {
+		compressed, err := compress([]byte(model))
+		if err != nil {
+			t.Fatalf("compress(%q): %v", model, err)
+		}
+		decompressed, err := decompress(compressed)
+		if err != nil {
+			t.Fatalf("decompress(%q): %v", compressed, err)
+		}
+		if string(decompressed) != model {
+			t.Errorf("decompress(compress(%q)) = %q", model, decompressed)
+		}
+	}
+}
+
+func TestEncodeURIComponent(t *testing.T) {
+	tests := map[string]string{
+		"abcXYZ019":           "abcXYZ019",
+		"-_.!~*'()":           "-_.!~*'()",
+		"a b/c?d=e&f#g":       "a%20b%2Fc%3Fd%3De%26f%23g",
+		"<mxCell id=\"1\"/>":  "%3CmxCell%20id%3D%221%22%2F%3E",
+		"100%":                "100%25",
+		"é€":                  "%C3%A9%E2%82%AC",
+		"line\nbreak\ttab+;,": "line%0Abreak%09tab%2B%3B%2C",
+	}
+
+	for input, want := range tests {
+		if got := encodeURIComponent(input); got != want {
+			t.Errorf("encodeURIComponent(%q) = %q, want %q", input, got, want)
+		}
+	}
+}
+
+// testGraphModel returns a model with every kind of cell the model reads and writes
+func testGraphModel() GraphModel {
+	model := NewGraphModel()
+	model.Background = "#FFFFFF"
+	model.Root.Cells = append(model.Root.Cells,
+		Cell{
+			ID: "ns-shop", Value: "Namespace: shop", Style: NamespaceStyle, Vertex: true, Parent: "1",
+			Attrs:    []xml.Attr{{Name: xml.Name{Local: "collapsed"}, Value: "1"}},
+			Geometry: &Geometry{X: 80, Y: 80, Width: 400, Height: 300, As: "geometry"},
+		},
+		Cell{
+			ID: "web", Value: "web <b>&</b> \"api\"", Style: "rounded=1;html=1;", Vertex: true, Parent: "ns-shop",
+			Geometry:   &Geometry{X: 80, Y: 80, Width: 140, Height: 80, As: "geometry"},
+			Object:     "UserObject",
+			Properties: []xml.Attr{{Name: xml.Name{Local: "kind"}, Value: "Deployment"}, {Name: xml.Name{Local: "link"}, Value: "https://example.com/?a=1&b=2"}},
+		},
+		Cell{
+			ID: "note", Value: "hand-drawn", Vertex: true, Parent: "1",
+			Geometry:   &Geometry{Width: 100, Height: 40, As: "geometry"},
+			Object:     "object",
+			Properties: []xml.Attr{{Name: xml.Name{Local: "owner"}, Value: "team-a"}},
+		},
+		Cell{
+			ID: "edge", Style: "endArrow=block;", Edge: true, Parent: "1", Source: "web", Target: "note",
+			Geometry: &Geometry{
+				Relative: "1", As: "geometry",
+				Points: []Point{{X: 10, Y: 20, As: "sourcePoint"}, {X: 30, Y: 40, As: "targetPoint"}},
+				Array:  &Array{As: "points", Points: []Point{{X: 150, Y: 60}, {X: 150, Y: 200}}},
+			},
+		},
+		Cell{ID: "label-only", Value: "0"},
+	)
+	return model
+}
+
+// compareCells checks that decoded cells equal the encoded ones
+func compareCells(t *testing.T, got, want []Cell) {
+	t.Helper()
+
+	if len(got) != len(want) {
+		t.Fatalf("decoded %d cells, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if !reflect.DeepEqual(got[i], want[i]) {
+			t.Errorf("cell %d = %+v, want %+v", i, got[i], want[i])
+			if got[i].Geometry != nil && want[i].Geometry != nil {
+				t.Errorf("geometry %+v, want %+v", *got[i].Geometry, *want[i].Geometry)
+			}
+		}
+	}
+}
@@ -0,0 +1,177 @@
+package drawio
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+
+	"k8s-to-drawio/pkg/models"
+)
+
+// OverviewPageID is the ID of the overview page of multi-page diagrams
+const OverviewPageID = "overview"
+
+// namespacePageID returns the ID of the detail page of a namespace
+func namespacePageID(namespace string) string {
+	return "page-" + namespace
+}
+
+// overviewEdgeID returns the ID of the aggregated connection between two namespaces.
+// The names are hashed like the IDs of resource connections, since joining them
+// would be ambiguous for names containing the separator.
+func overviewEdgeID(source, target string) string {
+	sum := sha256.Sum256([]byte(source + "|" + target))
+	return "edge-" + hex.EncodeToString(sum[:4])
+}
+
+// generatePages lays out an overview page with one box per namespace, linking to
+// its detail page, and the aggregated connections between namespaces, followed by
+// one page per namespace with its resources and the connections between them.
+func (g *Generator) generatePages(diagram *models.Diagram) ([]page, error) {
+	namespaceOf := make(map[string]string, len(diagram.Nodes))
+	namespaceNodes := make(map[string][]models.DiagramNode)
+	for _, node := range diagram.Nodes {
+		ns := node.Namespace
+		if ns == "" {
+			ns = "default"
+		}
+		namespaceOf[node.ID] = ns
+		namespaceNodes[ns] = append(namespaceNodes[ns], node)
+	}
+
+	namespaces := make([]string, 0, len(namespaceNodes))
+	for ns := range namespaceNodes {
+		namespaces = append(namespaces, ns)
+	}
+	sort.Strings(namespaces)
+
+	overview, err := g.overviewPage(diagram, namespaces, namespaceNodes, namespaceOf)
+	if err != nil {
+		return nil, err
+	}
+	pages := []page{overview}
+
+	for _, ns := range namespaces {
+		detail := &models.Diagram{
+			Nodes:       namespaceNodes[ns],
+			Connections: make([]models.Connection, 0),
+			Layout:      diagram.Layout,
+			Namespaces:  make(map[string]models.NamespaceGroup),
+		}
+		for _, connection := range diagram.Connections {
+			if namespaceOf[connection.SourceID] == ns && namespaceOf[connection.TargetID] == ns {
+				detail.Connections = append(detail.Connections, connection)
+			}
+		}
+
+		if err := g.layout.ApplyLayout(detail); err != nil {
+			return nil, fmt.Errorf("failed to apply layout to namespace %s: %w", ns, err)
+		}
+		g.fitNodesToShapes(detail)
+		cells, err := g.cells(detail)
+		if err != nil {
+			return nil, err
+		}
+		pages = append(pages, page{id: namespacePageID(ns), name: ns, cells: cells})
+	}
+
+	return pages, nil
+}
+
+// overviewPage lays out the namespaces as boxes with the aggregated connections between them
+func (g *Generator) overviewPage(diagram *models.Diagram, namespaces []string, namespaceNodes map[string][]models.DiagramNode, namespaceOf map[string]string) (page, error) {
+	overview := &models.Diagram{
+		Nodes:       make([]models.DiagramNode, 0, len(namespaces)),
+		Connections: make([]models.Connection, 0),
+		Layout:      diagram.Layout,
+		Namespaces:  make(map[string]models.NamespaceGroup),
+	}
+	for _, ns := range namespaces {
+		overview.Nodes = append(overview.Nodes, models.DiagramNode{
+			ID:        namespaceCellID(ns),
+			Label:     ns,
+			Kind:      "Namespace",
+			Namespace: ns,
+		})
+	}
+
+	// Aggregate the connections between every pair of namespaces
+	type namespacePair struct{ source, target string }
+	relations := make(map[namespacePair]map[string]bool)
+	counts := make(map[namespacePair]int)
+	var pairs []namespacePair
+	for _, connection := range diagram.Connections {
+		pair := namespacePair{namespaceOf[connection.SourceID], namespaceOf[connection.TargetID]}
+		if pair.source == "" || pair.target == "" || pair.source == pair.target {
+			continue
+		}
+		if relations[pair] == nil {
+			relations[pair] = make(map[string]bool)
+			pairs = append(pairs, pair)
+		}
+		relations[pair][string(connection.Relation)] = true
+		counts[pair]++
+	}
+	sort.Slice(pairs, func(i, j int) bool {
+		if pairs[i].source != pairs[j].source {
+			return pairs[i].source < pairs[j].source
+		}
+		return pairs[i].target < pairs[j].target
+	})
+
+	for _, pair := range pairs {
+		var names []string
+		for relation := range relations[pair] {
+			names = append(names, relation)
+		}
+		sort.Strings(names)
+		label := strings.Join(names, ", ")
+		if counts[pair] > 1 {
+			label = fmt.Sprintf("%s (%d)", label, counts[pair])
+		}
+
+		overview.Connections = append(overview.Connections, models.Connection{
+			ID:       overviewEdgeID(pair.source, pair.target),
+			SourceID: namespaceCellID(pair.source),
+			TargetID: namespaceCellID(pair.target),
+			Label:    label,
+		})
+	}
+
+	// Namespaces are laid out like resources of a flat diagram
+	if err := NewLayout(g.layout.algorithm, true).ApplyLayout(overview); err != nil {
+		return page{}, fmt.Errorf("failed to apply layout to overview: %w", err)
+	}
+
+	var cells []Cell
+	for _, node := range overview.Nodes {
+		count := len(namespaceNodes[node.Label])
+		label := fmt.Sprintf("%s\n%d resources", models.NamespaceLabel(node.Label), count)
+		if count == 1 {
+			label = fmt.Sprintf("%s\n1 resource", models.NamespaceLabel(node.Label))
+		}
+		cells = append(cells, NewOverviewNamespace(
+			node.ID,
+			label,
+			PageLink(namespacePageID(node.Label)),
+			mergeStyle(OverviewNamespaceStyle, g.theme.Namespace),
+			node.X,
+			node.Y,
+			node.Width,
+			node.Height,
+		))
+	}
+	for _, connection := range overview.Connections {
+		cells = append(cells, NewConnection(
+			connection.ID,
+			connection.Label,
+			mergeStyle(DefaultEdgeStyle, g.theme.Edge),
+			connection.SourceID,
+			connection.TargetID,
+		))
+	}
+
+	return page{id: OverviewPageID, name: "Overview", cells: tagGenerated(cells)}, nil
+}
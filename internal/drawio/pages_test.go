@@ -0,0 +1,47 @@
+package drawio
+
+import (
+	"testing"
+
+	"k8s-to-drawio/pkg/models"
+)
+
+func TestOverviewEdgeIDs(t *testing.T) {
+	// Joined with a dash, both connections would be edge-a-b-c
+	diagram := testDiagram(
+		[]models.DiagramNode{
+			testNode("a-b", "Deployment", "web"),
+			testNode("c", "Secret", "creds"),
+			testNode("a", "Deployment", "api"),
+			testNode("b-c", "Secret", "creds"),
+		},
+		"a-b-Deployment-web", "c-Secret-creds",
+		"a-Deployment-api", "b-c-Secret-creds",
+	)
+	cells := generateCells(t, Options{Layout: "hierarchical", MultiPage: true}, diagram)
+
+	edges := make(map[string]Cell)
+	for _, cell := range cells {
+		if !cell.Edge {
+			continue
+		}
+		if _, duplicate := edges[cell.ID]; duplicate {
+			t.Errorf("duplicate overview edge ID %s", cell.ID)
+		}
+		edges[cell.ID] = cell
+	}
+
+	want := map[string][2]string{
+		overviewEdgeID("a-b", "c"): {"ns-a-b", "ns-c"},
+		overviewEdgeID("a", "b-c"): {"ns-a", "ns-b-c"},
+	}
+	if len(edges) != len(want) {
+		t.Errorf("got %d overview edges, want %d", len(edges), len(want))
+	}
+	for id, endpoints := range want {
+		edge, exists := edges[id]
+		if !exists || edge.Source != endpoints[0] || edge.Target != endpoints[1] {
+			t.Errorf("edge %s = %s -> %s, want %s -> %s", id, edge.Source, edge.Target, endpoints[0], endpoints[1])
+		}
+	}
+}
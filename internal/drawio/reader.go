@@ -0,0 +1,75 @@
+package drawio
+
+import (
+	"fmt"
+	"os"
+)
+
+// ExistingDiagram is the first page of an existing Draw.io file
+type ExistingDiagram struct {
+	Cells []Cell
+	byID  map[string]int
+}
+
+// ReadFile reads a Draw.io file with plain or compressed pages
+func ReadFile(filename string) (*MxFile, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", filename, err)
+	}
+
+	file, err := ParseFile(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", filename, err)
+	}
+	return file, nil
+}
+
+// ReadDiagramFile reads the cells of the first page of a Draw.io file
+func ReadDiagramFile(filename string) (*ExistingDiagram, error) {
+	file, err := ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	if len(file.Diagrams) == 0 {
+		return nil, fmt.Errorf("failed to parse %s: no diagram found", filename)
+	}
+	return NewExistingDiagram(file.Diagrams[0].Model), nil
+}
+
+// NewExistingDiagram indexes the cells of a graph model by ID
+func NewExistingDiagram(model GraphModel) *ExistingDiagram {
+	diagram := &ExistingDiagram{
+		Cells: model.Root.Cells,
+		byID:  make(map[string]int, len(model.Root.Cells)),
+	}
+	for i, cell := range diagram.Cells {
+		diagram.byID[cell.ID] = i
+	}
+	return diagram
+}
+
+// Cell returns the cell with the given ID
+func (d *ExistingDiagram) Cell(id string) (Cell, bool) {
+	i, exists := d.byID[id]
+	if !exists {
+		return Cell{}, false
+	}
+	return d.Cells[i], true
+}
+
+// AbsolutePosition returns the position of a cell relative to the page rather than its parent
+func (d *ExistingDiagram) AbsolutePosition(id string) (float64, float64) {
+	x, y := 0.0, 0.0
+	for depth := 0; depth < len(d.Cells); depth++ {
+		cell, exists := d.Cell(id)
+		if !exists || !cell.Vertex {
+			break
+		}
+		bounds := cell.Bounds()
+		x += bounds.X
+		y += bounds.Y
+		id = cell.Parent
+	}
+	return x, y
+}
@@ -1,95 +1,138 @@
 package drawio
 
 import (
-	"fmt"
+	"encoding/xml"
 	"strings"
 )
 
-// ShapeTemplates contains Draw.io shape templates for different Kubernetes resources
-var ShapeTemplates = map[string]string{
-	"Deployment": `<mxCell id="%s" value="%s" style="rounded=1;whiteSpace=wrap;html=1;fillColor=#d5e8d4;strokeColor=#82b366;" vertex="1" parent="1">
-		<mxGeometry x="%.1f" y="%.1f" width="%.1f" height="%.1f" as="geometry"/>
-	</mxCell>`,
-
-	"Service": `<mxCell id="%s" value="%s" style="ellipse;whiteSpace=wrap;html=1;fillColor=#fff2cc;strokeColor=#d6b656;" vertex="1" parent="1">
-		<mxGeometry x="%.1f" y="%.1f" width="%.1f" height="%.1f" as="geometry"/>
-	</mxCell>`,
-
-	"Ingress": `<mxCell id="%s" value="%s" style="rhombus;whiteSpace=wrap;html=1;fillColor=#f8cecc;strokeColor=#b85450;" vertex="1" parent="1">
-		<mxGeometry x="%.1f" y="%.1f" width="%.1f" height="%.1f" as="geometry"/>
-	</mxCell>`,
-
-	"ConfigMap": `<mxCell id="%s" value="%s" style="shape=note;whiteSpace=wrap;html=1;backgroundOutline=1;darkOpacity=0.05;fillColor=#e1d5e7;strokeColor=#9673a6;" vertex="1" parent="1">
-		<mxGeometry x="%.1f" y="%.1f" width="%.1f" height="%.1f" as="geometry"/>
-	</mxCell>`,
-
-	"Secret": `<mxCell id="%s" value="%s" style="shape=note;whiteSpace=wrap;html=1;backgroundOutline=1;darkOpacity=0.05;fillColor=#f5f5f5;strokeColor=#666666;" vertex="1" parent="1">
-		<mxGeometry x="%.1f" y="%.1f" width="%.1f" height="%.1f" as="geometry"/>
-	</mxCell>`,
+// ShapeStyles contains Draw.io shape styles for different Kubernetes resources
+var ShapeStyles = map[string]string{
+	"Deployment":            "rounded=1;whiteSpace=wrap;html=1;fillColor=#d5e8d4;strokeColor=#82b366;",
+	"Service":               "ellipse;whiteSpace=wrap;html=1;fillColor=#fff2cc;strokeColor=#d6b656;",
+	"Ingress":               "rhombus;whiteSpace=wrap;html=1;fillColor=#f8cecc;strokeColor=#b85450;",
+	"ConfigMap":             "shape=note;whiteSpace=wrap;html=1;backgroundOutline=1;darkOpacity=0.05;fillColor=#e1d5e7;strokeColor=#9673a6;",
+	"Secret":                "shape=note;whiteSpace=wrap;html=1;backgroundOutline=1;darkOpacity=0.05;fillColor=#f5f5f5;strokeColor=#666666;",
+	"PersistentVolumeClaim": "shape=cylinder3;whiteSpace=wrap;html=1;boundedLbl=1;backgroundOutline=1;size=15;fillColor=#dae8fc;strokeColor=#6c8ebf;",
+	"StatefulSet":           "rounded=1;whiteSpace=wrap;html=1;fillColor=#d5e8d4;strokeColor=#82b366;",
+	"DaemonSet":             "rounded=1;whiteSpace=wrap;html=1;fillColor=#d5e8d4;strokeColor=#82b366;",
+	"VaultSecret":           "shape=hexagon;whiteSpace=wrap;html=1;backgroundOutline=1;darkOpacity=0.05;fillColor=#ffe6cc;strokeColor=#d79b00;",
+	"Route":                 "shape=trapezoid;whiteSpace=wrap;html=1;fillColor=#ffc9c9;strokeColor=#d6536d;size=0.2;",
+	"ServiceMonitor":        "shape=monitor;whiteSpace=wrap;html=1;fillColor=#e6f3ff;strokeColor=#4a90e2;",
+}
 
-	"PersistentVolumeClaim": `<mxCell id="%s" value="%s" style="shape=cylinder3;whiteSpace=wrap;html=1;boundedLbl=1;backgroundOutline=1;size=15;fillColor=#dae8fc;strokeColor=#6c8ebf;" vertex="1" parent="1">
-		<mxGeometry x="%.1f" y="%.1f" width="%.1f" height="%.1f" as="geometry"/>
-	</mxCell>`,
+// DefaultEdgeStyle is used for connections without a known relation
+const DefaultEdgeStyle = "endArrow=classic;html=1;rounded=0;"
+
+// OptionalEdgeStyle is appended to the style of connections whose references are all optional
+const OptionalEdgeStyle = "dashed=1;dashPattern=2 4;opacity=60;"
+
+// EdgeStyles contains Draw.io edge styles for the different dependency relations
+var EdgeStyles = map[string]string{
+	"mounts":               "endArrow=classic;html=1;rounded=0;strokeColor=#9673a6;",
+	"envFrom":              "endArrow=classic;html=1;rounded=0;dashed=1;strokeColor=#9673a6;",
+	"selects":              "endArrow=classic;html=1;rounded=0;strokeWidth=2;strokeColor=#d6b656;",
+	"routes-to":            "endArrow=classic;html=1;rounded=0;strokeWidth=2;strokeColor=#b85450;",
+	"binds":                "endArrow=block;endFill=0;html=1;rounded=0;dashed=1;strokeColor=#666666;",
+	"runs-as":              "endArrow=open;html=1;rounded=0;dashed=1;strokeColor=#666666;",
+	"image-pull":           "endArrow=open;html=1;rounded=0;dashed=1;strokeColor=#999999;",
+	"scrapes":              "endArrow=classic;html=1;rounded=0;dashed=1;dashPattern=1 4;strokeColor=#4a90e2;",
+	"injects-vault-secret": "endArrow=classic;html=1;rounded=0;strokeWidth=2;strokeColor=#d79b00;",
+	"vault-auth":           "endArrow=open;html=1;rounded=0;dashed=1;strokeColor=#d79b00;",
+}
 
-	"StatefulSet": `<mxCell id="%s" value="%s" style="rounded=1;whiteSpace=wrap;html=1;fillColor=#d5e8d4;strokeColor=#82b366;" vertex="1" parent="1">
-		<mxGeometry x="%.1f" y="%.1f" width="%.1f" height="%.1f" as="geometry"/>
-	</mxCell>`,
+// NamespaceStyle is the style of namespace containers
+const NamespaceStyle = "swimlane;fontStyle=0;horizontal=1;startSize=30;container=1;collapsible=1;marginBottom=0;fillColor=#e1d5e7;strokeColor=#9673a6;"
 
-	"DaemonSet": `<mxCell id="%s" value="%s" style="rounded=1;whiteSpace=wrap;html=1;fillColor=#d5e8d4;strokeColor=#82b366;" vertex="1" parent="1">
-		<mxGeometry x="%.1f" y="%.1f" width="%.1f" height="%.1f" as="geometry"/>
-	</mxCell>`,
+// OverviewNamespaceStyle is the style of namespace boxes on the overview page
+const OverviewNamespaceStyle = "rounded=1;whiteSpace=wrap;html=1;fillColor=#e1d5e7;strokeColor=#9673a6;fontStyle=1;"
 
-	"VaultSecret": `<mxCell id="%s" value="%s" style="shape=hexagon;whiteSpace=wrap;html=1;backgroundOutline=1;darkOpacity=0.05;fillColor=#ffe6cc;strokeColor=#d79b00;" vertex="1" parent="1">
-		<mxGeometry x="%.1f" y="%.1f" width="%.1f" height="%.1f" as="geometry"/>
-	</mxCell>`,
+func GetShapeStyle(kind string) string {
+	if style, exists := ShapeStyles[kind]; exists {
+		return style
+	}
+	// Default style for unknown resource types
+	return ShapeStyles["Deployment"]
+}
 
-	"Route": `<mxCell id="%s" value="%s" style="shape=trapezoid;whiteSpace=wrap;html=1;fillColor=#ffc9c9;strokeColor=#d6536d;size=0.2;" vertex="1" parent="1">
-		<mxGeometry x="%.1f" y="%.1f" width="%.1f" height="%.1f" as="geometry"/>
-	</mxCell>`,
+func GetEdgeStyle(relation string) string {
+	if style, exists := EdgeStyles[relation]; exists {
+		return style
+	}
+	return DefaultEdgeStyle
+}
 
-	"ServiceMonitor": `<mxCell id="%s" value="%s" style="shape=monitor;whiteSpace=wrap;html=1;fillColor=#e6f3ff;strokeColor=#4a90e2;" vertex="1" parent="1">
-		<mxGeometry x="%.1f" y="%.1f" width="%.1f" height="%.1f" as="geometry"/>
-	</mxCell>`,
+// ParseStyle returns the shape and the attributes of a Draw.io style. The shape is
+// the shape attribute, or else the first attribute without a value, such as ellipse.
+func ParseStyle(style string) (string, StyleAttributes) {
+	shape := ""
+	attrs := make(StyleAttributes)
+	for _, part := range strings.Split(style, ";") {
+		if part == "" {
+			continue
+		}
+		key, value, found := strings.Cut(part, "=")
+		if !found {
+			if shape == "" {
+				shape = key
+			}
+			continue
+		}
+		attrs[key] = value
+	}
+	if attrs["shape"] != "" {
+		shape = attrs["shape"]
+	}
+	return shape, attrs
 }
 
-// ConnectionTemplate for drawing connections between resources
-var ConnectionTemplate = `<mxCell id="%s" value="%s" style="endArrow=classic;html=1;rounded=0;" edge="1" parent="1" source="%s" target="%s">
-	<mxGeometry width="50" height="50" relative="1" as="geometry">
-		<mxPoint x="%.1f" y="%.1f" as="sourcePoint"/>
-		<mxPoint x="%.1f" y="%.1f" as="targetPoint"/>
-	</mxGeometry>
-</mxCell>`
-
-// NamespaceGroupTemplate for namespace groupings
-var NamespaceGroupTemplate = `<mxCell id="%s" value="%s" style="swimlane;fontStyle=0;childLayout=stackLayout;horizontal=1;startSize=30;horizontalStack=0;resizeParent=1;resizeParentMax=0;resizeLast=0;collapsible=1;marginBottom=0;fillColor=#e1d5e7;strokeColor=#9673a6;" vertex="1" parent="1">
-	<mxGeometry x="%.1f" y="%.1f" width="%.1f" height="%.1f" as="geometry"/>
-</mxCell>`
-
-func GetShapeTemplate(kind string) string {
-	if template, exists := ShapeTemplates[kind]; exists {
-		return template
+// NewShape returns a vertex with the given style. x and y are relative to the parent cell.
+func NewShape(id, label, style, parent string, x, y, width, height float64) Cell {
+	return Cell{
+		ID:       id,
+		Value:    label,
+		Style:    style,
+		Vertex:   true,
+		Parent:   parent,
+		Geometry: &Geometry{X: x, Y: y, Width: width, Height: height, As: "geometry"},
 	}
-	// Default template for unknown resource types
-	return ShapeTemplates["Deployment"]
 }
 
-func FormatShape(template, id, label string, x, y, width, height float64) string {
-	return fmt.Sprintf(template, id, label, x, y, width, height)
+// NewNamespaceGroup returns the container of a namespace. Resources of the namespace are
+// its child cells, so the namespace can be moved, collapsed and resized as a unit.
+func NewNamespaceGroup(id, name, style string, x, y, width, height float64) Cell {
+	return NewShape(id, name, style, "1", x, y, width, height)
 }
 
-func FormatConnection(id, label, sourceID, targetID string, x1, y1, x2, y2 float64) string {
-	return fmt.Sprintf(ConnectionTemplate, id, label, sourceID, targetID, x1, y1, x2, y2)
+// NewOverviewNamespace returns a namespace box of the overview page, linking to the
+// page of the namespace
+func NewOverviewNamespace(id, label, link, style string, x, y, width, height float64) Cell {
+	cell := NewShape(id, label, style, "1", x, y, width, height)
+	cell.Object = "UserObject"
+	cell.Properties = []xml.Attr{{Name: xml.Name{Local: "link"}, Value: link}}
+	return cell
 }
 
-func FormatNamespaceGroup(id, name string, x, y, width, height float64) string {
-	return fmt.Sprintf(NamespaceGroupTemplate, id, name, x, y, width, height)
+// NewConnection returns an edge between two cells, its points are calculated by Draw.io
+func NewConnection(id, label, style, sourceID, targetID string) Cell {
+	return NewConnectionWithGeometry(id, label, style, sourceID, targetID, &Geometry{Relative: "1", As: "geometry"})
+}
+
+// NewConnectionWithGeometry returns an edge with the given geometry, such as one with
+// waypoints read from an existing diagram
+func NewConnectionWithGeometry(id, label, style, sourceID, targetID string, geometry *Geometry) Cell {
+	return Cell{
+		ID:       id,
+		Value:    label,
+		Style:    style,
+		Edge:     true,
+		Parent:   "1",
+		Source:   sourceID,
+		Target:   targetID,
+		Geometry: geometry,
+	}
 }
 
-func EscapeXML(s string) string {
-	s = strings.ReplaceAll(s, "&", "&amp;")
-	s = strings.ReplaceAll(s, "<", "&lt;")
-	s = strings.ReplaceAll(s, ">", "&gt;")
-	s = strings.ReplaceAll(s, "\"", "&quot;")
-	s = strings.ReplaceAll(s, "'", "&#39;")
-	return s
+// PageLink returns a Draw.io link to the page with the given ID
+func PageLink(pageID string) string {
+	return "data:page/id," + pageID
 }
@@ -0,0 +1,355 @@
+package drawio
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"sigs.k8s.io/yaml"
+
+	"k8s-to-drawio/internal/k8s"
+	"k8s-to-drawio/pkg/models"
+)
+
+//go:embed themes/*.yaml
+var builtinThemes embed.FS
+
+// Themes shipped with the tool, selectable by name with LoadTheme
+const (
+	ThemeDefault        = "default"
+	ThemeDark           = "dark"
+	ThemeColorblindSafe = "colorblind-safe"
+)
+
+// BuiltinThemes lists the names of the themes shipped with the tool
+var BuiltinThemes = []string{ThemeDefault, ThemeDark, ThemeColorblindSafe}
+
+// DefaultTooltip is the tooltip template of resources of themes without a tooltip
+const DefaultTooltip = `{{.Kind}} {{.Name}}{{with .Namespace}}
+Namespace: {{.}}{{end}}{{with .Properties.replicas}}
+Replicas: {{.}}{{end}}{{with .Properties.images}}
+Images: {{.}}{{end}}{{with .Properties.ports}}
+Ports: {{.}}{{end}}{{with .Properties.source}}
+Source: {{.}}{{end}}`
+
+// Theme customises the styles of a diagram. See themes/default.yaml for the format.
+type Theme struct {
+	Name       string                     `json:"name,omitempty"`
+	Background string                     `json:"background,omitempty"` // page background colour
+	Namespace  StyleAttributes            `json:"namespace,omitempty"`  // namespace containers and overview boxes
+	Edge       StyleAttributes            `json:"edge,omitempty"`       // every connection
+	Edges      map[string]StyleAttributes `json:"edges,omitempty"`      // connections by relation
+	Legend     StyleAttributes            `json:"legend,omitempty"`     // legend container, its fontColor also applies to the entries
+	Nodes      []NodeStyle                `json:"nodes,omitempty"`      // resources, matching rules apply in order
+	Tooltip    *string                    `json:"tooltip,omitempty"`    // text/template of resource tooltips, defaults to DefaultTooltip, empty for none
+
+	compiled []compiledNodeStyle
+	tooltip  *template.Template
+}
+
+// NodeStyle styles the resources matching all of its conditions. A rule without
+// conditions matches every resource.
+type NodeStyle struct {
+	Kinds      []string               `json:"kinds,omitempty"`      // resource kinds
+	Categories []string               `json:"categories,omitempty"` // categories of k8s.ResourceCategories
+	Selector   map[string]interface{} `json:"selector,omitempty"`   // label selector with matchLabels and matchExpressions
+	Style      StyleAttributes        `json:"style,omitempty"`      // merged into the shape style
+	Width      float64                `json:"width,omitempty"`      // node width reserved by the layout
+	Height     float64                `json:"height,omitempty"`     // node height reserved by the layout
+	Label      string                 `json:"label,omitempty"`      // text/template of the label, see LabelData
+	Tooltip    string                 `json:"tooltip,omitempty"`    // text/template of the tooltip, see LabelData
+}
+
+// LabelData is the data passed to label and tooltip templates
+type LabelData struct {
+	Kind       string
+	Name       string
+	Namespace  string
+	Category   string
+	Labels     map[string]string
+	Properties map[string]string // see models.DiagramNode.Properties
+}
+
+// StyleAttributes are Draw.io style attributes such as fillColor or fontColor. They
+// replace attributes of the same name in the generated style, others are appended.
+type StyleAttributes map[string]string
+
+// UnmarshalJSON accepts numbers and booleans as attribute values, so they do not
+// have to be quoted in YAML
+func (a *StyleAttributes) UnmarshalJSON(data []byte) error {
+	var raw map[string]interface{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	*a = make(StyleAttributes, len(raw))
+	for key, value := range raw {
+		switch value := value.(type) {
+		case string:
+			(*a)[key] = value
+		case float64:
+			(*a)[key] = strconv.FormatFloat(value, 'f', -1, 64)
+		case bool:
+			(*a)[key] = "0"
+			if value {
+				(*a)[key] = "1"
+			}
+		default:
+			return fmt.Errorf("style attribute %s must be a string, number or boolean", key)
+		}
+	}
+	return nil
+}
+
+// compiledNodeStyle is a validated node style with its selector and label template parsed
+type compiledNodeStyle struct {
+	NodeStyle
+	kinds      map[string]bool
+	categories map[string]bool
+	selector   *k8s.Selector
+	label      *template.Template
+	tooltip    *template.Template
+}
+
+// themedNode is the result of applying the matching node styles to a resource
+type themedNode struct {
+	style   StyleAttributes
+	width   float64
+	height  float64
+	label   *template.Template
+	tooltip *template.Template
+}
+
+// LoadTheme returns the built-in theme with the given name, or reads and validates
+// a YAML or JSON theme file
+func LoadTheme(nameOrFile string) (*Theme, error) {
+	if nameOrFile == "" {
+		nameOrFile = ThemeDefault
+	}
+
+	for _, name := range BuiltinThemes {
+		if nameOrFile == name {
+			data, err := builtinThemes.ReadFile("themes/" + name + ".yaml")
+			if err != nil {
+				return nil, err
+			}
+			return parseTheme(data, fmt.Sprintf("built-in theme %s", name))
+		}
+	}
+
+	data, err := os.ReadFile(nameOrFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read theme file: %w (built-in themes: %s)", err, strings.Join(BuiltinThemes, ", "))
+	}
+	return parseTheme(data, nameOrFile)
+}
+
+func parseTheme(data []byte, source string) (*Theme, error) {
+	var theme Theme
+	if err := yaml.UnmarshalStrict(data, &theme); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", source, err)
+	}
+
+	if err := theme.compile(); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", source, err)
+	}
+	return &theme, nil
+}
+
+// compile validates the theme and parses its selectors and templates
+func (t *Theme) compile() error {
+	tooltip := DefaultTooltip
+	if t.Tooltip != nil {
+		tooltip = *t.Tooltip
+	}
+	t.tooltip = nil
+	if tooltip != "" {
+		var err error
+		if t.tooltip, err = parseTemplate(tooltip); err != nil {
+			return fmt.Errorf("invalid tooltip template: %w", err)
+		}
+	}
+
+	categories := map[string]bool{"unknown": true}
+	for _, category := range k8s.ResourceCategories {
+		categories[category] = true
+	}
+
+	t.compiled = make([]compiledNodeStyle, 0, len(t.Nodes))
+	for i, node := range t.Nodes {
+		compiled := compiledNodeStyle{NodeStyle: node}
+
+		if node.Width < 0 || node.Height < 0 {
+			return fmt.Errorf("node style %d: width and height must not be negative", i+1)
+		}
+		if len(node.Kinds) > 0 {
+			compiled.kinds = make(map[string]bool, len(node.Kinds))
+			for _, kind := range node.Kinds {
+				compiled.kinds[kind] = true
+			}
+		}
+		if len(node.Categories) > 0 {
+			compiled.categories = make(map[string]bool, len(node.Categories))
+			for _, category := range node.Categories {
+				if !categories[category] {
+					return fmt.Errorf("node style %d: unknown category %q", i+1, category)
+				}
+				compiled.categories[category] = true
+			}
+		}
+		if node.Selector != nil {
+			selector, err := k8s.NewLabelSelector(node.Selector)
+			if err != nil {
+				return fmt.Errorf("node style %d: invalid selector: %w", i+1, err)
+			}
+			selector = selector.AnyNamespace()
+			compiled.selector = &selector
+		}
+		if node.Label != "" {
+			label, err := parseTemplate(node.Label)
+			if err != nil {
+				return fmt.Errorf("node style %d: invalid label template: %w", i+1, err)
+			}
+			compiled.label = label
+		}
+		if node.Tooltip != "" {
+			tooltip, err := parseTemplate(node.Tooltip)
+			if err != nil {
+				return fmt.Errorf("node style %d: invalid tooltip template: %w", i+1, err)
+			}
+			compiled.tooltip = tooltip
+		}
+
+		t.compiled = append(t.compiled, compiled)
+	}
+	return nil
+}
+
+// parseTemplate parses a label or tooltip template
+func parseTemplate(text string) (*template.Template, error) {
+	parsed, err := template.New("").Option("missingkey=zero").Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	// Catch unknown fields before rendering the diagram
+	if err := parsed.Execute(io.Discard, LabelData{}); err != nil {
+		return nil, err
+	}
+	return parsed, nil
+}
+
+// matches reports whether a node style applies to a resource
+func (s compiledNodeStyle) matches(node models.DiagramNode) bool {
+	if s.kinds != nil && !s.kinds[node.Kind] {
+		return false
+	}
+	if s.categories != nil && !s.categories[k8s.GetResourceCategory(node.Kind)] {
+		return false
+	}
+	if s.selector != nil && !s.selector.Matches(node.Labels, node.Namespace) {
+		return false
+	}
+	return true
+}
+
+// node applies the matching node styles to a resource, later styles override earlier ones
+func (t *Theme) node(node models.DiagramNode) themedNode {
+	themed := themedNode{style: StyleAttributes{}, width: nodeWidth, height: nodeHeight, tooltip: t.tooltip}
+	for _, style := range t.compiled {
+		if !style.matches(node) {
+			continue
+		}
+		for key, value := range style.Style {
+			themed.style[key] = value
+		}
+		if style.Width > 0 {
+			themed.width = style.Width
+		}
+		if style.Height > 0 {
+			themed.height = style.Height
+		}
+		if style.label != nil {
+			themed.label = style.label
+		}
+		if style.tooltip != nil {
+			themed.tooltip = style.tooltip
+		}
+	}
+	return themed
+}
+
+// nodeSize returns the size the layout reserves for a resource
+func (t *Theme) nodeSize(node models.DiagramNode) (float64, float64) {
+	themed := t.node(node)
+	return themed.width, themed.height
+}
+
+// edgeStyle returns the style of a connection with the given relation
+func (t *Theme) edgeStyle(relation string) string {
+	return mergeStyle(mergeStyle(GetEdgeStyle(relation), t.Edge), t.Edges[relation])
+}
+
+// render executes a label or tooltip template for a resource
+func render(tmpl *template.Template, node models.DiagramNode) (string, error) {
+	properties := make(map[string]string, len(node.Properties))
+	for _, property := range node.Properties {
+		properties[property.Name] = property.Value
+	}
+
+	var text bytes.Buffer
+	err := tmpl.Execute(&text, LabelData{
+		Kind:       node.Kind,
+		Name:       node.Label,
+		Namespace:  node.Namespace,
+		Category:   k8s.GetResourceCategory(node.Kind),
+		Labels:     node.Labels,
+		Properties: properties,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to render template for %s %s: %w", node.Kind, node.Label, err)
+	}
+	return text.String(), nil
+}
+
+// mergeStyle sets the given attributes in a Draw.io style. Existing attributes are
+// replaced in place, new ones are appended in name order.
+func mergeStyle(style string, attrs StyleAttributes) string {
+	if len(attrs) == 0 {
+		return style
+	}
+
+	set := make(map[string]bool, len(attrs))
+	var parts []string
+	for _, part := range strings.Split(style, ";") {
+		if part == "" {
+			continue
+		}
+		if key, _, found := strings.Cut(part, "="); found {
+			if value, exists := attrs[key]; exists {
+				part = key + "=" + value
+				set[key] = true
+			}
+		}
+		parts = append(parts, part)
+	}
+
+	keys := make([]string, 0, len(attrs))
+	for key := range attrs {
+		if !set[key] {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		parts = append(parts, key+"="+attrs[key])
+	}
+
+	return strings.Join(parts, ";") + ";"
+}
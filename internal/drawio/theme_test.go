@@ -0,0 +1,172 @@
+package drawio
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"k8s-to-drawio/pkg/models"
+)
+
+func TestBuiltinThemes(t *testing.T) {
+	for _, name := range append([]string{""}, BuiltinThemes...) {
+		theme, err := LoadTheme(name)
+		if err != nil {
+			t.Errorf("LoadTheme(%q): %v", name, err)
+			continue
+		}
+		if want := name; want != "" && theme.Name != want {
+			t.Errorf("LoadTheme(%q) has name %q", name, theme.Name)
+		}
+	}
+}
+
+func TestLoadThemeFile(t *testing.T) {
+	dir := t.TempDir()
+	yamlFile := filepath.Join(dir, "theme.yaml")
+	writeFile(t, yamlFile, `
+name: custom
+background: "#202020"
+namespace: {fillColor: "#e1d5e7"}
+edge: {fontColor: "#333333"}
+edges:
+  mounts: {strokeColor: "#9673a6", strokeWidth: 2}
+nodes:
+- categories: [workload]
+  style: {fillColor: "#d5e8d4", rounded: true}
+- kinds: [Deployment]
+  selector: {matchLabels: {tier: frontend}}
+  style: {fillColor: "#ffe6cc"}
+  width: 180
+  height: 100
+`)
+	jsonFile := filepath.Join(dir, "theme.json")
+	writeFile(t, jsonFile, `{"name": "custom", "nodes": [{"kinds": ["Secret"], "style": {"opacity": 50}}]}`)
+
+	theme, err := LoadTheme(yamlFile)
+	if err != nil {
+		t.Fatalf("LoadTheme: %v", err)
+	}
+	if theme.Name != "custom" || theme.Background != "#202020" {
+		t.Errorf("theme %q with background %q", theme.Name, theme.Background)
+	}
+
+	// Numbers and booleans do not have to be quoted
+	if got := theme.edgeStyle("mounts"); !strings.Contains(got, "strokeColor=#9673a6;") || !strings.Contains(got, "strokeWidth=2;") || !strings.Contains(got, "fontColor=#333333;") {
+		t.Errorf("mounts edge style = %q", got)
+	}
+
+	// Matching node styles apply in order
+	frontend := models.DiagramNode{Kind: "Deployment", Namespace: "shop", Labels: map[string]string{"tier": "frontend"}}
+	themed := theme.node(frontend)
+	if want := (StyleAttributes{"fillColor": "#ffe6cc", "rounded": "1"}); !reflect.DeepEqual(themed.style, want) {
+		t.Errorf("frontend style = %v, want %v", themed.style, want)
+	}
+	if themed.width != 180 || themed.height != 100 {
+		t.Errorf("frontend size = %vx%v, want 180x100", themed.width, themed.height)
+	}
+	backend := models.DiagramNode{Kind: "Deployment", Namespace: "shop", Labels: map[string]string{"tier": "backend"}}
+	if width, height := theme.nodeSize(backend); width != nodeWidth || height != nodeHeight {
+		t.Errorf("backend size = %vx%v, want the default", width, height)
+	}
+	if got := theme.node(models.DiagramNode{Kind: "Service"}).style; len(got) != 0 {
+		t.Errorf("unstyled kind has style %v", got)
+	}
+
+	theme, err = LoadTheme(jsonFile)
+	if err != nil {
+		t.Fatalf("LoadTheme: %v", err)
+	}
+	if got := theme.node(models.DiagramNode{Kind: "Secret"}).style; got["opacity"] != "50" {
+		t.Errorf("JSON theme style = %v", got)
+	}
+}
+
+func TestLoadThemeErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		theme string
+		want  string
+	}{
+		{"unknown field", "nodes: [{kind: [Secret]}]", `unknown field "kind"`},
+		{"unknown category", "nodes: [{categories: [compute]}]", `node style 1: unknown category "compute"`},
+		{"invalid selector", "nodes: [{}, {selector: {matchExpressions: [{key: app, operator: Has}]}}]", "node style 2: invalid selector"},
+		{"invalid label", `nodes: [{label: "{{.Name"}]`, "node style 1: invalid label template"},
+		{"unknown template field", `nodes: [{tooltip: "{{.Image}}"}]`, "node style 1: invalid tooltip template"},
+		{"invalid tooltip", `tooltip: "{{end}}"`, "invalid tooltip template"},
+		{"negative size", "nodes: [{width: -10}]", "node style 1: width and height must not be negative"},
+		{"nested style value", "edge: {strokeColor: [red]}", "style attribute strokeColor must be a string, number or boolean"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filename := filepath.Join(t.TempDir(), "theme.yaml")
+			writeFile(t, filename, tt.theme)
+
+			_, err := LoadTheme(filename)
+			if err == nil || !strings.Contains(err.Error(), tt.want) || !strings.Contains(err.Error(), filename) {
+				t.Errorf("LoadTheme() error = %v, want one naming the file and containing %q", err, tt.want)
+			}
+		})
+	}
+
+	_, err := LoadTheme("no-such-theme")
+	if err == nil || !strings.Contains(err.Error(), "built-in themes: default, dark, colorblind-safe") {
+		t.Errorf("LoadTheme() of a missing file error = %v, want the built-in themes listed", err)
+	}
+}
+
+func TestMergeStyle(t *testing.T) {
+	tests := []struct {
+		style string
+		attrs StyleAttributes
+		want  string
+	}{
+		{"rounded=1;fillColor=#fff;", nil, "rounded=1;fillColor=#fff;"},
+		{"rounded=1;fillColor=#fff;", StyleAttributes{"fillColor": "#000"}, "rounded=1;fillColor=#000;"},
+		{"ellipse;fillColor=#fff", StyleAttributes{"strokeColor": "#f00", "dashed": "1"}, "ellipse;fillColor=#fff;dashed=1;strokeColor=#f00;"},
+	}
+
+	for _, tt := range tests {
+		if got := mergeStyle(tt.style, tt.attrs); got != tt.want {
+			t.Errorf("mergeStyle(%q, %v) = %q, want %q", tt.style, tt.attrs, got, tt.want)
+		}
+	}
+}
+
+func TestThemedDiagram(t *testing.T) {
+	theme, err := LoadTheme(ThemeDark)
+	if err != nil {
+		t.Fatalf("LoadTheme: %v", err)
+	}
+	theme.Nodes = append(theme.Nodes, NodeStyle{Kinds: []string{"Secret"}, Style: StyleAttributes{"fillColor": "#123456"}})
+	theme.Edges = map[string]StyleAttributes{string(models.RelationMounts): {"strokeColor": "#654321"}}
+
+	diagram := shopDiagram()
+	diagram.Connections[0].Relation = models.RelationMounts
+	cells := cellsByID(generateCells(t, Options{Layout: "hierarchical", Theme: theme}, diagram))
+
+	if style := cells["shop-Secret-db-creds"].Style; !strings.Contains(style, "fillColor=#123456;") {
+		t.Errorf("themed Secret style = %q", style)
+	}
+	_, namespace := ParseStyle(cells["ns-shop"].Style)
+	for key, value := range theme.Namespace {
+		if namespace[key] != value {
+			t.Errorf("namespace style has %s=%s, want the theme's %s", key, namespace[key], value)
+		}
+	}
+	if style := cells[diagram.Connections[0].ID].Style; !strings.Contains(style, "strokeColor=#654321;") {
+		t.Errorf("themed connection style = %q", style)
+	}
+}
+
+// writeFile writes a test file
+func writeFile(t *testing.T, filename, content string) {
+	t.Helper()
+
+	if err := os.WriteFile(filename, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,233 @@
+package drawio
+
+import (
+	"math"
+	"sort"
+	"strings"
+
+	"k8s-to-drawio/pkg/models"
+)
+
+// DeletedStyle is merged into the style of generated cells whose resource no longer
+// exists. Its deleted attribute marks the cell, see markDeleted.
+const DeletedStyle = "deleted=1;dashed=1;opacity=40;strokeColor=#FF0000;fontColor=#FF0000;"
+
+// GeneratedStyleKey is the style attribute tagging the cells written by the
+// generator. Only tagged cells are replaced or removed by an update, every other
+// cell was added by the user.
+const GeneratedStyleKey = "k8s-to-drawio"
+
+// tagGenerated sets GeneratedStyleKey in the style of generated cells
+func tagGenerated(cells []Cell) []Cell {
+	for i := range cells {
+		cells[i].Style = mergeStyle(cells[i].Style, StyleAttributes{GeneratedStyleKey: "1"})
+	}
+	return cells
+}
+
+// isGenerated reports whether a cell of an existing diagram was written by the generator
+func isGenerated(cell Cell) bool {
+	_, attrs := ParseStyle(cell.Style)
+	return attrs[GeneratedStyleKey] == "1"
+}
+
+// mergeExisting keeps the geometry of nodes and namespaces that already exist in the
+// diagram being updated. Nodes that are new to an existing namespace are placed below
+// its kept nodes, new namespaces and nodes outside namespaces below all existing cells.
+func (g *Generator) mergeExisting(diagram *models.Diagram) {
+	existing := g.existing
+
+	bottom := 0.0
+	for _, cell := range existing.Cells {
+		if cell.Vertex {
+			_, y := existing.AbsolutePosition(cell.ID)
+			bottom = math.Max(bottom, y+cell.Bounds().Height)
+		}
+	}
+
+	kept := make(map[string]bool)
+	for i, node := range diagram.Nodes {
+		if cell, exists := existing.Cell(node.ID); exists && cell.Vertex {
+			diagram.Nodes[i].X, diagram.Nodes[i].Y = existing.AbsolutePosition(node.ID)
+			diagram.Nodes[i].Width = cell.Bounds().Width
+			diagram.Nodes[i].Height = cell.Bounds().Height
+			kept[node.ID] = true
+		}
+	}
+
+	nodeIndex := make(map[string]int, len(diagram.Nodes))
+	for i, node := range diagram.Nodes {
+		nodeIndex[node.ID] = i
+	}
+	grouped := make(map[string]bool)
+
+	namespaceNames := make([]string, 0, len(diagram.Namespaces))
+	for name := range diagram.Namespaces {
+		namespaceNames = append(namespaceNames, name)
+	}
+	sort.Strings(namespaceNames)
+
+	for _, name := range namespaceNames {
+		namespace := diagram.Namespaces[name]
+		var newNodes []int
+		for _, nodeID := range namespace.NodeIDs {
+			grouped[nodeID] = true
+			if !kept[nodeID] {
+				newNodes = append(newNodes, nodeIndex[nodeID])
+			}
+		}
+
+		cell, exists := existing.Cell(namespaceCellID(name))
+		if !exists || !cell.Vertex {
+			// New namespace: move it with its nodes below the existing cells
+			dy := bottom + 70 - namespace.Y
+			for _, i := range newNodes {
+				diagram.Nodes[i].Y += dy
+			}
+			namespace.Y += dy
+			bottom = namespace.Y + namespace.Height
+			diagram.Namespaces[name] = namespace
+			continue
+		}
+
+		// Existing namespace: keep its geometry, place new nodes below the kept ones
+		x, y := existing.AbsolutePosition(cell.ID)
+		offsetY := 0.0
+		if len(newNodes) < len(namespace.NodeIDs) {
+			keptBottom, newTop := 0.0, math.Inf(1)
+			for _, nodeID := range namespace.NodeIDs {
+				node := diagram.Nodes[nodeIndex[nodeID]]
+				if kept[nodeID] {
+					keptBottom = math.Max(keptBottom, node.Y+node.Height-y)
+				} else {
+					newTop = math.Min(newTop, node.Y-namespace.Y)
+				}
+			}
+			if len(newNodes) > 0 {
+				offsetY = keptBottom + 80 - newTop
+			}
+		}
+		for _, i := range newNodes {
+			diagram.Nodes[i].X += x - namespace.X
+			diagram.Nodes[i].Y += y - namespace.Y + offsetY
+		}
+
+		// Grow the namespace to fit its nodes
+		width, height := cell.Bounds().Width, cell.Bounds().Height
+		for _, nodeID := range namespace.NodeIDs {
+			node := diagram.Nodes[nodeIndex[nodeID]]
+			width = math.Max(width, node.X+node.Width-x+80)
+			height = math.Max(height, node.Y+node.Height-y+80)
+		}
+		namespace.X, namespace.Y = x, y
+		namespace.Width, namespace.Height = width, height
+		diagram.Namespaces[name] = namespace
+	}
+
+	// New nodes outside namespaces go below the existing cells
+	newTop := math.Inf(1)
+	for _, node := range diagram.Nodes {
+		if !kept[node.ID] && !grouped[node.ID] {
+			newTop = math.Min(newTop, node.Y)
+		}
+	}
+	for i, node := range diagram.Nodes {
+		if !kept[node.ID] && !grouped[node.ID] {
+			diagram.Nodes[i].Y += bottom + 80 - newTop
+		}
+	}
+}
+
+// existingGeometry returns the geometry of a connection in the diagram being
+// updated, so waypoints added by the user are kept
+func (g *Generator) existingGeometry(id string) (*Geometry, bool) {
+	if g.existing == nil {
+		return nil, false
+	}
+	cell, exists := g.existing.Cell(id)
+	if !exists || !cell.Edge || cell.Geometry == nil {
+		return nil, false
+	}
+	return cell.Geometry, true
+}
+
+// retainedCells returns the cells of the diagram being updated that are written
+// after the generated ones: cells added by the user unchanged and generated cells
+// whose resource no longer exists, marked with DeletedStyle. Without markDeleted
+// those are dropped, except for cells still referenced by user cells as parent,
+// source or target, and their ancestors, so user cells are not left dangling.
+// Layers that are no longer generated are kept unchanged while they hold retained cells.
+func (g *Generator) retainedCells(generatedCells []Cell) []Cell {
+	if g.existing == nil {
+		return nil
+	}
+
+	generated := make(map[string]bool, len(generatedCells))
+	for _, cell := range generatedCells {
+		generated[cell.ID] = true
+	}
+
+	// A cell with the ID of a generated cell is replaced even without the tag, such
+	// as the cells of diagrams written before cells were tagged
+	referenced := make(map[string]bool)
+	reference := func(id string) {
+		for id != "" && !referenced[id] {
+			referenced[id] = true
+			cell, exists := g.existing.Cell(id)
+			if !exists {
+				return
+			}
+			id = cell.Parent
+		}
+	}
+	for _, cell := range g.existing.Cells {
+		if !generated[cell.ID] && !isGenerated(cell) {
+			reference(cell.Parent)
+			reference(cell.Source)
+			reference(cell.Target)
+		}
+	}
+
+	var layers, cells []Cell
+	for _, cell := range g.existing.Cells {
+		switch {
+		case cell.ID == "0" || cell.ID == "1":
+			// Root cells are always written by the generator
+		case generated[cell.ID]:
+			// Regenerated
+		case !isGenerated(cell):
+			cells = append(cells, cell)
+		case cell.Parent == "0":
+			// Layer that is no longer generated
+			layers = append(layers, cell)
+		case strings.HasPrefix(cell.ID, legendID):
+			// The legend is regenerated from scratch, never marked
+		case g.markDeleted || referenced[cell.ID]:
+			cells = append(cells, markDeleted(cell))
+		}
+	}
+
+	parents := make(map[string]bool)
+	for _, cell := range cells {
+		parents[cell.Parent] = true
+	}
+	var retained []Cell
+	for _, layer := range layers {
+		if parents[layer.ID] {
+			retained = append(retained, layer)
+		}
+	}
+	return append(retained, cells...)
+}
+
+// markDeleted merges DeletedStyle into the style of a cell. Cells that are already
+// marked are kept as they are, including style changes made by the user since.
+func markDeleted(cell Cell) Cell {
+	_, style := ParseStyle(cell.Style)
+	if style["deleted"] == "1" {
+		return cell
+	}
+	_, deleted := ParseStyle(DeletedStyle)
+	cell.Style = mergeStyle(cell.Style, deleted)
+	return cell
+}
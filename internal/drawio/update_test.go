@@ -0,0 +1,230 @@
+package drawio
+
+import (
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+
+	"k8s-to-drawio/pkg/models"
+)
+
+func TestUpdateKeepsGeometry(t *testing.T) {
+	cells := generateCells(t, Options{Layout: "hierarchical"}, shopDiagram())
+
+	// The user moves a resource within its namespace and the namespace itself
+	moveCell(cells, "shop-Deployment-web", 500, 600)
+	moveCell(cells, "ns-shop", 1000, 40)
+
+	updated := cellsByID(generateCells(t, Options{Layout: "hierarchical", Existing: existingDiagram(cells)}, shopDiagram()))
+
+	web := updated["shop-Deployment-web"]
+	if bounds := web.Bounds(); web.Parent != "ns-shop" || bounds.X != 500 || bounds.Y != 600 {
+		t.Errorf("moved resource: parent %s at (%v, %v), want ns-shop at (500, 600)", web.Parent, bounds.X, bounds.Y)
+	}
+	namespace := updated["ns-shop"].Bounds()
+	if namespace.X != 1000 || namespace.Y != 40 {
+		t.Errorf("moved namespace at (%v, %v), want (1000, 40)", namespace.X, namespace.Y)
+	}
+	if namespace.Width < 500+web.Bounds().Width || namespace.Height < 600+web.Bounds().Height {
+		t.Errorf("namespace of %vx%v does not fit the moved resource", namespace.Width, namespace.Height)
+	}
+
+	// Resources that were not moved keep their position too
+	before := cellsByID(cells)
+	for _, id := range []string{"shop-Service-web", "monitoring-Deployment-prometheus"} {
+		if got, want := updated[id].Bounds(), before[id].Bounds(); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s at %+v, want %+v", id, got, want)
+		}
+	}
+}
+
+func TestUpdatePlacesNewNodes(t *testing.T) {
+	cells := generateCells(t, Options{Layout: "hierarchical"}, shopDiagram())
+	existing := existingDiagram(cells)
+
+	diagram := shopDiagram()
+	diagram.Nodes = append(diagram.Nodes,
+		testNode("shop", "ConfigMap", "new"),
+		testNode("extra", "Secret", "creds"),
+	)
+	updated := cellsByID(generateCells(t, Options{Layout: "hierarchical", Existing: existing}, diagram))
+
+	// A new resource of an existing namespace goes below the kept resources
+	keptBottom := 0.0
+	for _, cell := range cells {
+		if cell.Parent == "ns-shop" {
+			keptBottom = math.Max(keptBottom, cell.Bounds().Y+cell.Bounds().Height)
+		}
+	}
+	added := updated["shop-ConfigMap-new"]
+	if added.Parent != "ns-shop" || added.Bounds().Y < keptBottom {
+		t.Errorf("new resource: parent %s at y=%v, want ns-shop below y=%v", added.Parent, added.Bounds().Y, keptBottom)
+	}
+
+	// A new namespace goes below all existing cells
+	existingBottom := 0.0
+	for _, cell := range cells {
+		if cell.Vertex {
+			_, y := existing.AbsolutePosition(cell.ID)
+			existingBottom = math.Max(existingBottom, y+cell.Bounds().Height)
+		}
+	}
+	if y := updated["ns-extra"].Bounds().Y; y < existingBottom {
+		t.Errorf("new namespace at y=%v, want below y=%v", y, existingBottom)
+	}
+}
+
+func TestUpdateRetainsUserCells(t *testing.T) {
+	cells := generateCells(t, Options{Layout: "hierarchical"}, shopDiagram())
+	note := NewShape("note", "Rotated weekly", "shape=note;", "ns-shop", 20, 40, 120, 40)
+	link := NewConnection("note-link", "", "endArrow=none;dashed=1;", "note", "shop-Secret-db-creds")
+	removedLink := NewConnection("removed-link", "", "endArrow=none;", "note", "monitoring-ConfigMap-prometheus")
+	cells = append(cells, note, link, removedLink)
+
+	diagram := withoutNodes(shopDiagram(), "shop-Secret-db-creds", "shop-PersistentVolumeClaim-data",
+		"monitoring-ServiceMonitor-web", "monitoring-Deployment-prometheus", "monitoring-ConfigMap-prometheus")
+	updated := cellsByID(generateCells(t, Options{Layout: "hierarchical", Existing: existingDiagram(cells)}, diagram))
+
+	for _, want := range []Cell{note, link, removedLink} {
+		if got := updated[want.ID]; !reflect.DeepEqual(got, want) {
+			t.Errorf("user cell %s = %+v, want it unchanged %+v", want.ID, got, want)
+		}
+	}
+
+	// The deleted Secret is still the target of the user's connection, so it is kept
+	// and marked even without MarkDeleted, the deleted PVC is removed with its connection
+	if secret, exists := updated["shop-Secret-db-creds"]; !exists || !isDeleted(secret) {
+		t.Errorf("referenced deleted resource = %+v, want it marked deleted", secret)
+	}
+	// The namespace of a kept resource is kept too, so the resource has a parent
+	for _, id := range []string{"monitoring-ConfigMap-prometheus", "ns-monitoring"} {
+		if cell, exists := updated[id]; !exists || !isDeleted(cell) {
+			t.Errorf("%s = %+v, want it marked deleted", id, cell)
+		}
+	}
+	if _, exists := updated["monitoring-Deployment-prometheus"]; exists {
+		t.Errorf("unreferenced resource of a deleted namespace was kept")
+	}
+	if _, exists := updated["shop-PersistentVolumeClaim-data"]; exists {
+		t.Errorf("unreferenced deleted resource was kept")
+	}
+	for _, cell := range updated {
+		if cell.Edge && (cell.Target == "shop-PersistentVolumeClaim-data" || cell.Source == "shop-PersistentVolumeClaim-data") {
+			t.Errorf("connection %s of the deleted resource was kept", cell.ID)
+		}
+	}
+}
+
+func TestUpdateMarksDeletedCells(t *testing.T) {
+	cells := generateCells(t, Options{Layout: "hierarchical"}, shopDiagram())
+	diagram := func() *models.Diagram {
+		return withoutNodes(shopDiagram(), "shop-PersistentVolumeClaim-data")
+	}
+
+	opts := Options{Layout: "hierarchical", Existing: existingDiagram(cells), MarkDeleted: true}
+	marked := generateCells(t, opts, diagram())
+	byID := cellsByID(marked)
+
+	pvc := byID["shop-PersistentVolumeClaim-data"]
+	if !isDeleted(pvc) {
+		t.Fatalf("deleted resource = %+v, want it marked deleted", pvc)
+	}
+	if !reflect.DeepEqual(pvc.Bounds(), cellsByID(cells)["shop-PersistentVolumeClaim-data"].Bounds()) {
+		t.Errorf("deleted resource moved to %+v", pvc.Bounds())
+	}
+	for _, cell := range marked {
+		if cell.Edge && cell.Target == pvc.ID && !isDeleted(cell) {
+			t.Errorf("connection %s of the deleted resource is not marked", cell.ID)
+		}
+	}
+
+	// A marked cell restyled by the user is left as it is by the next update
+	for i := range marked {
+		if marked[i].ID == pvc.ID {
+			marked[i].Style += "fillColor=#000000;"
+			pvc = marked[i]
+		}
+	}
+	opts.Existing = existingDiagram(marked)
+	again := cellsByID(generateCells(t, opts, diagram()))
+	if got := again[pvc.ID]; got.Style != pvc.Style {
+		t.Errorf("already marked cell style = %q, want it unchanged %q", got.Style, pvc.Style)
+	}
+}
+
+func TestMarkDeleted(t *testing.T) {
+	tests := []struct {
+		name  string
+		style string
+		want  string
+	}{
+		{
+			name:  "generated cell",
+			style: "ellipse;html=1;strokeColor=#d6b656;k8s-to-drawio=1;",
+			want:  "ellipse;html=1;strokeColor=#FF0000;k8s-to-drawio=1;dashed=1;deleted=1;fontColor=#FF0000;opacity=40;",
+		},
+		{
+			name:  "already marked",
+			style: "ellipse;deleted=1;strokeColor=#00FF00;",
+			want:  "ellipse;deleted=1;strokeColor=#00FF00;",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := markDeleted(Cell{ID: "web", Style: tt.style})
+			if got.Style != tt.want {
+				t.Errorf("style = %q, want %q", got.Style, tt.want)
+			}
+			if again := markDeleted(got); again.Style != got.Style {
+				t.Errorf("marking twice gives %q, want %q", again.Style, got.Style)
+			}
+		})
+	}
+}
+
+// existingDiagram returns the cells as the first page of a diagram being updated
+func existingDiagram(cells []Cell) *ExistingDiagram {
+	return NewExistingDiagram(GraphModel{Root: Root{Cells: cells}})
+}
+
+// moveCell sets the position of a cell relative to its parent
+func moveCell(cells []Cell, id string, x, y float64) {
+	for i := range cells {
+		if cells[i].ID == id {
+			geometry := *cells[i].Geometry
+			geometry.X, geometry.Y = x, y
+			cells[i].Geometry = &geometry
+		}
+	}
+}
+
+// withoutNodes removes nodes and their connections from a diagram
+func withoutNodes(diagram *models.Diagram, ids ...string) *models.Diagram {
+	removed := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		removed[id] = true
+	}
+
+	var nodes []models.DiagramNode
+	for _, node := range diagram.Nodes {
+		if !removed[node.ID] {
+			nodes = append(nodes, node)
+		}
+	}
+	var connections []models.Connection
+	for _, connection := range diagram.Connections {
+		if !removed[connection.SourceID] && !removed[connection.TargetID] {
+			connections = append(connections, connection)
+		}
+	}
+	diagram.Nodes, diagram.Connections = nodes, connections
+	return diagram
+}
+
+// isDeleted reports whether a cell is marked deleted without duplicating style keys
+func isDeleted(cell Cell) bool {
+	_, style := ParseStyle(cell.Style)
+	return style["deleted"] == "1" && strings.Count(cell.Style, "strokeColor=") <= 1
+}
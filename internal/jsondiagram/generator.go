@@ -0,0 +1,49 @@
+package jsondiagram
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"k8s-to-drawio/internal/drawio"
+	"k8s-to-drawio/internal/output"
+	"k8s-to-drawio/pkg/models"
+)
+
+// FormatName is the name of the output format of this package
+const FormatName = "json"
+
+func init() {
+	output.Register(output.Format{
+		Name:       FormatName,
+		Extensions: []string{".json"},
+		New: func(opts output.Options) (output.Generator, error) {
+			return NewGenerator(opts.Layout, opts.NoNamespaces), nil
+		},
+	})
+}
+
+// Generator writes the laid out diagram as JSON: its nodes with their position and
+// properties, connections and namespaces, for scripts and other renderers
+type Generator struct {
+	layout *drawio.Layout
+}
+
+// NewGenerator creates a generator placing nodes with the given layout algorithm
+func NewGenerator(layoutAlgorithm string, noNamespaces bool) *Generator {
+	return &Generator{layout: drawio.NewLayout(layoutAlgorithm, noNamespaces)}
+}
+
+// Generate lays out a diagram and writes it as indented JSON
+func (g *Generator) Generate(w io.Writer, diagram *models.Diagram) error {
+	if err := g.layout.ApplyLayout(diagram); err != nil {
+		return fmt.Errorf("failed to apply layout: %w", err)
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(diagram); err != nil {
+		return fmt.Errorf("failed to encode diagram: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,140 @@
+package k8s
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/yaml"
+)
+
+// yamlDocument is a single document of a multi-document YAML file
+type yamlDocument struct {
+	index int    // 1-based index of the document within the file
+	line  int    // 1-based line the document starts on
+	data  []byte // raw document content without the separator
+}
+
+var (
+	documentSeparator = regexp.MustCompile(`^---(\s.*)?$`)
+	errorLine         = regexp.MustCompile(`line (\d+)`)
+)
+
+// errNotManifest is reported for documents that are not Kubernetes manifests, such as
+// Helm values, reference rules and kustomizations found next to the manifests
+var errNotManifest = errors.New("not a Kubernetes manifest")
+
+// kustomizeGroup is the API group of kustomization files, which configure kustomize
+// rather than describe resources
+const kustomizeGroup = "kustomize.config.k8s.io"
+
+// splitDocuments splits YAML content on "---" separators while keeping track
+// of the line every document starts on, so errors can point back to the file.
+func splitDocuments(content []byte) []yamlDocument {
+	var documents []yamlDocument
+	current := yamlDocument{index: 1, line: 1}
+	var buf bytes.Buffer
+
+	flush := func(nextLine int) {
+		if len(bytes.TrimSpace(buf.Bytes())) > 0 {
+			current.data = append([]byte(nil), buf.Bytes()...)
+			documents = append(documents, current)
+			current = yamlDocument{index: current.index + 1}
+		}
+		current.line = nextLine
+		buf.Reset()
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	scanner.Buffer(make([]byte, 0, 64*1024), len(content)+1)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := scanner.Bytes()
+		if documentSeparator.Match(line) {
+			flush(lineNumber + 1)
+			continue
+		}
+		buf.Write(line)
+		buf.WriteByte('\n')
+	}
+	flush(lineNumber + 1)
+
+	return documents
+}
+
+// decodeDocument decodes a YAML or JSON document into an unstructured object.
+// It returns nil without an error for documents that only contain comments or null,
+// and errNotManifest for documents without apiVersion and kind.
+func decodeDocument(document yamlDocument) (*unstructured.Unstructured, error) {
+	data, err := yaml.YAMLToJSON(document.data)
+	if err != nil {
+		return nil, err
+	}
+
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 || bytes.Equal(data, []byte("null")) {
+		return nil, nil
+	}
+	if err := checkManifest(data); err != nil {
+		return nil, err
+	}
+
+	obj := &unstructured.Unstructured{}
+	if err := obj.UnmarshalJSON(data); err != nil {
+		return nil, err
+	}
+	return obj, nil
+}
+
+// checkManifest returns errNotManifest if a JSON document is not an object with an
+// apiVersion or a kind, or is a kustomization. Objects with only one of them are
+// broken manifests and fail to decode.
+func checkManifest(data []byte) error {
+	var header map[string]interface{}
+	if err := json.Unmarshal(data, &header); err != nil {
+		return fmt.Errorf("%w: not an object", errNotManifest)
+	}
+	apiVersion, hasAPIVersion := header["apiVersion"]
+	_, hasKind := header["kind"]
+	if !hasAPIVersion && !hasKind {
+		return fmt.Errorf("%w: no apiVersion and kind", errNotManifest)
+	}
+	if !hasAPIVersion {
+		// The unstructured decoder only requires a kind
+		return errors.New("object has a kind but no apiVersion")
+	}
+	if version, ok := apiVersion.(string); ok && strings.HasPrefix(version, kustomizeGroup+"/") {
+		return fmt.Errorf("%w: kustomization", errNotManifest)
+	}
+	return nil
+}
+
+// errorLineInFile maps a line number reported by the YAML decoder back to the
+// file, falling back to the first line of the document.
+func errorLineInFile(document yamlDocument, err error) int {
+	if match := errorLine.FindStringSubmatch(err.Error()); match != nil {
+		if n, convErr := strconv.Atoi(match[1]); convErr == nil && n > 0 {
+			return document.line + n - 1
+		}
+	}
+	return document.line
+}
+
+// parseErrors is returned in strict mode when one or more documents fail to parse
+type parseErrors []error
+
+func (e parseErrors) Error() string {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "%d document(s) failed to parse", len(e))
+	for _, err := range e {
+		fmt.Fprintf(&buf, "\n  %v", err)
+	}
+	return buf.String()
+}
@@ -0,0 +1,168 @@
+package k8s
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"k8s-to-drawio/pkg/models"
+)
+
+// malformedManifests has two valid resources, three documents that fail to decode
+// and two documents that are not manifests
+const malformedManifests = `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: first
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: broken
+  labels: [unclosed
+---
+# Helm values
+replicas: 3
+---
+apiVersion: kustomize.config.k8s.io/v1beta1
+kind: Kustomization
+resources: [first.yaml]
+---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: last
+--- # no apiVersion
+kind: Service
+metadata: {name: web}
+---
+apiVersion: v1
+metadata: {name: no-kind}
+`
+
+func TestParseFileDiagnostics(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "manifests.yaml")
+	writeTestFile(t, filename, malformedManifests)
+
+	collection, err := NewParser("").ParseFile(filename)
+	if err != nil {
+		t.Fatalf("ParseFile: %v", err)
+	}
+
+	var names []string
+	for _, resource := range collection.Resources {
+		names = append(names, resource.Name)
+	}
+	if got := strings.Join(names, ","); got != "first,last" {
+		t.Errorf("resources = %s, want first,last", got)
+	}
+
+	checkDiagnostics(t, "diagnostics", collection.Diagnostics, []string{
+		fmt.Sprintf("%s:10: document 2: yaml: line 5:", filename),
+		fmt.Sprintf("%s:24: document 6: object has a kind but no apiVersion", filename),
+		fmt.Sprintf("%s:27: document 7: Object 'Kind' is missing", filename),
+	})
+	checkDiagnostics(t, "skipped", collection.Skipped, []string{
+		fmt.Sprintf("%s:12: document 3: not a Kubernetes manifest: no apiVersion and kind", filename),
+		fmt.Sprintf("%s:15: document 4: not a Kubernetes manifest: kustomization", filename),
+	})
+}
+
+func TestParseFileStrict(t *testing.T) {
+	dir := t.TempDir()
+	broken := filepath.Join(dir, "broken.yaml")
+	writeTestFile(t, broken, malformedManifests)
+	notManifests := filepath.Join(dir, "values.yaml")
+	writeTestFile(t, notManifests, "replicas: 3\n---\n[a, b]\n---\napiVersion: v1\nkind: ConfigMap\nmetadata: {name: app}\n")
+
+	parser := NewParserWithOptions(ParserOptions{Strict: true})
+	_, err := parser.ParseFile(broken)
+	if err == nil {
+		t.Fatal("ParseFile in strict mode succeeded, want an error")
+	}
+	for _, want := range []string{
+		"3 document(s) failed to parse",
+		broken + ":10: document 2:",
+		broken + ":24: document 6:",
+		broken + ":27: document 7:",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not contain %q", err, want)
+		}
+	}
+
+	// Documents that are not manifests do not fail strict mode
+	collection, err := parser.ParseFile(notManifests)
+	if err != nil {
+		t.Fatalf("ParseFile in strict mode: %v", err)
+	}
+	if len(collection.Resources) != 1 || len(collection.Skipped) != 2 {
+		t.Errorf("got %d resources and %d skipped documents, want 1 and 2", len(collection.Resources), len(collection.Skipped))
+	}
+
+	// Lenient mode reports the broken directory content instead of failing
+	collection, err = NewParser("").ParseDirectory(dir)
+	if err != nil {
+		t.Fatalf("ParseDirectory: %v", err)
+	}
+	if len(collection.Diagnostics) != 3 {
+		t.Errorf("got %d diagnostics, want 3", len(collection.Diagnostics))
+	}
+	if _, err := parser.ParseDirectory(dir); err == nil {
+		t.Error("ParseDirectory in strict mode succeeded, want an error")
+	}
+}
+
+func TestParseBuildOutputDiagnostics(t *testing.T) {
+	const source = "kustomize build of overlays/prod"
+
+	collection, err := NewParser("").ParseBuildOutput(source, []byte(malformedManifests))
+	if err != nil {
+		t.Fatalf("ParseBuildOutput: %v", err)
+	}
+
+	// Build output is not written to a file, so diagnostics name the source without a line
+	checkDiagnostics(t, "diagnostics", collection.Diagnostics, []string{
+		source + ": document 2: yaml: line 5:",
+		source + ": document 6: object has a kind but no apiVersion",
+		source + ": document 7: Object 'Kind' is missing",
+	})
+	for _, resource := range collection.Resources {
+		if resource.SourceFile != source {
+			t.Errorf("%s has source %q, want %q", resource.Name, resource.SourceFile, source)
+		}
+	}
+
+	_, err = NewParserWithOptions(ParserOptions{Strict: true}).ParseBuildOutput(source, []byte(malformedManifests))
+	if err == nil || !strings.Contains(err.Error(), source+": document 2:") {
+		t.Errorf("strict ParseBuildOutput error = %v, want the diagnostics of %s", err, source)
+	}
+}
+
+func TestSplitDocuments(t *testing.T) {
+	documents := splitDocuments([]byte("\n# leading comment\na: 1\n---\n---\n--- # separator comment\nb: 2\n...\n---\n\n"))
+
+	var got []string
+	for _, document := range documents {
+		got = append(got, fmt.Sprintf("%d@%d:%q", document.index, document.line, document.data))
+	}
+	want := []string{`1@1:"\n# leading comment\na: 1\n"`, `2@7:"b: 2\n...\n"`}
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("documents = %v, want %v", got, want)
+	}
+}
+
+// checkDiagnostics compares the messages of diagnostics with the expected prefixes
+func checkDiagnostics(t *testing.T, name string, diagnostics []models.ParseDiagnostic, want []string) {
+	t.Helper()
+
+	if len(diagnostics) != len(want) {
+		t.Fatalf("%s = %v, want %d", name, diagnostics, len(want))
+	}
+	for i, diagnostic := range diagnostics {
+		if !strings.HasPrefix(diagnostic.Error(), want[i]) {
+			t.Errorf("%s[%d] = %q, want prefix %q", name, i, diagnostic, want[i])
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package k8s
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// pathSegment is one step of a JSONPath expression
+type pathSegment struct {
+	fields   []string // field names, more than one for a union like ['a','b']
+	index    int      // array index when fields is empty and wildcard is false
+	wildcard bool     // [*], every element of an array or every value of a map
+}
+
+// pathMatch is a value found by a JSONPath expression together with its concrete path
+type pathMatch struct {
+	value interface{}
+	path  []interface{} // field names and array indexes, formatted with fieldPath
+}
+
+// parsePath parses the subset of JSONPath used by reference rules: dotted field names,
+// quoted field names and unions in brackets, array indexes and the [*] wildcard, e.g.
+// spec.rules[*].http.paths[*].backend.service.name or ['containers','initContainers'][*].env
+func parsePath(expr string) ([]pathSegment, error) {
+	expr = strings.TrimSpace(expr)
+	expr = strings.TrimSuffix(strings.TrimPrefix(expr, "{"), "}")
+	expr = strings.TrimPrefix(expr, "$")
+
+	var segments []pathSegment
+	for i := 0; i < len(expr); {
+		switch expr[i] {
+		case '.':
+			i++
+
+		case '[':
+			end := strings.IndexByte(expr[i:], ']')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated '[' in path %q", expr)
+			}
+			segment, err := parseBracket(strings.TrimSpace(expr[i+1 : i+end]))
+			if err != nil {
+				return nil, fmt.Errorf("invalid path %q: %w", expr, err)
+			}
+			segments = append(segments, segment)
+			i += end + 1
+
+		default:
+			end := strings.IndexAny(expr[i:], ".[")
+			if end < 0 {
+				end = len(expr) - i
+			}
+			segments = append(segments, pathSegment{fields: []string{expr[i : i+end]}})
+			i += end
+		}
+	}
+
+	if len(segments) == 0 {
+		return nil, fmt.Errorf("empty path")
+	}
+	return segments, nil
+}
+
+func parseBracket(content string) (pathSegment, error) {
+	if content == "*" {
+		return pathSegment{wildcard: true}, nil
+	}
+
+	if index, err := strconv.Atoi(content); err == nil {
+		if index < 0 {
+			return pathSegment{}, fmt.Errorf("negative index %d", index)
+		}
+		return pathSegment{index: index}, nil
+	}
+
+	var fields []string
+	for _, part := range strings.Split(content, ",") {
+		part = strings.TrimSpace(part)
+		if len(part) < 2 || (part[0] != '\'' && part[0] != '"') || part[len(part)-1] != part[0] {
+			return pathSegment{}, fmt.Errorf("expected *, an index or quoted field names in brackets, got %q", content)
+		}
+		fields = append(fields, part[1:len(part)-1])
+	}
+	return pathSegment{fields: fields}, nil
+}
+
+// evaluatePath returns every value reachable from obj through segments
+func evaluatePath(obj interface{}, segments []pathSegment) []pathMatch {
+	matches := []pathMatch{{value: obj}}
+
+	for _, segment := range segments {
+		var next []pathMatch
+		for _, match := range matches {
+			next = append(next, applySegment(match, segment)...)
+		}
+		matches = next
+	}
+
+	return matches
+}
+
+func applySegment(match pathMatch, segment pathSegment) []pathMatch {
+	var results []pathMatch
+	child := func(value interface{}, element interface{}) {
+		path := make([]interface{}, len(match.path), len(match.path)+1)
+		copy(path, match.path)
+		results = append(results, pathMatch{value: value, path: append(path, element)})
+	}
+
+	switch value := match.value.(type) {
+	case map[string]interface{}:
+		switch {
+		case segment.wildcard:
+			keys := make([]string, 0, len(value))
+			for key := range value {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			for _, key := range keys {
+				child(value[key], key)
+			}
+		case len(segment.fields) > 0:
+			for _, field := range segment.fields {
+				if fieldValue, exists := value[field]; exists {
+					child(fieldValue, field)
+				}
+			}
+		}
+
+	case []interface{}:
+		switch {
+		case segment.wildcard:
+			for i, item := range value {
+				child(item, i)
+			}
+		case len(segment.fields) == 0 && segment.index < len(value):
+			child(value[segment.index], segment.index)
+		}
+	}
+
+	return results
+}
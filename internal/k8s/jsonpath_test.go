@@ -0,0 +1,240 @@
+package k8s
+
+import (
+	"reflect"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/yaml"
+
+	"k8s-to-drawio/pkg/models"
+)
+
+func TestParsePathErrors(t *testing.T) {
+	for _, expr := range []string{
+		"",
+		"$",
+		"spec.volumes[*",
+		"spec.volumes[-1]",
+		"spec['volumes',secrets]",
+		"spec[volumes]",
+	} {
+		if _, err := parsePath(expr); err == nil {
+			t.Errorf("parsePath(%q) succeeded, want an error", expr)
+		}
+	}
+}
+
+func TestEvaluatePath(t *testing.T) {
+	obj := decodeYAML(t, `
+spec:
+  volumes:
+  - name: config
+    configMap: {name: app-config}
+  - name: data
+    persistentVolumeClaim: {claimName: data}
+  - name: creds
+    secret: {secretName: creds}
+  initContainers:
+  - envFrom:
+    - configMapRef: {name: init-env}
+  containers:
+  - envFrom:
+    - configMapRef: {name: env-a}
+    - secretRef: {name: secret-a}
+  - envFrom:
+    - configMapRef: {name: env-b}
+  selector:
+    b: two
+    a: one
+  tls:
+  - hosts: [a.example.com, b.example.com]
+  - hosts: [c.example.com]
+  labels:
+    app.kubernetes.io/name: web
+`)
+
+	tests := []struct {
+		name  string
+		expr  string
+		want  []interface{}
+		paths []string
+	}{
+		{
+			name:  "field",
+			expr:  "spec.volumes[0].configMap.name",
+			want:  []interface{}{"app-config"},
+			paths: []string{"spec.volumes[0].configMap.name"},
+		},
+		{
+			name:  "wildcard over array skips elements without the field",
+			expr:  "spec.volumes[*].configMap.name",
+			want:  []interface{}{"app-config"},
+			paths: []string{"spec.volumes[0].configMap.name"},
+		},
+		{
+			name:  "wildcard over map in key order",
+			expr:  "spec.selector[*]",
+			want:  []interface{}{"one", "two"},
+			paths: []string{"spec.selector.a", "spec.selector.b"},
+		},
+		{
+			name:  "nested arrays",
+			expr:  "spec.containers[*].envFrom[*].configMapRef.name",
+			want:  []interface{}{"env-a", "env-b"},
+			paths: []string{"spec.containers[0].envFrom[0].configMapRef.name", "spec.containers[1].envFrom[0].configMapRef.name"},
+		},
+		{
+			name:  "nested arrays of strings",
+			expr:  "spec.tls[*].hosts[*]",
+			want:  []interface{}{"a.example.com", "b.example.com", "c.example.com"},
+			paths: []string{"spec.tls[0].hosts[0]", "spec.tls[0].hosts[1]", "spec.tls[1].hosts[0]"},
+		},
+		{
+			name:  "union in field order",
+			expr:  "spec['initContainers','containers'][*].envFrom[*].configMapRef.name",
+			want:  []interface{}{"init-env", "env-a", "env-b"},
+			paths: []string{"spec.initContainers[0].envFrom[0].configMapRef.name", "spec.containers[0].envFrom[0].configMapRef.name", "spec.containers[1].envFrom[0].configMapRef.name"},
+		},
+		{
+			name:  "quoted field with dots",
+			expr:  "{$.spec.labels['app.kubernetes.io/name']}",
+			want:  []interface{}{"web"},
+			paths: []string{"spec.labels['app.kubernetes.io/name']"},
+		},
+		{
+			name: "missing field",
+			expr: "spec.template.spec.volumes[*]",
+		},
+		{
+			name: "missing union fields",
+			expr: "spec['ephemeralContainers','sidecars'][*].envFrom",
+		},
+		{
+			name: "index out of range",
+			expr: "spec.volumes[3].name",
+		},
+		{
+			name: "index into a map",
+			expr: "spec.selector[0]",
+		},
+		{
+			name: "field of a string",
+			expr: "spec.volumes[*].name.first",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			segments, err := parsePath(tt.expr)
+			if err != nil {
+				t.Fatalf("parsePath(%q): %v", tt.expr, err)
+			}
+
+			var values []interface{}
+			var paths []string
+			for _, match := range evaluatePath(obj, segments) {
+				values = append(values, match.value)
+				paths = append(paths, fieldPath(match.path...))
+			}
+
+			if !reflect.DeepEqual(values, tt.want) {
+				t.Errorf("values = %v, want %v", values, tt.want)
+			}
+			if !reflect.DeepEqual(paths, tt.paths) {
+				t.Errorf("paths = %v, want %v", paths, tt.paths)
+			}
+		})
+	}
+}
+
+func TestRuleOptionalAndKeys(t *testing.T) {
+	rules, err := compileRules([]ReferenceRule{{
+		Kinds:      []string{"ConfigMapUser"},
+		Path:       "spec.refs[*]",
+		TargetKind: "ConfigMap",
+		Relation:   "uses",
+		Optional:   "optional",
+		Keys:       "items[*].key",
+	}})
+	if err != nil {
+		t.Fatalf("compileRules: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		ref      string
+		optional bool
+		keys     []string
+	}{
+		{
+			name: "whole object",
+			ref:  "{name: config}",
+		},
+		{
+			name:     "optional with keys",
+			ref:      "{name: config, optional: true, items: [{key: a, path: a.txt}, {key: b, path: b.txt}]}",
+			optional: true,
+			keys:     []string{"a", "b"},
+		},
+		{
+			name: "explicitly required",
+			ref:  "{name: config, optional: false}",
+		},
+		{
+			name: "optional that is not a bool",
+			ref:  "{name: config, optional: \"yes\"}",
+		},
+		{
+			name: "items without keys",
+			ref:  "{name: config, items: [{path: a.txt}, {key: \"\"}, {key: 3}]}",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resource := testResource(t, "apiVersion: example.com/v1\nkind: ConfigMapUser\nmetadata: {name: user}\nspec:\n  refs:\n  - "+tt.ref+"\n")
+
+			dependencies := rules[0].dependencies(resource, nil)
+			if len(dependencies) != 1 {
+				t.Fatalf("got %d dependencies, want 1", len(dependencies))
+			}
+			dep := dependencies[0]
+
+			if dep.Path != "spec.refs[0].name" {
+				t.Errorf("path = %q, want spec.refs[0].name", dep.Path)
+			}
+			if dep.Optional != tt.optional {
+				t.Errorf("optional = %v, want %v", dep.Optional, tt.optional)
+			}
+			if !reflect.DeepEqual(dep.Keys, tt.keys) {
+				t.Errorf("keys = %v, want %v", dep.Keys, tt.keys)
+			}
+		})
+	}
+}
+
+// decodeYAML decodes a YAML object the way manifests are decoded
+func decodeYAML(t *testing.T, data string) map[string]interface{} {
+	t.Helper()
+
+	var obj map[string]interface{}
+	if err := yaml.Unmarshal([]byte(data), &obj); err != nil {
+		t.Fatalf("invalid YAML: %v", err)
+	}
+	return obj
+}
+
+// testResource returns the parsed resource of a single manifest
+func testResource(t *testing.T, manifest string) models.K8sResource {
+	t.Helper()
+
+	obj := &unstructured.Unstructured{Object: decodeYAML(t, manifest)}
+	return models.K8sResource{
+		Object:    obj,
+		Kind:      obj.GetKind(),
+		Name:      obj.GetName(),
+		Namespace: obj.GetNamespace(),
+		Labels:    obj.GetLabels(),
+	}
+}
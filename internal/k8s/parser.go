@@ -1,53 +1,73 @@
 package k8s
 
 import (
+	"errors"
 	"fmt"
 	"io/ioutil"
-	"path/filepath"
+	"sort"
 	"strings"
 
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
-	utilYaml "k8s.io/apimachinery/pkg/util/yaml"
 
 	"k8s-to-drawio/pkg/models"
 )
 
+// ParserOptions configures which resources the parser picks up
+type ParserOptions struct {
+	Namespace string
+	Include   []string        // glob patterns for files to parse, defaults to DefaultIncludePatterns
+	Exclude   []string        // glob patterns for files and directories to skip
+	Strict    bool            // fail if any document cannot be decoded instead of skipping it
+	Rules     []ReferenceRule // reference rules applied in addition to the built-in rules
+}
+
 type Parser struct {
 	namespace string
+	include   []string
+	exclude   []string
+	strict    bool
+	rules     []ReferenceRule
 }
 
 func NewParser(namespace string) *Parser {
+	return NewParserWithOptions(ParserOptions{Namespace: namespace})
+}
+
+func NewParserWithOptions(opts ParserOptions) *Parser {
 	return &Parser{
-		namespace: namespace,
+		namespace: opts.Namespace,
+		include:   opts.Include,
+		exclude:   opts.Exclude,
+		strict:    opts.Strict,
+		rules:     opts.Rules,
 	}
 }
 
+// ParseDirectory recursively parses all manifest files below dir
 func (p *Parser) ParseDirectory(dir string) (*models.ResourceCollection, error) {
-	files, err := filepath.Glob(filepath.Join(dir, "*.yaml"))
+	files, err := findManifestFiles(dir, p.include, p.exclude)
 	if err != nil {
-		return nil, err
-	}
-
-	yamlFiles, err := filepath.Glob(filepath.Join(dir, "*.yml"))
-	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("failed to scan directory %s: %w", dir, err)
 	}
-	files = append(files, yamlFiles...)
 
 	collection := &models.ResourceCollection{
 		Resources:    make([]models.K8sResource, 0),
-		Dependencies: make(map[string][]string),
+		Dependencies: make([]models.Dependency, 0),
 	}
 
 	for _, file := range files {
-		resources, err := p.parseFile(file)
-		if err != nil {
+		if err := p.parseFile(file, collection); err != nil {
 			return nil, fmt.Errorf("failed to parse file %s: %w", file, err)
 		}
-		collection.Resources = append(collection.Resources, resources...)
 	}
 
-	p.buildDependencies(collection)
+	if err := p.checkDiagnostics(collection); err != nil {
+		return nil, err
+	}
+
+	if err := p.buildDependencies(collection); err != nil {
+		return nil, err
+	}
 	return collection, nil
 }
 
@@ -55,36 +75,91 @@ func (p *Parser) ParseDirectory(dir string) (*models.ResourceCollection, error)
 func (p *Parser) ParseFile(filename string) (*models.ResourceCollection, error) {
 	collection := &models.ResourceCollection{
 		Resources:    make([]models.K8sResource, 0),
-		Dependencies: make(map[string][]string),
+		Dependencies: make([]models.Dependency, 0),
 	}
 
-	resources, err := p.parseFile(filename)
-	if err != nil {
+	if err := p.parseFile(filename, collection); err != nil {
 		return nil, fmt.Errorf("failed to parse file %s: %w", filename, err)
 	}
-	collection.Resources = append(collection.Resources, resources...)
 
-	p.buildDependencies(collection)
+	if err := p.checkDiagnostics(collection); err != nil {
+		return nil, err
+	}
+
+	if err := p.buildDependencies(collection); err != nil {
+		return nil, err
+	}
 	return collection, nil
 }
 
-func (p *Parser) parseFile(filename string) ([]models.K8sResource, error) {
-	content, err := ioutil.ReadFile(filename)
-	if err != nil {
+// checkDiagnostics fails the parse in strict mode if any document could not be decoded
+func (p *Parser) checkDiagnostics(collection *models.ResourceCollection) error {
+	if !p.strict || len(collection.Diagnostics) == 0 {
+		return nil
+	}
+
+	errs := make(parseErrors, len(collection.Diagnostics))
+	for i, diagnostic := range collection.Diagnostics {
+		errs[i] = diagnostic
+	}
+	return errs
+}
+
+// ParseBuildOutput parses the manifests generated by a tool such as kustomize.
+// Resources and diagnostics name the given source rather than a file, and
+// diagnostics have no line, as the generated output is not written to disk.
+func (p *Parser) ParseBuildOutput(source string, content []byte) (*models.ResourceCollection, error) {
+	collection := &models.ResourceCollection{
+		Resources:    make([]models.K8sResource, 0),
+		Dependencies: make([]models.Dependency, 0),
+	}
+	p.parseDocuments(source, content, false, collection)
+
+	if err := p.checkDiagnostics(collection); err != nil {
 		return nil, err
 	}
 
-	var resources []models.K8sResource
-	decoder := utilYaml.NewYAMLOrJSONDecoder(strings.NewReader(string(content)), 4096)
+	if err := p.buildDependencies(collection); err != nil {
+		return nil, err
+	}
+	return collection, nil
+}
 
-	for {
-		var obj unstructured.Unstructured
-		err := decoder.Decode(&obj)
+// parseFile decodes every document in filename into collection
+func (p *Parser) parseFile(filename string, collection *models.ResourceCollection) error {
+	content, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+	p.parseDocuments(filename, content, true, collection)
+	return nil
+}
+
+// parseDocuments decodes every document of content read from source into collection.
+// Documents that fail to decode are reported as diagnostics and skipped, so the rest
+// of the content is still parsed. Documents that are not manifests are skipped with a
+// note. With lines, diagnostics point at the line of the error in source.
+func (p *Parser) parseDocuments(source string, content []byte, lines bool, collection *models.ResourceCollection) {
+	for _, document := range splitDocuments(content) {
+		obj, err := decodeDocument(document)
 		if err != nil {
-			break // End of file or error
+			diagnostic := models.ParseDiagnostic{
+				File:     source,
+				Document: document.index,
+				Err:      err,
+			}
+			if lines {
+				diagnostic.Line = errorLineInFile(document, err)
+			}
+			if errors.Is(err, errNotManifest) {
+				collection.Skipped = append(collection.Skipped, diagnostic)
+			} else {
+				collection.Diagnostics = append(collection.Diagnostics, diagnostic)
+			}
+			continue
 		}
 
-		if obj.Object == nil {
+		if obj == nil {
 			continue
 		}
 
@@ -93,443 +168,279 @@ func (p *Parser) parseFile(filename string) ([]models.K8sResource, error) {
 			continue
 		}
 
-		resource := models.K8sResource{
-			Object:      &obj,
+		collection.Resources = append(collection.Resources, models.K8sResource{
+			Object:      obj,
 			Kind:        obj.GetKind(),
 			Name:        obj.GetName(),
 			Namespace:   obj.GetNamespace(),
 			Labels:      obj.GetLabels(),
 			Annotations: obj.GetAnnotations(),
-		}
-
-		resources = append(resources, resource)
+			SourceFile:  source,
+		})
 	}
-
-	return resources, nil
 }
 
-func (p *Parser) buildDependencies(collection *models.ResourceCollection) {
+// buildDependencies finds the dependencies of every resource using the reference
+// rules for name-based references and findDependencies for everything else
+func (p *Parser) buildDependencies(collection *models.ResourceCollection) error {
+	builtin, err := BuiltinRules()
+	if err != nil {
+		return err
+	}
+	rules, err := compileRules(append(builtin, p.rules...))
+	if err != nil {
+		return err
+	}
+
+	kindGroups := collectKindGroups(collection.Resources)
 	for _, resource := range collection.Resources {
-		deps := p.findDependencies(resource, collection.Resources)
-		if len(deps) > 0 {
-			// Use kind+name as key to avoid conflicts between resources with same name
-			key := fmt.Sprintf("%s/%s", resource.Kind, resource.Name)
-			collection.Dependencies[key] = deps
+		source := ResourceIdentity(resource)
+
+		var dependencies []models.Dependency
+		for _, rule := range rules {
+			dependencies = append(dependencies, rule.dependencies(resource, kindGroups)...)
+		}
+		dependencies = append(dependencies, p.findDependencies(resource, collection.Resources)...)
+
+		for _, dep := range dependencies {
+			dep.Source = source
+			collection.Dependencies = append(collection.Dependencies, dep)
 		}
 	}
+	return nil
 }
 
-func (p *Parser) findDependencies(resource models.K8sResource, allResources []models.K8sResource) []string {
-	var dependencies []string
+// findDependencies returns the resources referenced by resource through label selectors
+// and annotations. The Source of the returned dependencies is filled in by the caller.
+// References without an explicit namespace resolve to the namespace of resource.
+func (p *Parser) findDependencies(resource models.K8sResource, allResources []models.K8sResource) []models.Dependency {
+	var dependencies []models.Dependency
+	namespace := ResourceIdentity(resource).Namespace
 
 	// fmt.Printf("Finding dependencies for %s (kind: %s)\n", resource.Name, resource.Kind)
 
 	switch resource.Kind {
 	case "Service":
-		// Services depend on Deployments/StatefulSets via selectors
+		// Services select the pods of workloads in their own namespace
 		if obj, ok := resource.Object.(*unstructured.Unstructured); ok {
-			if selector, found, _ := unstructured.NestedMap(obj.Object, "spec", "selector"); found {
-				for _, other := range allResources {
-					// fmt.Printf("Checking if %s matches selector %+v\n", other.Name, selector)
-					if p.matchesSelector(other, selector) {
-						// fmt.Printf("MATCH: %s matches selector for service %s\n", other.Name, resource.Name)
-						dependencies = append(dependencies, other.Name)
-					}
+			if selectorMap, found, _ := unstructured.NestedMap(obj.Object, "spec", "selector"); found {
+				if selector, err := NewSetSelector(selectorMap, namespace); err == nil {
+					dependencies = append(dependencies, selectedDependencies(selector, allResources, PodLabels, models.RelationSelects, "spec.selector")...)
 				}
 			}
 		}
 
-	case "Ingress":
-		// Ingress depends on Services
+	case "ServiceMonitor", "PodMonitor":
+		// Monitors select Services or pods via label selectors (Prometheus Operator)
 		if obj, ok := resource.Object.(*unstructured.Unstructured); ok {
-			if rules, found, _ := unstructured.NestedSlice(obj.Object, "spec", "rules"); found {
-				for _, rule := range rules {
-					if ruleMap, ok := rule.(map[string]interface{}); ok {
-						if http, found, _ := unstructured.NestedMap(ruleMap, "http"); found {
-							if paths, found, _ := unstructured.NestedSlice(http, "paths"); found {
-								for _, path := range paths {
-									if pathMap, ok := path.(map[string]interface{}); ok {
-										if backend, found, _ := unstructured.NestedMap(pathMap, "backend"); found {
-											if service, found, _ := unstructured.NestedMap(backend, "service"); found {
-												if serviceName, found, _ := unstructured.NestedString(service, "name"); found {
-													dependencies = append(dependencies, serviceName)
-												}
-											}
-										}
-									}
-								}
-							}
-						}
+			if selectorMap, found, _ := unstructured.NestedMap(obj.Object, "spec", "selector"); found {
+				if selector, err := p.monitorSelector(obj, selectorMap, namespace); err == nil {
+					labelsOf := ResourceLabels("Service")
+					if resource.Kind == "PodMonitor" {
+						labelsOf = PodLabels
 					}
+					dependencies = append(dependencies, selectedDependencies(selector, allResources, labelsOf, models.RelationScrapes, "spec.selector")...)
 				}
 			}
 		}
 
-	case "Route":
-		// Routes depend on Services (OpenShift Routes)
+	case "NetworkPolicy":
+		// NetworkPolicies apply to the pods matched by their pod selector, an empty one matches all pods
 		if obj, ok := resource.Object.(*unstructured.Unstructured); ok {
-			if to, found, _ := unstructured.NestedMap(obj.Object, "spec", "to"); found {
-				if kind, found, _ := unstructured.NestedString(to, "kind"); found && kind == "Service" {
-					if serviceName, found, _ := unstructured.NestedString(to, "name"); found {
-						dependencies = append(dependencies, serviceName)
-					}
+			if selectorMap, found, _ := unstructured.NestedMap(obj.Object, "spec", "podSelector"); found {
+				if selector, err := NewLabelSelector(selectorMap, namespace); err == nil {
+					dependencies = append(dependencies, selectedDependencies(selector, allResources, PodLabels, models.RelationSelects, "spec.podSelector")...)
 				}
 			}
 		}
 
-	case "ServiceMonitor":
-		// ServiceMonitors depend on Services via selectors (Prometheus Operator)
+	case "PodDisruptionBudget":
+		// PodDisruptionBudgets protect the pods matched by their selector
 		if obj, ok := resource.Object.(*unstructured.Unstructured); ok {
-			if selector, found, _ := unstructured.NestedMap(obj.Object, "spec", "selector"); found {
-				if matchLabels, found, _ := unstructured.NestedMap(selector, "matchLabels"); found {
-					for _, other := range allResources {
-						if other.Kind == "Service" && p.matchesServiceLabels(other, matchLabels) {
-							dependencies = append(dependencies, other.Name)
-						}
-					}
+			if selectorMap, found, _ := unstructured.NestedMap(obj.Object, "spec", "selector"); found {
+				if selector, err := NewLabelSelector(selectorMap, namespace); err == nil {
+					dependencies = append(dependencies, selectedDependencies(selector, allResources, PodLabels, models.RelationSelects, "spec.selector")...)
 				}
 			}
 		}
 
-	case "Deployment", "StatefulSet", "DaemonSet":
-		// These depend on ConfigMaps, Secrets, PVCs, and ServiceAccounts
-		volumeDeps := p.findVolumeDependencies(resource)
-		envDeps := p.findEnvDependencies(resource)
-		serviceAccountDeps := p.findServiceAccountDependencies(resource)
-		bankVaultsDeps := p.findBankVaultsDependencies(resource)
+	case "Deployment", "StatefulSet", "DaemonSet", "ReplicaSet", "Pod", "Job", "CronJob":
+		// Volume, environment and ServiceAccount references come from the rules,
+		// Bank-Vaults annotations need parsing beyond what a rule can express
+		dependencies = append(dependencies, p.findBankVaultsDependencies(resource)...)
 		// Also check annotations on the pod template for Bank-Vaults
-		bankVaultsTemplateDeps := p.findBankVaultsTemplateAnnotations(resource)
-		// fmt.Printf("%s volume deps: %+v\n", resource.Name, volumeDeps)
-		// fmt.Printf("%s env deps: %+v\n", resource.Name, envDeps)
-		// fmt.Printf("%s serviceAccount deps: %+v\n", resource.Name, serviceAccountDeps)
-		// fmt.Printf("%s bank-vaults deps: %+v\n", resource.Name, bankVaultsDeps)
-		// fmt.Printf("%s bank-vaults template deps: %+v\n", resource.Name, bankVaultsTemplateDeps)
-		dependencies = append(dependencies, volumeDeps...)
-		dependencies = append(dependencies, envDeps...)
-		dependencies = append(dependencies, serviceAccountDeps...)
-		dependencies = append(dependencies, bankVaultsDeps...)
-		dependencies = append(dependencies, bankVaultsTemplateDeps...)
-
-	case "RoleBinding", "ClusterRoleBinding":
-		// RoleBindings depend on ServiceAccounts and Roles
-		rbacDeps := p.findRoleBindingDependencies(resource)
-		dependencies = append(dependencies, rbacDeps...)
-
-	case "ServiceAccount":
-		// ServiceAccounts are used by workloads (reverse dependency)
-		workloadDeps := p.findServiceAccountUsers(resource, allResources)
-		dependencies = append(dependencies, workloadDeps...)
+		dependencies = append(dependencies, p.findBankVaultsTemplateAnnotations(resource)...)
+
 	}
 
-	// fmt.Printf("Final dependencies for %s: %+v\n", resource.Name, dependencies)
 	// fmt.Printf("Final dependencies for %s: %+v\n", resource.Name, dependencies)
 	return dependencies
 }
 
-func (p *Parser) matchesSelector(resource models.K8sResource, selector map[string]interface{}) bool {
-	if resource.Kind != "Deployment" && resource.Kind != "StatefulSet" && resource.Kind != "DaemonSet" {
-		return false
+// monitorSelector builds the selector of a ServiceMonitor or PodMonitor. The namespaceSelector
+// picks the namespaces to look in; without one only the monitor's own namespace is used.
+func (p *Parser) monitorSelector(obj *unstructured.Unstructured, selectorMap map[string]interface{}, namespace string) (Selector, error) {
+	if matchNames, found, _ := unstructured.NestedStringSlice(obj.Object, "spec", "namespaceSelector", "matchNames"); found && len(matchNames) > 0 {
+		return NewLabelSelector(selectorMap, matchNames...)
 	}
 
-	if obj, ok := resource.Object.(*unstructured.Unstructured); ok {
-		if labels, found, _ := unstructured.NestedMap(obj.Object, "spec", "template", "metadata", "labels"); found {
-			for key, value := range selector {
-				if labelValue, exists := labels[key]; !exists || labelValue != value {
-					return false
-				}
-			}
-			return true
-		}
-	}
-	return false
-}
-
-// matchesServiceLabels checks if a Service's labels match the given selector
-func (p *Parser) matchesServiceLabels(resource models.K8sResource, selector map[string]interface{}) bool {
-	if resource.Kind != "Service" {
-		return false
+	selector, err := NewLabelSelector(selectorMap, namespace)
+	if err != nil {
+		return Selector{}, err
 	}
-
-	if obj, ok := resource.Object.(*unstructured.Unstructured); ok {
-		labels := obj.GetLabels()
-		if labels != nil {
-			for key, value := range selector {
-				if labelValue, exists := labels[key]; !exists || labelValue != value {
-					return false
-				}
-			}
-			return true
-		}
+	if any, found, _ := unstructured.NestedBool(obj.Object, "spec", "namespaceSelector", "any"); found && any {
+		return selector.AnyNamespace(), nil
 	}
-	return false
+	return selector, nil
 }
 
-func (p *Parser) findVolumeDependencies(resource models.K8sResource) []string {
-	var dependencies []string
-
-	if obj, ok := resource.Object.(*unstructured.Unstructured); ok {
-		if volumes, found, _ := unstructured.NestedSlice(obj.Object, "spec", "template", "spec", "volumes"); found {
-			for _, vol := range volumes {
-				if volMap, ok := vol.(map[string]interface{}); ok {
-					if configMap, found, _ := unstructured.NestedMap(volMap, "configMap"); found {
-						if name, found, _ := unstructured.NestedString(configMap, "name"); found {
-							dependencies = append(dependencies, name)
-						}
-					}
-					if secret, found, _ := unstructured.NestedMap(volMap, "secret"); found {
-						if name, found, _ := unstructured.NestedString(secret, "secretName"); found {
-							dependencies = append(dependencies, name)
-						}
-					}
-					if pvc, found, _ := unstructured.NestedMap(volMap, "persistentVolumeClaim"); found {
-						if name, found, _ := unstructured.NestedString(pvc, "claimName"); found {
-							dependencies = append(dependencies, name)
-						}
-					}
-				}
-			}
-		}
-	}
-
-	return dependencies
+// findBankVaultsDependencies finds dependencies based on Bank-Vaults annotations on the resource itself
+func (p *Parser) findBankVaultsDependencies(resource models.K8sResource) []models.Dependency {
+	// fmt.Printf("Checking Bank-Vaults annotations for %s: %+v\n", resource.Name, resource.Annotations)
+	return p.bankVaultsAnnotationDependencies(resource, resource.Annotations, []string{"metadata", "annotations"})
 }
 
-func (p *Parser) findEnvDependencies(resource models.K8sResource) []string {
-	var dependencies []string
-
-	// fmt.Printf("Checking env dependencies for %s\n", resource.Name)
-
-	if obj, ok := resource.Object.(*unstructured.Unstructured); ok {
-		if containers, found, _ := unstructured.NestedSlice(obj.Object, "spec", "template", "spec", "containers"); found {
-			for _, container := range containers {
-				if containerMap, ok := container.(map[string]interface{}); ok {
-					// Check envFrom for ConfigMap and Secret references
-					if envFrom, found, _ := unstructured.NestedSlice(containerMap, "envFrom"); found {
-						// fmt.Printf("Found envFrom for %s: %+v\n", resource.Name, envFrom)
-						for _, envSource := range envFrom {
-							if envSourceMap, ok := envSource.(map[string]interface{}); ok {
-								if configMapRef, found, _ := unstructured.NestedMap(envSourceMap, "configMapRef"); found {
-									if name, found, _ := unstructured.NestedString(configMapRef, "name"); found {
-										// fmt.Printf("Found configMapRef: %s\n", name)
-										dependencies = append(dependencies, name)
-									}
-								}
-								if secretRef, found, _ := unstructured.NestedMap(envSourceMap, "secretRef"); found {
-									if name, found, _ := unstructured.NestedString(secretRef, "name"); found {
-										dependencies = append(dependencies, name)
-									}
-								}
-							}
-						}
-					}
-
-					// Check individual env entries for ConfigMap and Secret references
-					if env, found, _ := unstructured.NestedSlice(containerMap, "env"); found {
-						for _, envVar := range env {
-							if envMap, ok := envVar.(map[string]interface{}); ok {
-								if valueFrom, found, _ := unstructured.NestedMap(envMap, "valueFrom"); found {
-									if configMapRef, found, _ := unstructured.NestedMap(valueFrom, "configMapKeyRef"); found {
-										if name, found, _ := unstructured.NestedString(configMapRef, "name"); found {
-											dependencies = append(dependencies, name)
-										}
-									}
-									if secretRef, found, _ := unstructured.NestedMap(valueFrom, "secretKeyRef"); found {
-										if name, found, _ := unstructured.NestedString(secretRef, "name"); found {
-											dependencies = append(dependencies, name)
-										}
-									}
-								}
-							}
-						}
-					}
-				}
-			}
-		}
+// findBankVaultsTemplateAnnotations finds Bank-Vaults dependencies from pod template annotations
+func (p *Parser) findBankVaultsTemplateAnnotations(resource models.K8sResource) []models.Dependency {
+	templateMetadata, exists := PodTemplateMetadataPaths[resource.Kind]
+	if !exists {
+		return nil
 	}
 
-	// fmt.Printf("Final env dependencies for %s: %+v\n", resource.Name, dependencies)
-	return dependencies
-}
-
-// findServiceAccountDependencies finds ServiceAccount dependencies in workload specifications
-func (p *Parser) findServiceAccountDependencies(resource models.K8sResource) []string {
-	var dependencies []string
-
 	if obj, ok := resource.Object.(*unstructured.Unstructured); ok {
-		// Check serviceAccountName in pod template spec
-		if serviceAccountName, found, _ := unstructured.NestedString(obj.Object, "spec", "template", "spec", "serviceAccountName"); found && serviceAccountName != "" {
-			dependencies = append(dependencies, serviceAccountName)
+		// Check annotations on the pod template
+		annotationsPath := childPath(templateMetadata, "annotations")
+		if annotations, found, _ := unstructured.NestedStringMap(obj.Object, annotationsPath...); found {
+			// fmt.Printf("Found pod template annotations for %s: %+v\n", resource.Name, annotations)
+			return p.bankVaultsAnnotationDependencies(resource, annotations, annotationsPath)
 		}
 	}
-
-	return dependencies
+	return nil
 }
 
-// findBankVaultsDependencies finds dependencies based on Bank-Vaults annotations
-func (p *Parser) findBankVaultsDependencies(resource models.K8sResource) []string {
-	var dependencies []string
+// bankVaultsAnnotationDependencies looks for Bank-Vaults annotations that reference Kubernetes
+// resources or Vault secrets. annotationsPath is the location of annotations within resource.
+func (p *Parser) bankVaultsAnnotationDependencies(resource models.K8sResource, annotations map[string]string, annotationsPath []string) []models.Dependency {
+	var dependencies []models.Dependency
+	namespace := ResourceIdentity(resource).Namespace
 
-	// Check if resource has Bank-Vaults annotations
-	if resource.Annotations == nil {
-		return dependencies
+	// Iterate in a fixed order so the dependencies come out the same on every run
+	keys := make([]string, 0, len(annotations))
+	for key := range annotations {
+		keys = append(keys, key)
 	}
+	sort.Strings(keys)
 
-	// fmt.Printf("Checking Bank-Vaults annotations for %s: %+v\n", resource.Name, resource.Annotations)
+	for _, key := range keys {
+		value := annotations[key]
+		path := podFieldPath(annotationsPath, key)
 
-	// Look for Bank-Vaults specific annotations that reference Kubernetes resources
-	for key, value := range resource.Annotations {
+		// fmt.Printf("Processing annotation: %s = %s\n", key, value)
 		switch {
 		// vault.security.banzaicloud.io/vault-tls-secret references a Kubernetes Secret
 		case key == "vault.security.banzaicloud.io/vault-tls-secret" && value != "":
-			// fmt.Printf("Found vault-tls-secret reference: %s\n", value)
-			dependencies = append(dependencies, value)
+			dependencies = append(dependencies, models.Dependency{
+				Target:   ReferenceIdentity("Secret", namespace, value),
+				Relation: models.RelationVaultAuth,
+				Path:     path,
+			})
 
 		// vault.security.banzaicloud.io/vault-serviceaccount references a ServiceAccount
 		case key == "vault.security.banzaicloud.io/vault-serviceaccount" && value != "":
-			// fmt.Printf("Found vault-serviceaccount reference: %s\n", value)
-			dependencies = append(dependencies, value)
+			dependencies = append(dependencies, models.Dependency{
+				Target:   ReferenceIdentity("ServiceAccount", namespace, value),
+				Relation: models.RelationVaultAuth,
+				Path:     path,
+			})
+
+		// vault.security.banzaicloud.io/vault-env-from-path references Vault secret paths
+		// This creates a virtual dependency to represent the Vault secret access
+		case key == "vault.security.banzaicloud.io/vault-env-from-path" && value != "":
+			// Parse comma-delimited list of vault paths
+			for _, vaultPath := range strings.Split(value, ",") {
+				vaultPath = strings.TrimSpace(vaultPath)
+				if vaultPath != "" {
+					// Vault secrets are not Kubernetes resources, so they are referenced as
+					// virtual VaultSecret resources named by their path in the vaultstore namespace
+					dependencies = append(dependencies, models.Dependency{
+						Target:   VaultSecretIdentity(vaultPath),
+						Relation: models.RelationInjectsVaultSecret,
+						Path:     path,
+					})
+				}
+			}
 
 		// vault.security.banzaicloud.io/token-auth-mount can reference volumes/secrets
 		// Format: {volume:file} where volume might be a Secret or ConfigMap
 		case key == "vault.security.banzaicloud.io/token-auth-mount" && value != "":
-			// fmt.Printf("Found token-auth-mount reference: %s\n", value)
-			// Parse the volume:file format
-			if strings.Contains(value, ":") {
-				parts := strings.Split(value, ":")
-				if len(parts) >= 1 && parts[0] != "" {
-					// The volume name is the first part
-					// fmt.Printf("Extracted volume name: %s\n", parts[0])
-					dependencies = append(dependencies, parts[0])
-				}
+			// Parse the volume:file format, the volume name is the first part
+			if parts := strings.Split(value, ":"); len(parts) >= 2 && parts[0] != "" {
+				dependencies = append(dependencies, models.Dependency{
+					Target:   p.tokenAuthMountReference(resource, parts[0], namespace),
+					Relation: models.RelationMounts,
+					Path:     path,
+				})
 			}
 		}
 	}
 
-	// fmt.Printf("Final Bank-Vaults dependencies for %s: %+v\n", resource.Name, dependencies)
 	return dependencies
 }
 
-// findBankVaultsTemplateAnnotations finds Bank-Vaults dependencies from pod template annotations
-func (p *Parser) findBankVaultsTemplateAnnotations(resource models.K8sResource) []string {
-	var dependencies []string
-
+// tokenAuthMountReference resolves the volume named by a Bank-Vaults token-auth-mount
+// annotation. Volumes that are not declared in the pod spec are assumed to be Secrets.
+func (p *Parser) tokenAuthMountReference(resource models.K8sResource, volumeName, namespace string) models.ResourceID {
 	if obj, ok := resource.Object.(*unstructured.Unstructured); ok {
-		// Check annotations on the pod template
-		if annotations, found, _ := unstructured.NestedStringMap(obj.Object, "spec", "template", "metadata", "annotations"); found {
-			// fmt.Printf("Found pod template annotations for %s: %+v\n", resource.Name, annotations)
-
-			// Look for Bank-Vaults specific annotations that reference Kubernetes resources
-			for key, value := range annotations {
-				// fmt.Printf("Processing annotation: %s = %s\n", key, value)
-				switch {
-				// vault.security.banzaicloud.io/vault-tls-secret references a Kubernetes Secret
-				case key == "vault.security.banzaicloud.io/vault-tls-secret" && value != "":
-					// fmt.Printf("Found vault-tls-secret template reference: %s\n", value)
-					dependencies = append(dependencies, value)
-
-				// vault.security.banzaicloud.io/vault-serviceaccount references a ServiceAccount
-				case key == "vault.security.banzaicloud.io/vault-serviceaccount" && value != "":
-					// fmt.Printf("Found vault-serviceaccount template reference: %s\n", value)
-					dependencies = append(dependencies, value)
-
-				// vault.security.banzaicloud.io/vault-env-from-path references Vault secret paths
-				// This creates a virtual dependency to represent the Vault secret access
-				case key == "vault.security.banzaicloud.io/vault-env-from-path" && value != "":
-					// Parse comma-delimited list of vault paths
-					paths := strings.Split(value, ",")
-					for _, path := range paths {
-						path = strings.TrimSpace(path)
-						if path != "" {
-							// Create a virtual Vault secret node name from the path
-							// Convert "secret/myapp/config" to "vault-secret-myapp-config"
-							// Remove the "secret/" prefix if it exists, then replace remaining slashes with dashes
-							cleanPath := strings.TrimPrefix(path, "secret/")
-							virtualSecretName := "vault-secret-" + strings.ReplaceAll(strings.ReplaceAll(cleanPath, "/", "-"), ":", "-")
-							// fmt.Printf("Found vault-env-from-path: %s -> %s\n", path, virtualSecretName)
-							dependencies = append(dependencies, virtualSecretName)
-						}
+		if volumes, found, _ := unstructured.NestedSlice(obj.Object, childPath(PodSpecPaths[resource.Kind], "volumes")...); found {
+			for _, vol := range volumes {
+				if volMap, ok := vol.(map[string]interface{}); ok && volMap["name"] == volumeName {
+					if name, found, _ := unstructured.NestedString(volMap, "secret", "secretName"); found {
+						return ReferenceIdentity("Secret", namespace, name)
 					}
-
-				// vault.security.banzaicloud.io/token-auth-mount can reference volumes/secrets
-				// Format: {volume:file} where volume might be a Secret or ConfigMap
-				case key == "vault.security.banzaicloud.io/token-auth-mount" && value != "":
-					// fmt.Printf("Found token-auth-mount template reference: %s\n", value)
-					// Parse the volume:file format
-					if strings.Contains(value, ":") {
-						parts := strings.Split(value, ":")
-						if len(parts) >= 1 && parts[0] != "" {
-							// The volume name is the first part
-							// fmt.Printf("Extracted template volume name: %s\n", parts[0])
-							dependencies = append(dependencies, parts[0])
-						}
+					if name, found, _ := unstructured.NestedString(volMap, "configMap", "name"); found {
+						return ReferenceIdentity("ConfigMap", namespace, name)
 					}
 				}
 			}
 		}
 	}
-
-	// fmt.Printf("Final Bank-Vaults template dependencies for %s: %+v\n", resource.Name, dependencies)
-	return dependencies
+	return ReferenceIdentity("Secret", namespace, volumeName)
 }
 
-// findRoleBindingDependencies finds dependencies for RoleBinding and ClusterRoleBinding resources
-func (p *Parser) findRoleBindingDependencies(resource models.K8sResource) []string {
-	var dependencies []string
-
-	if obj, ok := resource.Object.(*unstructured.Unstructured); ok {
-		// Check subjects (ServiceAccounts, Users, Groups)
-		if subjects, found, _ := unstructured.NestedSlice(obj.Object, "subjects"); found {
-			for _, subject := range subjects {
-				if subjectMap, ok := subject.(map[string]interface{}); ok {
-					if kind, found, _ := unstructured.NestedString(subjectMap, "kind"); found && kind == "ServiceAccount" {
-						if name, found, _ := unstructured.NestedString(subjectMap, "name"); found {
-							dependencies = append(dependencies, name)
-						}
-					}
-				}
+// fieldPath formats path elements as a JSON path such as spec.template.spec.volumes[0].name.
+// Integers become indexes and keys that are not plain identifiers are quoted.
+func fieldPath(elements ...interface{}) string {
+	var b strings.Builder
+	for _, element := range elements {
+		switch e := element.(type) {
+		case int:
+			fmt.Fprintf(&b, "[%d]", e)
+		case string:
+			if strings.ContainsAny(e, "./") {
+				fmt.Fprintf(&b, "['%s']", e)
+				continue
 			}
-		}
-
-		// Check roleRef (Role or ClusterRole)
-		if roleRef, found, _ := unstructured.NestedMap(obj.Object, "roleRef"); found {
-			if name, found, _ := unstructured.NestedString(roleRef, "name"); found {
-				dependencies = append(dependencies, name)
+			if b.Len() > 0 {
+				b.WriteByte('.')
 			}
+			b.WriteString(e)
 		}
 	}
-
-	return dependencies
+	return b.String()
 }
 
-// findServiceAccountUsers finds workloads that use a specific ServiceAccount
-func (p *Parser) findServiceAccountUsers(serviceAccount models.K8sResource, allResources []models.K8sResource) []string {
-	var dependencies []string
-
-	// Look for workloads that reference this ServiceAccount
-	for _, resource := range allResources {
-		if resource.Kind == "Deployment" || resource.Kind == "StatefulSet" || resource.Kind == "DaemonSet" || resource.Kind == "Job" || resource.Kind == "CronJob" {
-			if obj, ok := resource.Object.(*unstructured.Unstructured); ok {
-				// Check serviceAccountName in pod template spec
-				var serviceAccountName string
-				var found bool
-
-				if resource.Kind == "CronJob" {
-					// CronJob has nested jobTemplate.spec.template.spec structure
-					serviceAccountName, found, _ = unstructured.NestedString(obj.Object, "spec", "jobTemplate", "spec", "template", "spec", "serviceAccountName")
-				} else if resource.Kind == "Job" {
-					// Job has spec.template.spec structure
-					serviceAccountName, found, _ = unstructured.NestedString(obj.Object, "spec", "template", "spec", "serviceAccountName")
-				} else {
-					// Deployment, StatefulSet, DaemonSet have spec.template.spec structure
-					serviceAccountName, found, _ = unstructured.NestedString(obj.Object, "spec", "template", "spec", "serviceAccountName")
-				}
+// childPath returns a copy of base extended with fields, for use with the unstructured.Nested* helpers
+func childPath(base []string, fields ...string) []string {
+	path := make([]string, 0, len(base)+len(fields))
+	path = append(path, base...)
+	return append(path, fields...)
+}
 
-				if found && serviceAccountName == serviceAccount.Name {
-					dependencies = append(dependencies, resource.Name)
-				}
-			}
-		}
+// podFieldPath formats a JSON path below base, e.g. the pod spec of a resource
+func podFieldPath(base []string, elements ...interface{}) string {
+	path := make([]interface{}, 0, len(base)+len(elements))
+	for _, field := range base {
+		path = append(path, field)
 	}
-
-	return dependencies
+	return fieldPath(append(path, elements...)...)
 }
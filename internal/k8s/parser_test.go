@@ -0,0 +1,79 @@
+package k8s
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestBankVaultsDependencies(t *testing.T) {
+	collection, err := NewParser("").ParseBuildOutput("fixture", []byte(`
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+  namespace: shop
+  annotations:
+    vault.security.banzaicloud.io/vault-tls-secret: vault-tls
+spec:
+  template:
+    metadata:
+      annotations:
+        vault.security.banzaicloud.io/vault-serviceaccount: vault-auth
+        vault.security.banzaicloud.io/vault-env-from-path: "secret/data/db, secret/data/api,"
+        vault.security.banzaicloud.io/token-auth-mount: "token:vault-token"
+    spec:
+      volumes:
+      - name: token
+        configMap: {name: vault-token}
+---
+apiVersion: batch/v1
+kind: Job
+metadata: {name: migrate, namespace: shop}
+spec:
+  template:
+    metadata:
+      annotations:
+        vault.security.banzaicloud.io/token-auth-mount: "undeclared:token"
+        vault.security.banzaicloud.io/vault-tls-secret: ""
+`))
+	if err != nil {
+		t.Fatalf("ParseBuildOutput: %v", err)
+	}
+
+	var got []string
+	for _, dep := range collection.Dependencies {
+		got = append(got, dep.Source.Name+": "+formatEdge(dep))
+	}
+	want := []string{
+		"web: mounts core/ConfigMap/shop/vault-token spec.template.spec.volumes[0].configMap.name",
+		"web: vault-auth core/Secret/shop/vault-tls metadata.annotations['vault.security.banzaicloud.io/vault-tls-secret']",
+		"web: mounts core/ConfigMap/shop/vault-token spec.template.metadata.annotations['vault.security.banzaicloud.io/token-auth-mount']",
+		"web: injects-vault-secret core/VaultSecret/vaultstore/secret/data/db spec.template.metadata.annotations['vault.security.banzaicloud.io/vault-env-from-path']",
+		"web: injects-vault-secret core/VaultSecret/vaultstore/secret/data/api spec.template.metadata.annotations['vault.security.banzaicloud.io/vault-env-from-path']",
+		"web: vault-auth core/ServiceAccount/shop/vault-auth spec.template.metadata.annotations['vault.security.banzaicloud.io/vault-serviceaccount']",
+		"migrate: mounts core/Secret/shop/undeclared spec.template.metadata.annotations['vault.security.banzaicloud.io/token-auth-mount']",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("edges:\n  %s\nwant:\n  %s", strings.Join(got, "\n  "), strings.Join(want, "\n  "))
+	}
+}
+
+func TestFieldPath(t *testing.T) {
+	tests := []struct {
+		elements []interface{}
+		want     string
+	}{
+		{[]interface{}{"spec", "template", "spec", "volumes", 0, "configMap", "name"}, "spec.template.spec.volumes[0].configMap.name"},
+		{[]interface{}{"subjects", 2, "name"}, "subjects[2].name"},
+		{[]interface{}{"metadata", "annotations", "example.com/owner"}, "metadata.annotations['example.com/owner']"},
+		{[]interface{}{"data", "app.yaml"}, "data['app.yaml']"},
+		{nil, ""},
+	}
+
+	for _, tt := range tests {
+		if got := fieldPath(tt.elements...); got != tt.want {
+			t.Errorf("fieldPath(%v) = %q, want %q", tt.elements, got, tt.want)
+		}
+	}
+}
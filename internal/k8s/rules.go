@@ -0,0 +1,258 @@
+package k8s
+
+import (
+	_ "embed"
+	"fmt"
+	"os"
+	"sort"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/yaml"
+
+	"k8s-to-drawio/pkg/models"
+)
+
+//go:embed rules/builtin.yaml
+var builtinRulesYAML []byte
+
+// ReferenceRule describes where resources of some kinds reference other resources by name.
+// See rules/builtin.yaml for the rules shipped with the tool.
+type ReferenceRule struct {
+	Kinds           []string          `json:"kinds,omitempty"`           // source kinds the rule applies to
+	PodSpec         bool              `json:"podSpec,omitempty"`         // path is relative to the pod spec, kinds default to all pod-bearing kinds
+	Path            string            `json:"path"`                      // JSONPath to the reference, a name string or an object holding the name
+	Name            string            `json:"name,omitempty"`            // field of the reference object holding the target name, defaults to "name"
+	Namespace       string            `json:"namespace,omitempty"`       // field of the reference object holding an explicit target namespace
+	TargetKind      string            `json:"targetKind,omitempty"`      // kind of the referenced resource
+	TargetKindField string            `json:"targetKindField,omitempty"` // field of the reference object holding the target kind
+	TargetGroup     string            `json:"targetGroup,omitempty"`     // API group of the target, defaults to the group of the target kind
+	Relation        string            `json:"relation"`                  // relation label of the resulting edges
+	Optional        string            `json:"optional,omitempty"`        // field of the reference object holding the optional flag
+	Keys            string            `json:"keys,omitempty"`            // JSONPath relative to the reference object selecting consumed keys
+	Match           map[string]string `json:"match,omitempty"`           // fields of the reference object that must have the given values
+}
+
+// ruleFile is the layout of a rules file
+type ruleFile struct {
+	Rules []ReferenceRule `json:"rules"`
+}
+
+// compiledRule is a validated rule with its paths parsed
+type compiledRule struct {
+	ReferenceRule
+	kinds map[string]bool
+	path  []pathSegment
+	keys  []pathSegment
+}
+
+// BuiltinRules returns the reference rules shipped with the tool
+func BuiltinRules() ([]ReferenceRule, error) {
+	return parseRules(builtinRulesYAML, "built-in rules")
+}
+
+// LoadRules reads and validates a YAML rules file
+func LoadRules(filename string) ([]ReferenceRule, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read rules file: %w", err)
+	}
+	return parseRules(data, filename)
+}
+
+func parseRules(data []byte, source string) ([]ReferenceRule, error) {
+	var file ruleFile
+	if err := yaml.UnmarshalStrict(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", source, err)
+	}
+
+	if _, err := compileRules(file.Rules); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", source, err)
+	}
+	return file.Rules, nil
+}
+
+func compileRules(rules []ReferenceRule) ([]compiledRule, error) {
+	compiled := make([]compiledRule, 0, len(rules))
+
+	for i, rule := range rules {
+		switch {
+		case len(rule.Kinds) == 0 && !rule.PodSpec:
+			return nil, fmt.Errorf("rule %d: kinds is required", i+1)
+		case rule.TargetKind == "" && rule.TargetKindField == "":
+			return nil, fmt.Errorf("rule %d: targetKind or targetKindField is required", i+1)
+		case rule.Relation == "":
+			return nil, fmt.Errorf("rule %d: relation is required", i+1)
+		}
+
+		path, err := parsePath(rule.Path)
+		if err != nil {
+			return nil, fmt.Errorf("rule %d: %w", i+1, err)
+		}
+
+		var keys []pathSegment
+		if rule.Keys != "" {
+			if keys, err = parsePath(rule.Keys); err != nil {
+				return nil, fmt.Errorf("rule %d: keys: %w", i+1, err)
+			}
+		}
+
+		kinds := make(map[string]bool)
+		for _, kind := range rule.Kinds {
+			kinds[kind] = true
+		}
+		if rule.PodSpec && len(kinds) == 0 {
+			for kind := range PodSpecPaths {
+				kinds[kind] = true
+			}
+		}
+		if rule.PodSpec {
+			for kind := range kinds {
+				if !IsPodBearing(kind) {
+					return nil, fmt.Errorf("rule %d: podSpec rules only apply to pod-bearing kinds, not %s", i+1, kind)
+				}
+			}
+		}
+
+		compiled = append(compiled, compiledRule{ReferenceRule: rule, kinds: kinds, path: path, keys: keys})
+	}
+
+	return compiled, nil
+}
+
+// dependencies applies the rule to resource. kindGroups maps kinds of the parsed
+// resources to their API group and is used for targets with an unknown kind.
+func (r compiledRule) dependencies(resource models.K8sResource, kindGroups map[string]string) []models.Dependency {
+	if !r.kinds[resource.Kind] {
+		return nil
+	}
+
+	obj, ok := resource.Object.(*unstructured.Unstructured)
+	if !ok {
+		return nil
+	}
+
+	var root interface{} = obj.Object
+	var prefix []interface{}
+	if r.PodSpec {
+		podSpec := PodSpecPaths[resource.Kind]
+		spec, found, _ := unstructured.NestedFieldNoCopy(obj.Object, podSpec...)
+		if !found {
+			return nil
+		}
+		root = spec
+		for _, field := range podSpec {
+			prefix = append(prefix, field)
+		}
+	}
+
+	var dependencies []models.Dependency
+	namespace := ResourceIdentity(resource).Namespace
+
+	for _, match := range evaluatePath(root, r.path) {
+		path := append(append([]interface{}{}, prefix...), match.path...)
+
+		var name string
+		var ref map[string]interface{}
+		switch value := match.value.(type) {
+		case string:
+			name = value
+		case map[string]interface{}:
+			ref = value
+			nameField := r.Name
+			if nameField == "" {
+				nameField = "name"
+			}
+			name, _, _ = unstructured.NestedString(ref, nameField)
+			path = append(path, nameField)
+		}
+		if name == "" || !r.matches(ref) {
+			continue
+		}
+
+		kind := r.TargetKind
+		targetNamespace := namespace
+		optional := false
+		if ref != nil {
+			if r.TargetKindField != "" {
+				if refKind, _, _ := unstructured.NestedString(ref, r.TargetKindField); refKind != "" {
+					kind = refKind
+				}
+			}
+			if r.Namespace != "" {
+				if refNamespace, _, _ := unstructured.NestedString(ref, r.Namespace); refNamespace != "" {
+					targetNamespace = refNamespace
+				}
+			}
+			if r.Optional != "" {
+				optional, _, _ = unstructured.NestedBool(ref, r.Optional)
+			}
+		}
+		if kind == "" {
+			continue
+		}
+
+		dependencies = append(dependencies, models.Dependency{
+			Target:   r.target(kind, targetNamespace, name, kindGroups),
+			Relation: models.Relation(r.Relation),
+			Path:     fieldPath(path...),
+			Optional: optional,
+			Keys:     r.referencedKeys(ref),
+		})
+	}
+
+	return dependencies
+}
+
+// matches checks the match fields of the rule against the reference object
+func (r compiledRule) matches(ref map[string]interface{}) bool {
+	for field, expected := range r.Match {
+		if ref == nil {
+			return false
+		}
+		if value, _, _ := unstructured.NestedString(ref, field); value != expected {
+			return false
+		}
+	}
+	return true
+}
+
+func (r compiledRule) referencedKeys(ref map[string]interface{}) []string {
+	if ref == nil || r.keys == nil {
+		return nil
+	}
+
+	var keys []string
+	for _, match := range evaluatePath(ref, r.keys) {
+		if key, ok := match.value.(string); ok && key != "" {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+func (r compiledRule) target(kind, namespace, name string, kindGroups map[string]string) models.ResourceID {
+	target := ReferenceIdentity(kind, namespace, name)
+	if r.TargetGroup != "" {
+		target.Group = CanonicalGroup(kind, r.TargetGroup)
+	} else if _, known := KindGroups[kind]; !known {
+		target.Group = kindGroups[kind]
+	}
+	return target
+}
+
+// collectKindGroups maps the kinds of the parsed resources to their API group. If
+// resources of one kind come from several groups, the alphabetically first group wins.
+func collectKindGroups(resources []models.K8sResource) map[string]string {
+	groups := make(map[string][]string)
+	for _, resource := range resources {
+		id := ResourceIdentity(resource)
+		groups[id.Kind] = append(groups[id.Kind], id.Group)
+	}
+
+	kindGroups := make(map[string]string, len(groups))
+	for kind, candidates := range groups {
+		sort.Strings(candidates)
+		kindGroups[kind] = candidates[0]
+	}
+	return kindGroups
+}
@@ -0,0 +1,405 @@
+package k8s
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"k8s-to-drawio/pkg/models"
+)
+
+// builtinFixture references at least one target through every built-in rule
+const builtinFixture = `
+apiVersion: apps/v1
+kind: Deployment
+metadata: {name: web, namespace: shop}
+spec:
+  template:
+    spec:
+      serviceAccountName: web
+      imagePullSecrets:
+      - name: registry
+      volumes:
+      - name: config
+        configMap:
+          name: web-config
+          optional: true
+          items: [{key: app.yaml, path: app.yaml}]
+      - name: creds
+        secret:
+          secretName: web-creds
+          items: [{key: password, path: password}]
+      - name: data
+        persistentVolumeClaim: {claimName: web-data}
+      - name: csi
+        csi:
+          driver: secrets-store.csi.k8s.io
+          nodePublishSecretRef: {name: csi-creds}
+      - name: projected
+        projected:
+          sources:
+          - configMap: {name: projected-config}
+          - secret: {name: projected-secret, optional: true, items: [{key: token, path: token}]}
+      initContainers:
+      - name: init
+        envFrom:
+        - configMapRef: {name: init-env}
+      containers:
+      - name: app
+        envFrom:
+        - secretRef: {name: app-env, optional: true}
+        env:
+        - name: LEVEL
+          valueFrom:
+            configMapKeyRef: {name: settings, key: level}
+        - name: TOKEN
+          valueFrom:
+            secretKeyRef: {name: tokens, key: token, optional: true}
+---
+apiVersion: networking.k8s.io/v1
+kind: Ingress
+metadata: {name: web, namespace: shop}
+spec:
+  defaultBackend:
+    service: {name: fallback}
+  rules:
+  - http:
+      paths:
+      - backend:
+          service: {name: web}
+---
+apiVersion: extensions/v1beta1
+kind: Ingress
+metadata: {name: legacy, namespace: shop}
+spec:
+  backend: {serviceName: legacy-fallback}
+  rules:
+  - http:
+      paths:
+      - backend: {serviceName: legacy-web}
+---
+apiVersion: route.openshift.io/v1
+kind: Route
+metadata: {name: web, namespace: shop}
+spec:
+  to: {kind: Service, name: web}
+  alternateBackends:
+  - {kind: Service, name: canary}
+  - {kind: Other, name: ignored}
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: RoleBinding
+metadata: {name: web, namespace: shop}
+subjects:
+- {kind: ServiceAccount, name: web}
+- {kind: User, name: alice}
+- {kind: ServiceAccount, name: ci, namespace: tools}
+roleRef: {apiGroup: rbac.authorization.k8s.io, kind: Role, name: web}
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRoleBinding
+metadata: {name: view}
+subjects:
+- {kind: ServiceAccount, name: ci, namespace: tools}
+roleRef: {apiGroup: rbac.authorization.k8s.io, kind: ClusterRole, name: view}
+`
+
+// builtinEdges lists the edges each built-in rule, identified by its path, finds in builtinFixture
+var builtinEdges = map[string][]string{
+	"volumes[*].configMap": {
+		"mounts core/ConfigMap/shop/web-config spec.template.spec.volumes[0].configMap.name optional keys=app.yaml",
+	},
+	"volumes[*].secret": {
+		"mounts core/Secret/shop/web-creds spec.template.spec.volumes[1].secret.secretName keys=password",
+	},
+	"volumes[*].persistentVolumeClaim": {
+		"mounts core/PersistentVolumeClaim/shop/web-data spec.template.spec.volumes[2].persistentVolumeClaim.claimName",
+	},
+	"volumes[*].csi.nodePublishSecretRef": {
+		"mounts core/Secret/shop/csi-creds spec.template.spec.volumes[3].csi.nodePublishSecretRef.name",
+	},
+	"volumes[*].projected.sources[*].configMap": {
+		"mounts core/ConfigMap/shop/projected-config spec.template.spec.volumes[4].projected.sources[0].configMap.name",
+	},
+	"volumes[*].projected.sources[*].secret": {
+		"mounts core/Secret/shop/projected-secret spec.template.spec.volumes[4].projected.sources[1].secret.name optional keys=token",
+	},
+	"['initContainers','containers','ephemeralContainers'][*].envFrom[*].configMapRef": {
+		"envFrom core/ConfigMap/shop/init-env spec.template.spec.initContainers[0].envFrom[0].configMapRef.name",
+	},
+	"['initContainers','containers','ephemeralContainers'][*].envFrom[*].secretRef": {
+		"envFrom core/Secret/shop/app-env spec.template.spec.containers[0].envFrom[0].secretRef.name optional",
+	},
+	"['initContainers','containers','ephemeralContainers'][*].env[*].valueFrom.configMapKeyRef": {
+		"envFrom core/ConfigMap/shop/settings spec.template.spec.containers[0].env[0].valueFrom.configMapKeyRef.name keys=level",
+	},
+	"['initContainers','containers','ephemeralContainers'][*].env[*].valueFrom.secretKeyRef": {
+		"envFrom core/Secret/shop/tokens spec.template.spec.containers[0].env[1].valueFrom.secretKeyRef.name optional keys=token",
+	},
+	"imagePullSecrets[*]": {
+		"image-pull core/Secret/shop/registry spec.template.spec.imagePullSecrets[0].name",
+	},
+	"serviceAccountName": {
+		"runs-as core/ServiceAccount/shop/web spec.template.spec.serviceAccountName",
+	},
+	"spec.rules[*].http.paths[*].backend.service": {
+		"routes-to core/Service/shop/web spec.rules[0].http.paths[0].backend.service.name",
+	},
+	"spec.defaultBackend.service": {
+		"routes-to core/Service/shop/fallback spec.defaultBackend.service.name",
+	},
+	"spec.rules[*].http.paths[*].backend": {
+		"routes-to core/Service/shop/legacy-web spec.rules[0].http.paths[0].backend.serviceName",
+	},
+	"spec.backend": {
+		"routes-to core/Service/shop/legacy-fallback spec.backend.serviceName",
+	},
+	"spec.to": {
+		"routes-to core/Service/shop/web spec.to.name",
+	},
+	"spec.alternateBackends[*]": {
+		"routes-to core/Service/shop/canary spec.alternateBackends[0].name",
+	},
+	"subjects[*]": {
+		"binds core/ServiceAccount/shop/web subjects[0].name",
+		"binds core/ServiceAccount/tools/ci subjects[2].name",
+		"binds core/ServiceAccount/tools/ci subjects[0].name",
+	},
+	"roleRef": {
+		"binds rbac.authorization.k8s.io/Role/shop/web roleRef.name",
+		"binds rbac.authorization.k8s.io/ClusterRole//view roleRef.name",
+	},
+}
+
+func TestBuiltinRules(t *testing.T) {
+	builtin, err := BuiltinRules()
+	if err != nil {
+		t.Fatalf("BuiltinRules: %v", err)
+	}
+	rules, err := compileRules(builtin)
+	if err != nil {
+		t.Fatalf("compileRules: %v", err)
+	}
+
+	collection, err := NewParser("").ParseBuildOutput("fixture", []byte(builtinFixture))
+	if err != nil {
+		t.Fatalf("ParseBuildOutput: %v", err)
+	}
+	kindGroups := collectKindGroups(collection.Resources)
+
+	for _, rule := range rules {
+		t.Run(rule.Path, func(t *testing.T) {
+			want, ok := builtinEdges[rule.Path]
+			if !ok {
+				t.Fatalf("no expected edges for rule, add a reference to builtinFixture")
+			}
+
+			var got []string
+			for _, resource := range collection.Resources {
+				for _, dep := range rule.dependencies(resource, kindGroups) {
+					got = append(got, formatEdge(dep))
+				}
+			}
+
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("edges:\n  %s\nwant:\n  %s", strings.Join(got, "\n  "), strings.Join(want, "\n  "))
+			}
+		})
+	}
+}
+
+func TestCompileRulesErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		rule ReferenceRule
+		want string
+	}{
+		{
+			name: "no kinds",
+			rule: ReferenceRule{Path: "spec.ref", TargetKind: "Secret", Relation: "uses"},
+			want: "kinds is required",
+		},
+		{
+			name: "no target kind",
+			rule: ReferenceRule{Kinds: []string{"App"}, Path: "spec.ref", Relation: "uses"},
+			want: "targetKind or targetKindField is required",
+		},
+		{
+			name: "no relation",
+			rule: ReferenceRule{Kinds: []string{"App"}, Path: "spec.ref", TargetKind: "Secret"},
+			want: "relation is required",
+		},
+		{
+			name: "invalid path",
+			rule: ReferenceRule{Kinds: []string{"App"}, Path: "spec.refs[*", TargetKind: "Secret", Relation: "uses"},
+			want: "unterminated '['",
+		},
+		{
+			name: "invalid keys",
+			rule: ReferenceRule{Kinds: []string{"App"}, Path: "spec.ref", TargetKind: "Secret", Relation: "uses", Keys: "items[x]"},
+			want: "keys:",
+		},
+		{
+			name: "pod spec of a kind without pods",
+			rule: ReferenceRule{Kinds: []string{"Service"}, PodSpec: true, Path: "volumes[*]", TargetKind: "Secret", Relation: "uses"},
+			want: "not Service",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := compileRules([]ReferenceRule{tt.rule})
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("compileRules() error = %v, want one containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+// formatEdge formats a dependency as "relation target path", followed by the
+// optional flag and the consumed keys if set
+func formatEdge(dep models.Dependency) string {
+	edge := fmt.Sprintf("%s %s %s", dep.Relation, dep.Target, dep.Path)
+	if dep.Optional {
+		edge += " optional"
+	}
+	if len(dep.Keys) > 0 {
+		edge += " keys=" + strings.Join(dep.Keys, ",")
+	}
+	return edge
+}
+
+func TestPodSpecKinds(t *testing.T) {
+	collection, err := NewParser("").ParseBuildOutput("fixture", []byte(`
+apiVersion: v1
+kind: Pod
+metadata: {name: debug, namespace: shop, labels: {app: web}}
+spec:
+  serviceAccountName: debug
+  volumes:
+  - {name: creds, secret: {secretName: creds}}
+---
+apiVersion: apps/v1
+kind: ReplicaSet
+metadata: {name: web-5d9f, namespace: shop}
+spec:
+  template:
+    metadata: {labels: {app: web}}
+    spec:
+      containers:
+      - name: app
+        envFrom: [{configMapRef: {name: settings}}]
+---
+apiVersion: batch/v1
+kind: Job
+metadata: {name: migrate, namespace: shop, labels: {app: web}}
+spec:
+  template:
+    metadata: {labels: {app: migrate}}
+    spec:
+      imagePullSecrets: [{name: registry}]
+---
+apiVersion: batch/v1
+kind: CronJob
+metadata: {name: backup, namespace: shop}
+spec:
+  jobTemplate:
+    spec:
+      template:
+        metadata: {labels: {app: web}}
+        spec:
+          volumes:
+          - {name: data, persistentVolumeClaim: {claimName: data}}
+---
+apiVersion: v1
+kind: Service
+metadata: {name: web, namespace: shop}
+spec:
+  selector: {app: web}
+`))
+	if err != nil {
+		t.Fatalf("ParseBuildOutput: %v", err)
+	}
+
+	var got []string
+	for _, dep := range collection.Dependencies {
+		got = append(got, dep.Source.Name+": "+formatEdge(dep))
+	}
+
+	// The Job is not selected by its own labels but by those of its pod template
+	want := []string{
+		"debug: mounts core/Secret/shop/creds spec.volumes[0].secret.secretName",
+		"debug: runs-as core/ServiceAccount/shop/debug spec.serviceAccountName",
+		"web-5d9f: envFrom core/ConfigMap/shop/settings spec.template.spec.containers[0].envFrom[0].configMapRef.name",
+		"migrate: image-pull core/Secret/shop/registry spec.template.spec.imagePullSecrets[0].name",
+		"backup: mounts core/PersistentVolumeClaim/shop/data spec.jobTemplate.spec.template.spec.volumes[0].persistentVolumeClaim.claimName",
+		"web: selects core/Pod/shop/debug spec.selector",
+		"web: selects apps/ReplicaSet/shop/web-5d9f spec.selector",
+		"web: selects batch/CronJob/shop/backup spec.selector",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("edges:\n  %s\nwant:\n  %s", strings.Join(got, "\n  "), strings.Join(want, "\n  "))
+	}
+}
+
+func TestPodSpecContainersAndVolumes(t *testing.T) {
+	collection, err := NewParser("").ParseBuildOutput("fixture", []byte(`
+apiVersion: v1
+kind: Pod
+metadata: {name: debug, namespace: shop}
+spec:
+  initContainers:
+  - name: wait
+    env:
+    - name: HOST
+      valueFrom: {configMapKeyRef: {name: endpoints, key: db}}
+  containers:
+  - name: app
+  ephemeralContainers:
+  - name: shell
+    envFrom: [{secretRef: {name: debug-creds}}]
+    env:
+    - name: POD
+      valueFrom: {fieldRef: {fieldPath: metadata.name}}
+  volumes:
+  - name: token
+    projected:
+      sources:
+      - serviceAccountToken: {path: token}
+      - downwardAPI: {items: [{path: labels, fieldRef: {fieldPath: metadata.labels}}]}
+      - configMap: {name: ca, items: [{key: ca.crt, path: ca.crt}]}
+      - secret: {name: client-cert}
+  - name: inline
+    csi: {driver: inline.storage.kubernetes.io}
+  - name: vault
+    csi:
+      driver: secrets-store.csi.k8s.io
+      nodePublishSecretRef: {name: vault-creds}
+  - name: scratch
+    emptyDir: {}
+`))
+	if err != nil {
+		t.Fatalf("ParseBuildOutput: %v", err)
+	}
+
+	var got []string
+	for _, dep := range collection.Dependencies {
+		got = append(got, formatEdge(dep))
+	}
+	sort.Strings(got)
+
+	// Service account tokens, downward API, field references, CSI volumes without a
+	// secret and emptyDir volumes reference nothing
+	want := []string{
+		"envFrom core/ConfigMap/shop/endpoints spec.initContainers[0].env[0].valueFrom.configMapKeyRef.name keys=db",
+		"envFrom core/Secret/shop/debug-creds spec.ephemeralContainers[0].envFrom[0].secretRef.name",
+		"mounts core/ConfigMap/shop/ca spec.volumes[0].projected.sources[2].configMap.name keys=ca.crt",
+		"mounts core/Secret/shop/client-cert spec.volumes[0].projected.sources[3].secret.name",
+		"mounts core/Secret/shop/vault-creds spec.volumes[2].csi.nodePublishSecretRef.name",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("edges:\n  %s\nwant:\n  %s", strings.Join(got, "\n  "), strings.Join(want, "\n  "))
+	}
+}
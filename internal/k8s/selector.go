@@ -0,0 +1,133 @@
+package k8s
+
+import (
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"k8s-to-drawio/pkg/models"
+)
+
+// Selector matches resources by their labels within a set of namespaces
+type Selector struct {
+	labels     labels.Selector
+	namespaces map[string]bool // nil matches resources in every namespace
+}
+
+// NewSetSelector builds a selector from a plain label map as used by Service spec.selector.
+// An empty map selects nothing, like a Service without selector.
+func NewSetSelector(set map[string]interface{}, namespaces ...string) (Selector, error) {
+	if len(set) == 0 {
+		return Selector{labels: labels.Nothing(), namespaces: namespaceSet(namespaces)}, nil
+	}
+
+	stringSet := make(labels.Set, len(set))
+	for key, value := range set {
+		str, ok := value.(string)
+		if !ok {
+			return Selector{}, fmt.Errorf("selector value for %q is not a string", key)
+		}
+		stringSet[key] = str
+	}
+
+	selector, err := labels.ValidatedSelectorFromSet(stringSet)
+	if err != nil {
+		return Selector{}, err
+	}
+	return Selector{labels: selector, namespaces: namespaceSet(namespaces)}, nil
+}
+
+// NewLabelSelector builds a selector from a metav1.LabelSelector in unstructured form,
+// supporting both matchLabels and matchExpressions. An empty selector matches everything.
+func NewLabelSelector(selector map[string]interface{}, namespaces ...string) (Selector, error) {
+	var labelSelector metav1.LabelSelector
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(selector, &labelSelector); err != nil {
+		return Selector{}, err
+	}
+
+	parsed, err := metav1.LabelSelectorAsSelector(&labelSelector)
+	if err != nil {
+		return Selector{}, err
+	}
+	return Selector{labels: parsed, namespaces: namespaceSet(namespaces)}, nil
+}
+
+// Matches reports whether a resource with the given labels in namespace is selected
+func (s Selector) Matches(resourceLabels map[string]string, namespace string) bool {
+	if s.labels == nil {
+		return false
+	}
+	if s.namespaces != nil && !s.namespaces[namespace] {
+		return false
+	}
+	return s.labels.Matches(labels.Set(resourceLabels))
+}
+
+// AnyNamespace returns a copy of the selector that matches resources in every namespace
+func (s Selector) AnyNamespace() Selector {
+	s.namespaces = nil
+	return s
+}
+
+func namespaceSet(namespaces []string) map[string]bool {
+	set := make(map[string]bool, len(namespaces))
+	for _, namespace := range namespaces {
+		set[namespace] = true
+	}
+	return set
+}
+
+// PodLabels returns the labels of the pods created by a pod-bearing resource
+func PodLabels(resource models.K8sResource) (map[string]string, bool) {
+	if !IsPodBearing(resource.Kind) {
+		return nil, false
+	}
+
+	metadata, hasTemplate := PodTemplateMetadataPaths[resource.Kind]
+	if !hasTemplate {
+		return resource.Labels, true
+	}
+
+	if obj, ok := resource.Object.(*unstructured.Unstructured); ok {
+		podLabels, _, _ := unstructured.NestedStringMap(obj.Object, childPath(metadata, "labels")...)
+		return podLabels, true
+	}
+	return nil, false
+}
+
+// ResourceLabels returns the metadata labels of resources of the given kind
+func ResourceLabels(kind string) func(models.K8sResource) (map[string]string, bool) {
+	return func(resource models.K8sResource) (map[string]string, bool) {
+		if resource.Kind != kind {
+			return nil, false
+		}
+		return resource.Labels, true
+	}
+}
+
+// selectedDependencies returns a dependency on every resource matched by selector. labelsOf
+// returns the labels to match for a resource, or false if the resource cannot be selected.
+func selectedDependencies(selector Selector, allResources []models.K8sResource, labelsOf func(models.K8sResource) (map[string]string, bool), relation models.Relation, path string) []models.Dependency {
+	var dependencies []models.Dependency
+
+	for _, other := range allResources {
+		resourceLabels, selectable := labelsOf(other)
+		if !selectable {
+			continue
+		}
+
+		otherID := ResourceIdentity(other)
+		if selector.Matches(resourceLabels, otherID.Namespace) {
+			dependencies = append(dependencies, models.Dependency{
+				Target:   otherID,
+				Relation: relation,
+				Path:     path,
+			})
+		}
+	}
+
+	return dependencies
+}
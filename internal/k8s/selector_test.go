@@ -0,0 +1,287 @@
+package k8s
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+
+	"k8s-to-drawio/pkg/models"
+)
+
+func TestSetSelector(t *testing.T) {
+	tests := []struct {
+		name      string
+		set       map[string]interface{}
+		labels    map[string]string
+		namespace string
+		want      bool
+	}{
+		{
+			name:      "matching labels",
+			set:       map[string]interface{}{"app": "web"},
+			labels:    map[string]string{"app": "web", "tier": "frontend"},
+			namespace: "shop",
+			want:      true,
+		},
+		{
+			name:      "different value",
+			set:       map[string]interface{}{"app": "web"},
+			labels:    map[string]string{"app": "api"},
+			namespace: "shop",
+		},
+		{
+			name:      "missing label",
+			set:       map[string]interface{}{"app": "web", "tier": "frontend"},
+			labels:    map[string]string{"app": "web"},
+			namespace: "shop",
+		},
+		{
+			name:      "other namespace",
+			set:       map[string]interface{}{"app": "web"},
+			labels:    map[string]string{"app": "web"},
+			namespace: "default",
+		},
+		{
+			name:      "empty selector selects nothing",
+			set:       map[string]interface{}{},
+			labels:    map[string]string{"app": "web"},
+			namespace: "shop",
+		},
+		{
+			name:      "empty selector and no labels",
+			set:       nil,
+			namespace: "shop",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			selector, err := NewSetSelector(tt.set, "shop")
+			if err != nil {
+				t.Fatalf("NewSetSelector: %v", err)
+			}
+			if got := selector.Matches(tt.labels, tt.namespace); got != tt.want {
+				t.Errorf("Matches(%v, %q) = %v, want %v", tt.labels, tt.namespace, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSetSelectorErrors(t *testing.T) {
+	for name, set := range map[string]map[string]interface{}{
+		"value is not a string": {"replicas": int64(3)},
+		"invalid label value":   {"app": "not a valid value"},
+	} {
+		if _, err := NewSetSelector(set); err == nil {
+			t.Errorf("%s: NewSetSelector(%v) succeeded, want an error", name, set)
+		}
+	}
+}
+
+func TestLabelSelector(t *testing.T) {
+	tests := []struct {
+		name     string
+		selector string
+		labels   map[string]string
+		want     bool
+	}{
+		{
+			name:     "empty selector selects everything",
+			selector: "{}",
+			labels:   map[string]string{"app": "web"},
+			want:     true,
+		},
+		{
+			name:     "empty selector selects resources without labels",
+			selector: "{}",
+			want:     true,
+		},
+		{
+			name:     "matchLabels",
+			selector: "{matchLabels: {app: web}}",
+			labels:   map[string]string{"app": "web", "tier": "frontend"},
+			want:     true,
+		},
+		{
+			name:     "matchLabels mismatch",
+			selector: "{matchLabels: {app: web}}",
+			labels:   map[string]string{"app": "api"},
+		},
+		{
+			name:     "In",
+			selector: "{matchExpressions: [{key: tier, operator: In, values: [frontend, backend]}]}",
+			labels:   map[string]string{"tier": "backend"},
+			want:     true,
+		},
+		{
+			name:     "In without the label",
+			selector: "{matchExpressions: [{key: tier, operator: In, values: [frontend]}]}",
+			labels:   map[string]string{"app": "web"},
+		},
+		{
+			name:     "NotIn",
+			selector: "{matchExpressions: [{key: tier, operator: NotIn, values: [cache]}]}",
+			labels:   map[string]string{"tier": "frontend"},
+			want:     true,
+		},
+		{
+			name:     "NotIn without the label",
+			selector: "{matchExpressions: [{key: tier, operator: NotIn, values: [cache]}]}",
+			want:     true,
+		},
+		{
+			name:     "NotIn excluded value",
+			selector: "{matchExpressions: [{key: tier, operator: NotIn, values: [cache]}]}",
+			labels:   map[string]string{"tier": "cache"},
+		},
+		{
+			name:     "Exists",
+			selector: "{matchExpressions: [{key: canary, operator: Exists}]}",
+			labels:   map[string]string{"canary": ""},
+			want:     true,
+		},
+		{
+			name:     "DoesNotExist",
+			selector: "{matchExpressions: [{key: canary, operator: DoesNotExist}]}",
+			labels:   map[string]string{"canary": "true"},
+		},
+		{
+			name:     "matchLabels and matchExpressions must all match",
+			selector: "{matchLabels: {app: web}, matchExpressions: [{key: tier, operator: In, values: [frontend]}]}",
+			labels:   map[string]string{"app": "web", "tier": "backend"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			selector, err := NewLabelSelector(decodeYAML(t, tt.selector), "shop")
+			if err != nil {
+				t.Fatalf("NewLabelSelector: %v", err)
+			}
+			if got := selector.Matches(tt.labels, "shop"); got != tt.want {
+				t.Errorf("Matches(%v) = %v, want %v", tt.labels, got, tt.want)
+			}
+			if selector.Matches(tt.labels, "default") {
+				t.Errorf("Matches(%v) in another namespace = true, want false", tt.labels)
+			}
+			if got := selector.AnyNamespace().Matches(tt.labels, "default"); got != tt.want {
+				t.Errorf("AnyNamespace().Matches(%v) = %v, want %v", tt.labels, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLabelSelectorErrors(t *testing.T) {
+	for _, selector := range []string{
+		"{matchExpressions: [{key: tier, operator: Matches, values: [web]}]}",
+		"{matchExpressions: [{key: tier, operator: In}]}",
+		"{matchExpressions: [{key: tier, operator: Exists, values: [web]}]}",
+		"{matchLabels: [app]}",
+	} {
+		if _, err := NewLabelSelector(decodeYAML(t, selector)); err == nil {
+			t.Errorf("NewLabelSelector(%s) succeeded, want an error", selector)
+		}
+	}
+}
+
+func TestSelectorDependencies(t *testing.T) {
+	collection, err := NewParser("").ParseBuildOutput("fixture", []byte(`
+apiVersion: v1
+kind: Service
+metadata: {name: web, namespace: shop, labels: {app: web}}
+spec:
+  selector: {app: web}
+---
+apiVersion: v1
+kind: Service
+metadata: {name: external, namespace: shop}
+spec:
+  selector: {}
+---
+apiVersion: v1
+kind: Service
+metadata: {name: web, namespace: staging, labels: {app: web}}
+spec:
+  selector: {app: web}
+---
+apiVersion: v1
+kind: Service
+metadata: {name: web, namespace: dev, labels: {app: web}}
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata: {name: web, namespace: shop, labels: {app: other}}
+spec:
+  template:
+    metadata: {labels: {app: web, track: stable}}
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata: {name: web, namespace: staging}
+spec:
+  template:
+    metadata: {labels: {app: web, track: canary}}
+---
+apiVersion: monitoring.coreos.com/v1
+kind: ServiceMonitor
+metadata: {name: own-namespace, namespace: shop}
+spec:
+  selector: {matchLabels: {app: web}}
+---
+apiVersion: monitoring.coreos.com/v1
+kind: ServiceMonitor
+metadata: {name: any-namespace, namespace: monitoring}
+spec:
+  namespaceSelector: {any: true}
+  selector: {matchLabels: {app: web}}
+---
+apiVersion: monitoring.coreos.com/v1
+kind: ServiceMonitor
+metadata: {name: match-names, namespace: monitoring}
+spec:
+  namespaceSelector: {matchNames: [staging, dev]}
+  selector: {matchLabels: {app: web}}
+---
+apiVersion: monitoring.coreos.com/v1
+kind: PodMonitor
+metadata: {name: canary, namespace: monitoring}
+spec:
+  namespaceSelector: {any: true}
+  selector:
+    matchExpressions: [{key: track, operator: NotIn, values: [stable]}]
+---
+apiVersion: networking.k8s.io/v1
+kind: NetworkPolicy
+metadata: {name: all-pods, namespace: staging}
+spec:
+  podSelector: {}
+`))
+	if err != nil {
+		t.Fatalf("ParseBuildOutput: %v", err)
+	}
+
+	targets := make(map[string][]string)
+	for _, dep := range collection.Dependencies {
+		if dep.Relation == models.RelationSelects || dep.Relation == models.RelationScrapes {
+			targets[dep.Source.String()] = append(targets[dep.Source.String()], dep.Target.String())
+		}
+	}
+	for _, list := range targets {
+		sort.Strings(list)
+	}
+
+	want := map[string][]string{
+		"core/Service/shop/web":                                   {"apps/Deployment/shop/web"},
+		"core/Service/staging/web":                                {"apps/Deployment/staging/web"},
+		"monitoring.coreos.com/ServiceMonitor/shop/own-namespace": {"core/Service/shop/web"},
+		"monitoring.coreos.com/ServiceMonitor/monitoring/any-namespace": {
+			"core/Service/dev/web", "core/Service/shop/web", "core/Service/staging/web",
+		},
+		"monitoring.coreos.com/ServiceMonitor/monitoring/match-names": {"core/Service/dev/web", "core/Service/staging/web"},
+		"monitoring.coreos.com/PodMonitor/monitoring/canary":          {"apps/Deployment/staging/web"},
+		"networking.k8s.io/NetworkPolicy/staging/all-pods":            {"apps/Deployment/staging/web"},
+	}
+	if !reflect.DeepEqual(targets, want) {
+		t.Errorf("selected targets = %v, want %v", targets, want)
+	}
+}
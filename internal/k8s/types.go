@@ -1,5 +1,9 @@
 package k8s
 
+import (
+	"k8s-to-drawio/pkg/models"
+)
+
 // SupportedResourceKinds lists all Kubernetes resource types supported by the parser
 var SupportedResourceKinds = []string{
 	"Deployment",
@@ -23,6 +27,9 @@ var SupportedResourceKinds = []string{
 	"CronJob",
 	"Route",
 	"ServiceMonitor",
+	"PodMonitor",
+	"NetworkPolicy",
+	"PodDisruptionBudget",
 }
 
 // ResourceCategories groups resources by their functional category
@@ -48,6 +55,98 @@ var ResourceCategories = map[string]string{
 	"ClusterRole":           "rbac",
 	"ClusterRoleBinding":    "rbac",
 	"ServiceMonitor":        "monitoring",
+	"PodMonitor":            "monitoring",
+	"NetworkPolicy":         "networking",
+	"PodDisruptionBudget":   "workload",
+	"VaultSecret":           "vault",
+}
+
+// KindGroups maps resource kinds to their API group, used to build identities
+// for references that only carry a kind and a name
+var KindGroups = map[string]string{
+	"Deployment":          "apps",
+	"StatefulSet":         "apps",
+	"DaemonSet":           "apps",
+	"ReplicaSet":          "apps",
+	"Job":                 "batch",
+	"CronJob":             "batch",
+	"Ingress":             "networking.k8s.io",
+	"Role":                "rbac.authorization.k8s.io",
+	"RoleBinding":         "rbac.authorization.k8s.io",
+	"ClusterRole":         "rbac.authorization.k8s.io",
+	"ClusterRoleBinding":  "rbac.authorization.k8s.io",
+	"Route":               "route.openshift.io",
+	"ServiceMonitor":      "monitoring.coreos.com",
+	"PodMonitor":          "monitoring.coreos.com",
+	"NetworkPolicy":       "networking.k8s.io",
+	"PodDisruptionBudget": "policy",
+}
+
+// LegacyKindGroups lists the API groups that served a kind before it moved to its
+// group in KindGroups, such as extensions/v1beta1 Deployments and Ingresses, and
+// OpenShift 3 Routes and roles
+var LegacyKindGroups = map[string][]string{
+	"Deployment":         {"extensions"},
+	"DaemonSet":          {"extensions"},
+	"ReplicaSet":         {"extensions"},
+	"Ingress":            {"extensions"},
+	"NetworkPolicy":      {"extensions"},
+	"Route":              {""},
+	"Role":               {"authorization.openshift.io"},
+	"RoleBinding":        {"authorization.openshift.io"},
+	"ClusterRole":        {"authorization.openshift.io"},
+	"ClusterRoleBinding": {"authorization.openshift.io"},
+}
+
+// CanonicalGroup returns the group of KindGroups for a kind declared in one of its
+// legacy groups, so resources and references to them get the same identity
+// whichever apiVersion the manifest uses. Other groups are returned unchanged.
+func CanonicalGroup(kind, group string) string {
+	for _, legacy := range LegacyKindGroups[kind] {
+		if group == legacy {
+			return KindGroups[kind]
+		}
+	}
+	return group
+}
+
+// ClusterScopedKinds lists resource kinds that do not live in a namespace
+var ClusterScopedKinds = map[string]bool{
+	"Namespace":          true,
+	"PersistentVolume":   true,
+	"ClusterRole":        true,
+	"ClusterRoleBinding": true,
+}
+
+// PodSpecPaths maps pod-bearing kinds to the location of their pod spec
+var PodSpecPaths = map[string][]string{
+	"Pod":         {"spec"},
+	"Deployment":  {"spec", "template", "spec"},
+	"StatefulSet": {"spec", "template", "spec"},
+	"DaemonSet":   {"spec", "template", "spec"},
+	"ReplicaSet":  {"spec", "template", "spec"},
+	"Job":         {"spec", "template", "spec"},
+	"CronJob":     {"spec", "jobTemplate", "spec", "template", "spec"},
+}
+
+// ContainerFields lists the pod spec fields holding containers
+var ContainerFields = []string{"initContainers", "containers", "ephemeralContainers"}
+
+// PodTemplateMetadataPaths maps kinds with a pod template to the location of the
+// template metadata. Pods are missing here as their metadata is the resource metadata.
+var PodTemplateMetadataPaths = map[string][]string{
+	"Deployment":  {"spec", "template", "metadata"},
+	"StatefulSet": {"spec", "template", "metadata"},
+	"DaemonSet":   {"spec", "template", "metadata"},
+	"ReplicaSet":  {"spec", "template", "metadata"},
+	"Job":         {"spec", "template", "metadata"},
+	"CronJob":     {"spec", "jobTemplate", "spec", "template", "metadata"},
+}
+
+// IsPodBearing checks if a resource kind contains a pod spec
+func IsPodBearing(kind string) bool {
+	_, exists := PodSpecPaths[kind]
+	return exists
 }
 
 // IsResourceSupported checks if a given resource kind is supported
@@ -67,3 +166,50 @@ func GetResourceCategory(kind string) string {
 	}
 	return "unknown"
 }
+
+// ResourceIdentity returns the identity of a parsed resource
+func ResourceIdentity(resource models.K8sResource) models.ResourceID {
+	group := KindGroups[resource.Kind]
+	if resource.Object != nil {
+		if gvk := resource.Object.GetObjectKind().GroupVersionKind(); gvk.Kind != "" {
+			group = CanonicalGroup(resource.Kind, gvk.Group)
+		}
+	}
+	return models.ResourceID{
+		Group:     group,
+		Kind:      resource.Kind,
+		Namespace: scopedNamespace(resource.Kind, resource.Namespace),
+		Name:      resource.Name,
+	}
+}
+
+// ReferenceIdentity returns the identity of a resource referenced by kind, namespace and name
+func ReferenceIdentity(kind, namespace, name string) models.ResourceID {
+	return models.ResourceID{
+		Group:     KindGroups[kind],
+		Kind:      kind,
+		Namespace: scopedNamespace(kind, namespace),
+		Name:      name,
+	}
+}
+
+// VaultSecretIdentity returns the identity of the virtual VaultSecret resource for a Vault path
+func VaultSecretIdentity(path string) models.ResourceID {
+	return models.ResourceID{
+		Kind:      "VaultSecret",
+		Namespace: models.VaultSecretNamespace,
+		Name:      path,
+	}
+}
+
+// scopedNamespace clears the namespace of cluster-scoped kinds and defaults
+// namespaced resources without an explicit namespace to "default"
+func scopedNamespace(kind, namespace string) string {
+	if ClusterScopedKinds[kind] {
+		return ""
+	}
+	if namespace == "" {
+		return "default"
+	}
+	return namespace
+}
@@ -0,0 +1,175 @@
+package k8s
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestCanonicalGroup(t *testing.T) {
+	tests := []struct {
+		kind, group, want string
+	}{
+		{"Deployment", "extensions", "apps"},
+		{"Deployment", "apps", "apps"},
+		{"Ingress", "extensions", "networking.k8s.io"},
+		{"NetworkPolicy", "extensions", "networking.k8s.io"},
+		{"Route", "", "route.openshift.io"},
+		{"ClusterRole", "authorization.openshift.io", "rbac.authorization.k8s.io"},
+		{"Ingress", "example.com", "example.com"},
+		{"ConfigMap", "", ""},
+		{"StatefulSet", "extensions", "extensions"},
+	}
+
+	for _, tt := range tests {
+		if got := CanonicalGroup(tt.kind, tt.group); got != tt.want {
+			t.Errorf("CanonicalGroup(%q, %q) = %q, want %q", tt.kind, tt.group, got, tt.want)
+		}
+	}
+}
+
+func TestLegacyKindGroups(t *testing.T) {
+	for kind, groups := range LegacyKindGroups {
+		if _, exists := KindGroups[kind]; !exists {
+			t.Errorf("legacy kind %s has no current group in KindGroups", kind)
+		}
+		for _, group := range groups {
+			if group == KindGroups[kind] {
+				t.Errorf("legacy group %q of %s is its current group", group, kind)
+			}
+		}
+	}
+}
+
+func TestResourceIdentity(t *testing.T) {
+	tests := []struct {
+		name     string
+		manifest string
+		want     string
+	}{
+		{
+			name:     "core resource",
+			manifest: "{apiVersion: v1, kind: ConfigMap, metadata: {name: config, namespace: shop}}",
+			want:     "core/ConfigMap/shop/config",
+		},
+		{
+			name:     "namespace defaults to default",
+			manifest: "{apiVersion: v1, kind: Secret, metadata: {name: creds}}",
+			want:     "core/Secret/default/creds",
+		},
+		{
+			name:     "cluster-scoped resource has no namespace",
+			manifest: "{apiVersion: rbac.authorization.k8s.io/v1, kind: ClusterRole, metadata: {name: view, namespace: shop}}",
+			want:     "rbac.authorization.k8s.io/ClusterRole//view",
+		},
+		{
+			name:     "legacy group",
+			manifest: "{apiVersion: extensions/v1beta1, kind: Deployment, metadata: {name: web, namespace: shop}}",
+			want:     "apps/Deployment/shop/web",
+		},
+		{
+			name:     "OpenShift 3 Route",
+			manifest: "{apiVersion: v1, kind: Route, metadata: {name: web, namespace: shop}}",
+			want:     "route.openshift.io/Route/shop/web",
+		},
+		{
+			name:     "custom resource with a built-in kind name",
+			manifest: "{apiVersion: example.com/v1, kind: Ingress, metadata: {name: web, namespace: shop}}",
+			want:     "example.com/Ingress/shop/web",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ResourceIdentity(testResource(t, tt.manifest)).String(); got != tt.want {
+				t.Errorf("ResourceIdentity() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReferenceIdentity(t *testing.T) {
+	tests := []struct {
+		kind, namespace, name string
+		want                  string
+	}{
+		{"Secret", "shop", "creds", "core/Secret/shop/creds"},
+		{"Secret", "", "creds", "core/Secret/default/creds"},
+		{"Deployment", "shop", "web", "apps/Deployment/shop/web"},
+		{"ClusterRole", "shop", "view", "rbac.authorization.k8s.io/ClusterRole//view"},
+		{"PersistentVolume", "", "data", "core/PersistentVolume//data"},
+	}
+
+	for _, tt := range tests {
+		if got := ReferenceIdentity(tt.kind, tt.namespace, tt.name).String(); got != tt.want {
+			t.Errorf("ReferenceIdentity(%q, %q, %q) = %s, want %s", tt.kind, tt.namespace, tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestNamespaceAwareReferences(t *testing.T) {
+	collection, err := NewParser("").ParseBuildOutput("fixture", []byte(`
+apiVersion: apps/v1
+kind: Deployment
+metadata: {name: web, namespace: shop}
+spec:
+  template:
+    spec:
+      volumes:
+      - name: config
+        configMap: {name: config}
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata: {name: web}
+spec:
+  template:
+    metadata: {labels: {app: web}}
+    spec:
+      volumes:
+      - name: config
+        configMap: {name: config}
+---
+apiVersion: extensions/v1beta1
+kind: Deployment
+metadata: {name: legacy, namespace: shop}
+spec:
+  template:
+    metadata: {labels: {app: web}}
+---
+apiVersion: v1
+kind: Service
+metadata: {name: web, namespace: shop}
+spec:
+  selector: {app: web}
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: RoleBinding
+metadata: {name: web, namespace: shop}
+subjects:
+- {kind: ServiceAccount, name: web}
+roleRef: {apiGroup: rbac.authorization.k8s.io, kind: ClusterRole, name: view}
+`))
+	if err != nil {
+		t.Fatalf("ParseBuildOutput: %v", err)
+	}
+
+	var got []string
+	for _, dep := range collection.Dependencies {
+		got = append(got, dep.Source.String()+" -> "+dep.Target.String())
+	}
+	sort.Strings(got)
+
+	// References without a namespace stay in the namespace of the referencing
+	// resource, and the legacy Deployment is selected by its current identity
+	want := []string{
+		"apps/Deployment/default/web -> core/ConfigMap/default/config",
+		"apps/Deployment/shop/web -> core/ConfigMap/shop/config",
+		"core/Service/shop/web -> apps/Deployment/shop/legacy",
+		"rbac.authorization.k8s.io/RoleBinding/shop/web -> core/ServiceAccount/shop/web",
+		"rbac.authorization.k8s.io/RoleBinding/shop/web -> rbac.authorization.k8s.io/ClusterRole//view",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("dependencies = %v, want %v", got, want)
+	}
+}
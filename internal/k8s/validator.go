@@ -26,14 +26,14 @@ func (v *Validator) Validate(collection *models.ResourceCollection) error {
 }
 
 func (v *Validator) validateResource(resource models.K8sResource) error {
-	if resource.Name == "" {
-		return fmt.Errorf("resource name is required")
-	}
-
 	if resource.Kind == "" {
 		return fmt.Errorf("resource kind is required")
 	}
 
+	if resource.Name == "" {
+		return fmt.Errorf("resource name is required")
+	}
+
 	// Add more specific validations based on resource type
 	switch resource.Kind {
 	case "Service":
@@ -0,0 +1,175 @@
+package k8s
+
+import (
+	"bufio"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// IgnoreFileName is the name of the ignore file read from the root of the input directory
+const IgnoreFileName = ".k8s-to-drawio-ignore"
+
+// DefaultIncludePatterns are used when no include patterns are given
+var DefaultIncludePatterns = []string{"*.yaml", "*.yml"}
+
+// globRule is a single include/exclude pattern
+type globRule struct {
+	pattern string
+	dirOnly bool
+	negate  bool
+}
+
+// findManifestFiles walks dir recursively and returns the manifest files that
+// match the include patterns and are not excluded by the exclude patterns or
+// the ignore file. Hidden directories are skipped.
+func findManifestFiles(dir string, include, exclude []string) ([]string, error) {
+	if len(include) == 0 {
+		include = DefaultIncludePatterns
+	}
+
+	includeRules := parseGlobRules(include)
+	excludeRules := parseGlobRules(exclude)
+
+	ignoreRules, err := readIgnoreFile(filepath.Join(dir, IgnoreFileName))
+	if err != nil {
+		return nil, err
+	}
+	excludeRules = append(excludeRules, ignoreRules...)
+
+	var files []string
+	err = filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		if rel == "." {
+			return nil
+		}
+		rel = filepath.ToSlash(rel)
+
+		if d.IsDir() {
+			if strings.HasPrefix(d.Name(), ".") || matchesRules(excludeRules, rel, true) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if d.Name() == IgnoreFileName {
+			return nil
+		}
+		if !matchesRules(includeRules, rel, false) || matchesRules(excludeRules, rel, false) {
+			return nil
+		}
+
+		files = append(files, p)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return files, nil
+}
+
+// readIgnoreFile reads gitignore-style patterns from filename. A missing file is not an error.
+func readIgnoreFile(filename string) ([]globRule, error) {
+	file, err := os.Open(filename)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var patterns []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		patterns = append(patterns, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return parseGlobRules(patterns), nil
+}
+
+func parseGlobRules(patterns []string) []globRule {
+	rules := make([]globRule, 0, len(patterns))
+	for _, pattern := range patterns {
+		rule := globRule{}
+		if strings.HasPrefix(pattern, "!") {
+			rule.negate = true
+			pattern = pattern[1:]
+		}
+		if strings.HasSuffix(pattern, "/") {
+			rule.dirOnly = true
+			pattern = strings.TrimSuffix(pattern, "/")
+		}
+		rule.pattern = strings.TrimPrefix(filepath.ToSlash(pattern), "/")
+		if rule.pattern != "" {
+			rules = append(rules, rule)
+		}
+	}
+	return rules
+}
+
+// matchesRules reports whether rel matches the rules. Like gitignore, the last
+// matching rule wins, so a negated pattern can re-include an earlier match.
+func matchesRules(rules []globRule, rel string, isDir bool) bool {
+	matched := false
+	for _, rule := range rules {
+		if rule.dirOnly && !isDir {
+			continue
+		}
+		if matchGlob(rule.pattern, rel) {
+			matched = !rule.negate
+		}
+	}
+	return matched
+}
+
+// matchGlob matches a slash-separated relative path against a glob pattern.
+// Patterns without a slash match the base name at any depth; patterns with a
+// slash are anchored at the input directory, and "**" matches zero or more
+// path segments.
+func matchGlob(pattern, rel string) bool {
+	if !strings.Contains(pattern, "/") {
+		ok, _ := path.Match(pattern, path.Base(rel))
+		return ok
+	}
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(rel, "/"))
+}
+
+func matchSegments(pattern, segments []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(segments); i++ {
+				if matchSegments(pattern[1:], segments[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(segments) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], segments[0]); !ok {
+			return false
+		}
+		pattern = pattern[1:]
+		segments = segments[1:]
+	}
+	return len(segments) == 0
+}
@@ -0,0 +1,173 @@
+package k8s
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestFindManifestFiles(t *testing.T) {
+	tree := []string{
+		"top.yaml",
+		".dotfile.yaml",
+		"app/deployment.yaml",
+		"app/service.yml",
+		"app/README.md",
+		"app/values.json",
+		"base/kustomization.yaml",
+		"base/nested/deep/config.yaml",
+		"overlays/dev/patch.yaml",
+		"overlays/prod/patch.yaml",
+		"charts/web/templates/deployment.yaml",
+		".git/config.yaml",
+		".github/workflows/ci.yaml",
+	}
+
+	tests := []struct {
+		name    string
+		include []string
+		exclude []string
+		ignore  string
+		want    []string
+	}{
+		{
+			name: "defaults walk recursively and skip hidden directories",
+			want: []string{
+				".dotfile.yaml",
+				"app/deployment.yaml",
+				"app/service.yml",
+				"base/kustomization.yaml",
+				"base/nested/deep/config.yaml",
+				"charts/web/templates/deployment.yaml",
+				"overlays/dev/patch.yaml",
+				"overlays/prod/patch.yaml",
+				"top.yaml",
+			},
+		},
+		{
+			name:    "pattern without slash matches the base name at any depth",
+			include: []string{"*.json", "README.md"},
+			want:    []string{"app/README.md", "app/values.json"},
+		},
+		{
+			name:    "pattern with slash is anchored",
+			include: []string{"app/*"},
+			want:    []string{"app/README.md", "app/deployment.yaml", "app/service.yml", "app/values.json"},
+		},
+		{
+			name:    "double star matches several segments",
+			include: []string{"base/**/*.yaml"},
+			want:    []string{"base/kustomization.yaml", "base/nested/deep/config.yaml"},
+		},
+		{
+			name:    "double star matches no segment",
+			include: []string{"**/top.yaml", "**/templates/*.yaml"},
+			want:    []string{"charts/web/templates/deployment.yaml", "top.yaml"},
+		},
+		{
+			name:    "exclude takes precedence over include",
+			include: []string{"app/*.yaml", "top.yaml"},
+			exclude: []string{"deployment.yaml"},
+			want:    []string{"top.yaml"},
+		},
+		{
+			name:    "excluded directories are skipped",
+			exclude: []string{"overlays/**", "base", "charts/"},
+			want:    []string{".dotfile.yaml", "app/deployment.yaml", "app/service.yml", "top.yaml"},
+		},
+		{
+			name:   "ignore file",
+			ignore: "# generated files\n\n*.yml\ncharts/\nbase/nested/\n",
+			want: []string{
+				".dotfile.yaml",
+				"app/deployment.yaml",
+				"base/kustomization.yaml",
+				"overlays/dev/patch.yaml",
+				"overlays/prod/patch.yaml",
+				"top.yaml",
+			},
+		},
+		{
+			name:    "negated pattern re-includes an earlier match",
+			include: []string{"**/patch.yaml", "*.yml"},
+			ignore:  "patch.yaml\n!overlays/prod/patch.yaml\n*.yml\n!app/service.yml\n",
+			want:    []string{"app/service.yml", "overlays/prod/patch.yaml"},
+		},
+		{
+			name:    "directory-only pattern does not match files",
+			include: []string{"top.yaml", "app/deployment.yaml"},
+			ignore:  "top.yaml/\n/app/deployment.yaml/\n",
+			want:    []string{"app/deployment.yaml", "top.yaml"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for _, file := range tree {
+				writeTestFile(t, filepath.Join(dir, file), "kind: ConfigMap\n")
+			}
+			if tt.ignore != "" {
+				writeTestFile(t, filepath.Join(dir, IgnoreFileName), tt.ignore)
+			}
+
+			files, err := findManifestFiles(dir, tt.include, tt.exclude)
+			if err != nil {
+				t.Fatalf("findManifestFiles: %v", err)
+			}
+
+			var got []string
+			for _, file := range files {
+				rel, err := filepath.Rel(dir, file)
+				if err != nil {
+					t.Fatal(err)
+				}
+				got = append(got, filepath.ToSlash(rel))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("files = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatchGlob(t *testing.T) {
+	tests := []struct {
+		pattern, rel string
+		want         bool
+	}{
+		{"*.yaml", "a/b/c.yaml", true},
+		{"*.yaml", "a/b/c.yml", false},
+		{"a/*.yaml", "a/c.yaml", true},
+		{"a/*.yaml", "a/b/c.yaml", false},
+		{"a/*.yaml", "x/a/c.yaml", false},
+		{"a/**", "a", true},
+		{"a/**", "a/b/c.yaml", true},
+		{"**/c.yaml", "c.yaml", true},
+		{"**/c.yaml", "a/b/c.yaml", true},
+		{"a/**/c.yaml", "a/c.yaml", true},
+		{"a/**/c.yaml", "a/b/d/c.yaml", true},
+		{"a/**/c.yaml", "b/c.yaml", false},
+		{"a/?/c.yaml", "a/b/c.yaml", true},
+		{"a/[bc]/*", "a/d/x", false},
+	}
+
+	for _, tt := range tests {
+		if got := matchGlob(tt.pattern, tt.rel); got != tt.want {
+			t.Errorf("matchGlob(%q, %q) = %v, want %v", tt.pattern, tt.rel, got, tt.want)
+		}
+	}
+}
+
+// writeTestFile creates filename with its parent directories
+func writeTestFile(t *testing.T, filename, content string) {
+	t.Helper()
+
+	if err := os.MkdirAll(filepath.Dir(filename), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filename, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
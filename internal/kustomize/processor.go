@@ -15,9 +15,9 @@ type Processor struct {
 	parser *k8s.Parser
 }
 
-func NewProcessor(namespace string) *Processor {
+func NewProcessor(opts k8s.ParserOptions) *Processor {
 	return &Processor{
-		parser: k8s.NewParser(namespace),
+		parser: k8s.NewParserWithOptions(opts),
 	}
 }
 
@@ -47,18 +47,16 @@ func (p *Processor) Process(dir string) (*models.ResourceCollection, error) {
 		return nil, fmt.Errorf("failed to convert resources to YAML: %w", err)
 	}
 
-	// Create temporary file with generated YAML
-	tempFile, err := os.CreateTemp("", "kustomized-*.yaml")
+	// Parse the generated YAML, diagnostics point at the kustomization since the
+	// build output is never written to a file
+	collection, err := p.parser.ParseBuildOutput(fmt.Sprintf("kustomize build of %s", dir), yaml)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create temp file: %w", err)
+		return nil, err
 	}
-	defer os.Remove(tempFile.Name())
 
-	if _, err := tempFile.Write(yaml); err != nil {
-		return nil, fmt.Errorf("failed to write temp file: %w", err)
+	for i := range collection.Resources {
+		collection.Resources[i].SourceFile = kustomizationPath
 	}
-	tempFile.Close()
 
-	// Parse the generated YAML
-	return p.parser.ParseFile(tempFile.Name())
+	return collection, nil
 }
@@ -0,0 +1,47 @@
+package kustomize
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"k8s-to-drawio/internal/k8s"
+)
+
+func TestProcessReportsKustomization(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"kustomization.yaml": "apiVersion: kustomize.config.k8s.io/v1beta1\nkind: Kustomization\nnamespace: shop\nresources: [app.yaml]\n",
+		"app.yaml":           "apiVersion: v1\nkind: ConfigMap\nmetadata: {name: app}\n---\napiVersion: v1\nkind: Secret\nmetadata: {name: creds}\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	collection, err := NewProcessor(k8s.ParserOptions{Strict: true}).Process(dir)
+	if err != nil {
+		t.Fatalf("Process: %v", err)
+	}
+
+	// The build output is not a file, so resources point at the kustomization
+	want := filepath.Join(dir, "kustomization.yaml")
+	if len(collection.Resources) != 2 {
+		t.Fatalf("got %d resources, want 2", len(collection.Resources))
+	}
+	for _, resource := range collection.Resources {
+		if resource.SourceFile != want || resource.Namespace != "shop" {
+			t.Errorf("%s/%s from %s, want shop from %s", resource.Namespace, resource.Name, resource.SourceFile, want)
+		}
+	}
+	if len(collection.Diagnostics) != 0 || len(collection.Skipped) != 0 {
+		t.Errorf("got diagnostics %v and skipped %v, want none", collection.Diagnostics, collection.Skipped)
+	}
+}
+
+func TestProcessWithoutKustomization(t *testing.T) {
+	if _, err := NewProcessor(k8s.ParserOptions{}).Process(t.TempDir()); err == nil {
+		t.Error("Process of a directory without kustomization.yaml succeeded, want an error")
+	}
+}
@@ -0,0 +1,162 @@
+package mermaid
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+
+	"k8s-to-drawio/internal/drawio"
+	"k8s-to-drawio/internal/output"
+	"k8s-to-drawio/pkg/models"
+)
+
+// Shape is the pair of brackets around the label of a Mermaid flowchart node
+type Shape struct {
+	Open  string
+	Close string
+}
+
+// Shapes contains the Mermaid node shapes for the Draw.io shapes of the resource
+// styles, see drawio.GetShapeStyle. Rectangles are drawn rounded or square like in
+// Draw.io, shapes without a Mermaid equivalent as rectangles.
+var Shapes = map[string]Shape{
+	"ellipse":   {"([", "])"},
+	"rhombus":   {"{", "}"},
+	"note":      {">", "]"},
+	"cylinder3": {"[(", ")]"},
+	"hexagon":   {"{{", "}}"},
+	"trapezoid": {"[/", "\\]"},
+	"monitor":   {"[[", "]]"},
+}
+
+// invalidIDChars matches the characters that are not allowed in Mermaid node IDs
+var invalidIDChars = regexp.MustCompile(`[^A-Za-z0-9_]`)
+
+// FormatName is the name of the output format of this package
+const FormatName = "mermaid"
+
+func init() {
+	output.Register(output.Format{
+		Name:       FormatName,
+		Extensions: []string{".mmd", ".mermaid"},
+		New: func(opts output.Options) (output.Generator, error) {
+			return NewGenerator(Options{NoNamespaces: opts.NoNamespaces}), nil
+		},
+	})
+}
+
+// Options configures the generator
+type Options struct {
+	NoNamespaces bool // do not group resources in namespace subgraphs
+}
+
+// Generator writes diagrams as Mermaid flowcharts. Mermaid lays out the chart
+// itself, so the positions of the diagram are not used.
+type Generator struct {
+	noNamespaces bool
+}
+
+// NewGenerator creates a generator with the given options
+func NewGenerator(opts Options) *Generator {
+	return &Generator{noNamespaces: opts.NoNamespaces}
+}
+
+// Generate writes the Mermaid flowchart of a diagram: namespaces as subgraphs,
+// resources as nodes shaped by kind and labelled connections
+func (g *Generator) Generate(w io.Writer, diagram *models.Diagram) error {
+	b := bufio.NewWriter(w)
+	b.WriteString("flowchart TB\n")
+
+	if g.noNamespaces {
+		for _, node := range diagram.Nodes {
+			b.WriteString("    " + nodeDefinition(node) + "\n")
+		}
+	} else {
+		namespaces, namespaceNodes := models.GroupByNamespace(diagram.Nodes)
+		for _, name := range namespaces {
+			fmt.Fprintf(b, "    subgraph %s[\"%s\"]\n", nodeID("ns-"+name), escape(models.NamespaceLabel(name)))
+			for _, i := range namespaceNodes[name] {
+				b.WriteString("        " + nodeDefinition(diagram.Nodes[i]) + "\n")
+			}
+			b.WriteString("    end\n")
+		}
+	}
+
+	for _, connection := range diagram.Connections {
+		label := connection.Label
+		arrow := "-->"
+		if connection.Optional {
+			label += " (optional)"
+			arrow = "-.->"
+		}
+		if label != "" {
+			arrow += fmt.Sprintf("|\"%s\"|", escape(label))
+		}
+		fmt.Fprintf(b, "    %s %s %s\n", nodeID(connection.SourceID), arrow, nodeID(connection.TargetID))
+	}
+
+	// Colour nodes with the fill and stroke of their Draw.io style, one class per kind
+	kinds := make(map[string][]string)
+	for _, node := range diagram.Nodes {
+		kinds[node.Kind] = append(kinds[node.Kind], nodeID(node.ID))
+	}
+	kindNames := make([]string, 0, len(kinds))
+	for kind := range kinds {
+		kindNames = append(kindNames, kind)
+	}
+	sort.Strings(kindNames)
+
+	for _, kind := range kindNames {
+		fmt.Fprintf(b, "    classDef %s %s\n", nodeID(kind), classStyle(kind))
+		fmt.Fprintf(b, "    class %s %s\n", strings.Join(kinds[kind], ","), nodeID(kind))
+	}
+
+	return b.Flush()
+}
+
+// nodeDefinition returns the Mermaid definition of a resource node
+func nodeDefinition(node models.DiagramNode) string {
+	shape := nodeShape(node.Kind)
+
+	label := escape(node.Kind) + "<br/>" + escape(node.Label)
+	if node.Kind == "VaultSecret" {
+		label = escape(node.Label)
+	}
+	return fmt.Sprintf("%s%s\"%s\"%s", nodeID(node.ID), shape.Open, label, shape.Close)
+}
+
+// nodeShape returns the Mermaid shape of the Draw.io shape of a kind
+func nodeShape(kind string) Shape {
+	name, attrs := drawio.ParseStyle(drawio.GetShapeStyle(kind))
+	if shape, exists := Shapes[name]; exists {
+		return shape
+	}
+	if attrs["rounded"] == "1" {
+		return Shape{"(", ")"}
+	}
+	return Shape{"[", "]"}
+}
+
+// classStyle returns the classDef style of a kind from the colours of its Draw.io style
+func classStyle(kind string) string {
+	_, attrs := drawio.ParseStyle(drawio.GetShapeStyle(kind))
+	style := fmt.Sprintf("fill:%s,stroke:%s", attrs["fillColor"], attrs["strokeColor"])
+	if attrs["fontColor"] != "" {
+		style += ",color:" + attrs["fontColor"]
+	}
+	return style
+}
+
+// nodeID returns a Mermaid node ID for a cell ID. Cell IDs end with a hash of the
+// resource identity, so replacing characters does not make them collide.
+func nodeID(id string) string {
+	return invalidIDChars.ReplaceAllString(id, "_")
+}
+
+// escape replaces the characters that end or break a quoted Mermaid label with entity codes
+func escape(text string) string {
+	return strings.NewReplacer(`"`, "#quot;", "&", "#amp;", "<", "#lt;", ">", "#gt;", "\n", "<br/>").Replace(text)
+}
@@ -0,0 +1,107 @@
+package mermaid
+
+import (
+	"bytes"
+	"testing"
+
+	"k8s-to-drawio/pkg/models"
+)
+
+func TestGenerate(t *testing.T) {
+	diagram := &models.Diagram{
+		Nodes: []models.DiagramNode{
+			{ID: "deployment-shop-web-f9619453", Kind: "Deployment", Label: "web", Namespace: "shop"},
+			{ID: "service-shop-web-1a2b3c4d", Kind: "Service", Label: `say "hi" <b> & co`, Namespace: "shop"},
+			{ID: "vaultsecret-vaultstore-secret-data-db-61bca63b", Kind: "VaultSecret", Label: "secret/data/db", Namespace: models.VaultSecretNamespace},
+			{ID: "clusterrole-view-a5ca6fd5", Kind: "ClusterRole", Label: "view"},
+		},
+		Connections: []models.Connection{
+			{SourceID: "service-shop-web-1a2b3c4d", TargetID: "deployment-shop-web-f9619453", Label: "selects"},
+			{SourceID: "deployment-shop-web-f9619453", TargetID: "vaultsecret-vaultstore-secret-data-db-61bca63b", Label: "a&b", Optional: true},
+			{SourceID: "deployment-shop-web-f9619453", TargetID: "clusterrole-view-a5ca6fd5"},
+		},
+	}
+
+	want := `flowchart TB
+    subgraph ns_default["Namespace: default"]
+        clusterrole_view_a5ca6fd5("ClusterRole<br/>view")
+    end
+    subgraph ns_shop["Namespace: shop"]
+        deployment_shop_web_f9619453("Deployment<br/>web")
+        service_shop_web_1a2b3c4d(["Service<br/>say #quot;hi#quot; #lt;b#gt; #amp; co"])
+    end
+    subgraph ns_vaultstore["vaultstore"]
+        vaultsecret_vaultstore_secret_data_db_61bca63b{{"secret/data/db"}}
+    end
+    service_shop_web_1a2b3c4d -->|"selects"| deployment_shop_web_f9619453
+    deployment_shop_web_f9619453 -.->|"a#amp;b (optional)"| vaultsecret_vaultstore_secret_data_db_61bca63b
+    deployment_shop_web_f9619453 --> clusterrole_view_a5ca6fd5
+`
+	got := generate(t, NewGenerator(Options{}), diagram)
+	if !bytes.HasPrefix(got, []byte(want)) {
+		t.Errorf("output:\n%s\nwant prefix:\n%s", got, want)
+	}
+
+	// One class per kind with the colours of the Draw.io style
+	for _, line := range []string{
+		"    classDef Deployment fill:",
+		"    class deployment_shop_web_f9619453 Deployment\n",
+		"    class vaultsecret_vaultstore_secret_data_db_61bca63b VaultSecret\n",
+	} {
+		if !bytes.Contains(got, []byte(line)) {
+			t.Errorf("output does not contain %q", line)
+		}
+	}
+}
+
+func TestGenerateWithoutNamespaces(t *testing.T) {
+	diagram := &models.Diagram{
+		Nodes: []models.DiagramNode{
+			{ID: "configmap-shop-settings-0a1b2c3d", Kind: "ConfigMap", Label: "settings", Namespace: "shop"},
+			{ID: "ingress-shop-web-72e1d89c", Kind: "Ingress", Label: "web", Namespace: "shop"},
+		},
+	}
+
+	got := generate(t, NewGenerator(Options{NoNamespaces: true}), diagram)
+	want := `flowchart TB
+    configmap_shop_settings_0a1b2c3d>"ConfigMap<br/>settings"]
+    ingress_shop_web_72e1d89c{"Ingress<br/>web"}
+`
+	if !bytes.HasPrefix(got, []byte(want)) || bytes.Contains(got, []byte("subgraph")) {
+		t.Errorf("output:\n%s\nwant prefix:\n%s", got, want)
+	}
+}
+
+func TestEscape(t *testing.T) {
+	tests := map[string]string{
+		"plain":          "plain",
+		`a "quoted" b`:   "a #quot;quoted#quot; b",
+		"<script>":       "#lt;script#gt;",
+		"&lt;":           "#amp;lt;",
+		"two\nlines":     "two<br/>lines",
+		"Namespace: a-b": "Namespace: a-b",
+	}
+
+	for text, want := range tests {
+		if got := escape(text); got != want {
+			t.Errorf("escape(%q) = %q, want %q", text, got, want)
+		}
+	}
+}
+
+func TestNodeID(t *testing.T) {
+	if got, want := nodeID("secret-default-my.secret-v1/x-f99d386b"), "secret_default_my_secret_v1_x_f99d386b"; got != want {
+		t.Errorf("nodeID() = %q, want %q", got, want)
+	}
+}
+
+// generate returns the output of a generator
+func generate(t *testing.T, generator *Generator, diagram *models.Diagram) []byte {
+	t.Helper()
+
+	var out bytes.Buffer
+	if err := generator.Generate(&out, diagram); err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	return out.Bytes()
+}
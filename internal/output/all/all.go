@@ -0,0 +1,11 @@
+// Package all registers every output format of the tool with the output package.
+// Importing it makes the formats available wherever diagrams are converted.
+package all
+
+import (
+	_ "k8s-to-drawio/internal/dot"
+	_ "k8s-to-drawio/internal/drawio"
+	_ "k8s-to-drawio/internal/jsondiagram"
+	_ "k8s-to-drawio/internal/mermaid"
+	_ "k8s-to-drawio/internal/svg"
+)
@@ -0,0 +1,140 @@
+package output
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s-to-drawio/pkg/models"
+)
+
+// Generator writes a diagram in an output format
+type Generator interface {
+	Generate(w io.Writer, diagram *models.Diagram) error
+}
+
+// Options are the conversion settings passed to the generators of all formats. The
+// settings of a Feature are only set for formats that support it.
+type Options struct {
+	Layout       string
+	NoNamespaces bool
+	Modified     time.Time // modification time recorded in the output, none when zero
+	UpdateFile   string    // existing file to update
+	MarkDeleted  bool      // keep resources deleted since UpdateFile, marked as deleted
+	MultiPage    bool      // one page per namespace plus an overview page
+	Icons        string    // icon set
+	Theme        string    // built-in theme name or theme file
+	Compressed   bool      // compress the output
+	Legend       bool      // add a legend of the shapes and connection styles used
+	Layers       bool      // one layer per resource category
+}
+
+// Feature is a set of optional settings of Options that only some formats support
+type Feature int
+
+const (
+	FeatureUpdate     Feature = 1 << iota // UpdateFile and MarkDeleted
+	FeatureMultiPage                      // MultiPage
+	FeatureIcons                          // Icons
+	FeatureTheme                          // Theme
+	FeatureCompressed                     // Compressed
+	FeatureLegend                         // Legend
+	FeatureLayers                         // Layers
+)
+
+// Format is an output format. Packages register their formats with Register when
+// they are imported.
+type Format struct {
+	Name       string
+	Extensions []string // output file extensions detected as this format, such as ".drawio"
+	Features   Feature  // optional settings the generator supports
+	New        func(opts Options) (Generator, error)
+}
+
+// Supports reports whether the generator of a format supports all the given features
+func (f Format) Supports(features Feature) bool {
+	return f.Features&features == features
+}
+
+var (
+	mu      sync.RWMutex
+	formats = make(map[string]Format)
+)
+
+// Register makes an output format available by name and file extension. It panics
+// if the name or an extension is already registered, like database/sql.Register.
+func Register(format Format) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if format.New == nil {
+		panic(fmt.Sprintf("output: format %s has no generator", format.Name))
+	}
+	if _, exists := formats[format.Name]; exists {
+		panic(fmt.Sprintf("output: format %s registered twice", format.Name))
+	}
+	for _, existing := range formats {
+		for _, extension := range format.Extensions {
+			if hasExtension(existing, extension) {
+				panic(fmt.Sprintf("output: extension %s of format %s already registered by %s", extension, format.Name, existing.Name))
+			}
+		}
+	}
+	formats[format.Name] = format
+}
+
+// Lookup returns the format with the given name
+func Lookup(name string) (Format, error) {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	format, exists := formats[name]
+	if !exists {
+		return Format{}, fmt.Errorf("unknown output format %q, expected one of %s", name, strings.Join(names(), ", "))
+	}
+	return format, nil
+}
+
+// Detect returns the format registered for the extension of a file name
+func Detect(filename string) (Format, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	extension := filepath.Ext(filename)
+	for _, format := range formats {
+		if hasExtension(format, extension) {
+			return format, true
+		}
+	}
+	return Format{}, false
+}
+
+// Names returns the names of the registered formats in alphabetical order
+func Names() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+	return names()
+}
+
+func names() []string {
+	list := make([]string, 0, len(formats))
+	for name := range formats {
+		list = append(list, name)
+	}
+	sort.Strings(list)
+	return list
+}
+
+// hasExtension reports whether a format is detected from a file extension, ignoring case
+func hasExtension(format Format, extension string) bool {
+	for _, candidate := range format.Extensions {
+		if strings.EqualFold(candidate, extension) {
+			return true
+		}
+	}
+	return false
+}
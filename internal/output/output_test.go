@@ -0,0 +1,126 @@
+package output
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"k8s-to-drawio/pkg/models"
+)
+
+type testGenerator struct{}
+
+func (testGenerator) Generate(w io.Writer, diagram *models.Diagram) error {
+	_, err := io.WriteString(w, "test")
+	return err
+}
+
+func newTestGenerator(opts Options) (Generator, error) {
+	return testGenerator{}, nil
+}
+
+// registerTestFormats registers formats only used by the tests of this package, once
+func registerTestFormats() {
+	if _, err := Lookup("test-text"); err == nil {
+		return
+	}
+	Register(Format{Name: "test-text", Extensions: []string{".test-txt", ".test-text"}, New: newTestGenerator})
+	Register(Format{Name: "test-pages", Extensions: []string{".test-pages"}, Features: FeatureMultiPage | FeatureTheme, New: newTestGenerator})
+}
+
+func TestLookup(t *testing.T) {
+	registerTestFormats()
+
+	format, err := Lookup("test-pages")
+	if err != nil {
+		t.Fatalf("Lookup: %v", err)
+	}
+	if format.Name != "test-pages" || format.New == nil {
+		t.Errorf("Lookup returned %+v", format)
+	}
+
+	_, err = Lookup("test-unknown")
+	if err == nil {
+		t.Fatal("Lookup of an unknown format succeeded, want an error")
+	}
+	if !strings.Contains(err.Error(), "test-pages, test-text") {
+		t.Errorf("error %q does not list the registered formats", err)
+	}
+}
+
+func TestDetect(t *testing.T) {
+	registerTestFormats()
+
+	tests := map[string]string{
+		"out/diagram.test-txt":  "test-text",
+		"diagram.test-text":     "test-text",
+		"DIAGRAM.TEST-PAGES":    "test-pages",
+		"diagram.test-txt.bak":  "",
+		"diagram":               "",
+		"test-text/diagram.png": "",
+	}
+
+	for filename, want := range tests {
+		format, exists := Detect(filename)
+		if format.Name != want || exists != (want != "") {
+			t.Errorf("Detect(%q) = %q, %v, want %q", filename, format.Name, exists, want)
+		}
+	}
+}
+
+func TestRegisterPanics(t *testing.T) {
+	registerTestFormats()
+
+	tests := map[string]Format{
+		"duplicate name":      {Name: "test-text", Extensions: []string{".test-other"}, New: newTestGenerator},
+		"duplicate extension": {Name: "test-other", Extensions: []string{".TEST-PAGES"}, New: newTestGenerator},
+		"no generator":        {Name: "test-other", Extensions: []string{".test-other"}},
+	}
+
+	for name, format := range tests {
+		t.Run(name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("Register(%+v) did not panic", format)
+				}
+			}()
+			Register(format)
+		})
+	}
+
+	if _, err := Lookup("test-other"); err == nil {
+		t.Error("a format that failed to register can be looked up")
+	}
+}
+
+func TestNames(t *testing.T) {
+	registerTestFormats()
+
+	names := Names()
+	for i := 1; i < len(names); i++ {
+		if names[i-1] >= names[i] {
+			t.Errorf("names %v are not sorted", names)
+		}
+	}
+}
+
+func TestSupports(t *testing.T) {
+	format := Format{Features: FeatureMultiPage | FeatureTheme}
+
+	tests := []struct {
+		features Feature
+		want     bool
+	}{
+		{0, true},
+		{FeatureTheme, true},
+		{FeatureMultiPage | FeatureTheme, true},
+		{FeatureUpdate, false},
+		{FeatureTheme | FeatureLayers, false},
+	}
+
+	for _, tt := range tests {
+		if got := format.Supports(tt.features); got != tt.want {
+			t.Errorf("Supports(%b) = %v, want %v", tt.features, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,349 @@
+package svg
+
+import (
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"k8s-to-drawio/internal/drawio"
+	"k8s-to-drawio/internal/output"
+	"k8s-to-drawio/pkg/models"
+)
+
+// FormatName is the name of the output format of this package
+const FormatName = "svg"
+
+func init() {
+	output.Register(output.Format{
+		Name:       FormatName,
+		Extensions: []string{".svg"},
+		New: func(opts output.Options) (output.Generator, error) {
+			return NewGenerator(opts.Layout, opts.NoNamespaces), nil
+		},
+	})
+}
+
+// Drawing settings
+const (
+	margin         = 80.0 // space around the diagram, matching the layout's origin
+	fontSize       = 12.0
+	charWidth      = fontSize * 0.6 // estimated average character width used to wrap labels
+	lineHeight     = 1.2            // line height of multi-line labels, in em
+	namespaceTitle = 30.0           // height of the header of namespace containers, see drawio.NamespaceStyle
+	parallelOffset = 14.0           // distance between connections of the same pair of nodes
+)
+
+// Generator renders laid out diagrams as standalone SVG images, with the shapes and
+// colours of the Draw.io styles, without Draw.io or a browser
+type Generator struct {
+	layout *drawio.Layout
+}
+
+// NewGenerator creates a generator placing nodes with the given layout algorithm
+func NewGenerator(layoutAlgorithm string, noNamespaces bool) *Generator {
+	return &Generator{layout: drawio.NewLayout(layoutAlgorithm, noNamespaces)}
+}
+
+// edge is a connection ready to be drawn: clipped at the shapes of its nodes
+type edge struct {
+	connection     models.Connection
+	label          string
+	x1, y1, x2, y2 float64
+	style          map[string]string
+	marker         string
+}
+
+// Generate lays out a diagram and writes it as an SVG image: namespace containers,
+// resource shapes with their labels and arrowed, labelled connections
+func (g *Generator) Generate(w io.Writer, diagram *models.Diagram) error {
+	if err := g.layout.ApplyLayout(diagram); err != nil {
+		return fmt.Errorf("failed to apply layout: %w", err)
+	}
+
+	width, height := 2*margin, 2*margin
+	for _, namespace := range diagram.Namespaces {
+		width = math.Max(width, namespace.X+namespace.Width+margin)
+		height = math.Max(height, namespace.Y+namespace.Height+margin)
+	}
+	for _, node := range diagram.Nodes {
+		width = math.Max(width, node.X+node.Width+margin)
+		height = math.Max(height, node.Y+node.Height+margin)
+	}
+
+	edges := layoutEdges(diagram)
+	markers := make(map[string]string)
+	for _, e := range edges {
+		markers[e.marker] = e.style["strokeColor"]
+	}
+
+	b := bufio.NewWriter(w)
+	b.WriteString(xml.Header)
+	fmt.Fprintf(b, "<svg xmlns=\"http://www.w3.org/2000/svg\" width=\"%s\" height=\"%s\" viewBox=\"0 0 %s %s\" font-family=\"Helvetica, Arial, sans-serif\" font-size=\"%s\">\n",
+		num(width), num(height), num(width), num(height), num(fontSize))
+	b.WriteString("<title>Kubernetes Architecture</title>\n")
+	writeMarkers(b, markers)
+	b.WriteString("<rect width=\"100%\" height=\"100%\" fill=\"#ffffff\"/>\n")
+
+	// Namespaces in a stable order
+	namespaceNames := make([]string, 0, len(diagram.Namespaces))
+	for name := range diagram.Namespaces {
+		namespaceNames = append(namespaceNames, name)
+	}
+	sort.Strings(namespaceNames)
+
+	b.WriteString("<g class=\"namespaces\">\n")
+	for _, name := range namespaceNames {
+		writeNamespace(b, diagram.Namespaces[name])
+	}
+	b.WriteString("</g>\n")
+
+	b.WriteString("<g class=\"nodes\">\n")
+	for _, node := range diagram.Nodes {
+		writeNode(b, node)
+	}
+	b.WriteString("</g>\n")
+
+	b.WriteString("<g class=\"connections\">\n")
+	for _, e := range edges {
+		writeEdge(b, e)
+	}
+	b.WriteString("</g>\n")
+
+	b.WriteString("</svg>\n")
+	return b.Flush()
+}
+
+// layoutEdges returns the connections of a diagram as lines between the borders of
+// their nodes. Connections between the same nodes are drawn side by side.
+func layoutEdges(diagram *models.Diagram) []edge {
+	nodes := make(map[string]models.DiagramNode, len(diagram.Nodes))
+	for _, node := range diagram.Nodes {
+		nodes[node.ID] = node
+	}
+
+	pairKey := func(connection models.Connection) string {
+		if connection.SourceID < connection.TargetID {
+			return connection.SourceID + "|" + connection.TargetID
+		}
+		return connection.TargetID + "|" + connection.SourceID
+	}
+	pairCounts := make(map[string]int)
+	for _, connection := range diagram.Connections {
+		pairCounts[pairKey(connection)]++
+	}
+
+	var edges []edge
+	pairIndex := make(map[string]int)
+	for _, connection := range diagram.Connections {
+		source, sourceExists := nodes[connection.SourceID]
+		target, targetExists := nodes[connection.TargetID]
+		if !sourceExists || !targetExists {
+			continue
+		}
+
+		key := pairKey(connection)
+		offset := (float64(pairIndex[key]) - float64(pairCounts[key]-1)/2) * parallelOffset
+		pairIndex[key]++
+
+		sx, sy := center(source)
+		tx, ty := center(target)
+		if sx == tx && sy == ty {
+			continue
+		}
+		x1, y1 := clip(source, tx, ty)
+		x2, y2 := clip(target, sx, sy)
+
+		// Shift the line perpendicular to its direction, keeping the offset
+		// independent of which end is the source
+		dx, dy := tx-sx, ty-sy
+		if connection.SourceID > connection.TargetID {
+			dx, dy = -dx, -dy
+		}
+		length := math.Hypot(dx, dy)
+		nx, ny := -dy/length*offset, dx/length*offset
+
+		label := connection.Label
+		style := drawio.GetEdgeStyle(string(connection.Relation))
+		if connection.Optional {
+			label += " (optional)"
+			style += drawio.OptionalEdgeStyle
+		}
+		_, attrs := drawio.ParseStyle(style)
+		if attrs["strokeColor"] == "" {
+			attrs["strokeColor"] = "#000000"
+		}
+
+		edges = append(edges, edge{
+			connection: connection,
+			label:      label,
+			x1:         x1 + nx,
+			y1:         y1 + ny,
+			x2:         x2 + nx,
+			y2:         y2 + ny,
+			style:      attrs,
+			marker:     markerID(attrs),
+		})
+	}
+	return edges
+}
+
+// writeMarkers writes the arrow heads used by the connections
+func writeMarkers(b *bufio.Writer, markers map[string]string) {
+	ids := make([]string, 0, len(markers))
+	for id := range markers {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	b.WriteString("<defs>\n")
+	for _, id := range ids {
+		color := markers[id]
+		var head string
+		switch {
+		case strings.HasPrefix(id, "arrow-open-"):
+			head = fmt.Sprintf("<path d=\"M1,1 L9,5 L1,9\" fill=\"none\" stroke=\"%s\" stroke-width=\"1.5\"/>", escape(color))
+		case strings.HasPrefix(id, "arrow-hollow-"):
+			head = fmt.Sprintf("<path d=\"M1,1 L9,5 L1,9 Z\" fill=\"#ffffff\" stroke=\"%s\" stroke-width=\"1.2\"/>", escape(color))
+		default:
+			head = fmt.Sprintf("<path d=\"M0,0 L10,5 L0,10 L3,5 Z\" fill=\"%s\"/>", escape(color))
+		}
+		fmt.Fprintf(b, "<marker id=\"%s\" viewBox=\"0 0 10 10\" refX=\"9\" refY=\"5\" markerWidth=\"8\" markerHeight=\"8\" markerUnits=\"userSpaceOnUse\" orient=\"auto\">%s</marker>\n", id, head)
+	}
+	b.WriteString("</defs>\n")
+}
+
+// writeNamespace writes the container of a namespace with its title in the header
+func writeNamespace(b *bufio.Writer, namespace models.NamespaceGroup) {
+	_, style := drawio.ParseStyle(drawio.NamespaceStyle)
+	label := models.NamespaceLabel(namespace.Name)
+
+	fmt.Fprintf(b, "<g id=\"%s\">\n", escape("ns-"+namespace.Name))
+	fmt.Fprintf(b, "<rect x=\"%s\" y=\"%s\" width=\"%s\" height=\"%s\" fill=\"#ffffff\" stroke=\"%s\"/>\n",
+		num(namespace.X), num(namespace.Y), num(namespace.Width), num(namespace.Height), escape(style["strokeColor"]))
+	fmt.Fprintf(b, "<rect x=\"%s\" y=\"%s\" width=\"%s\" height=\"%s\" fill=\"%s\" stroke=\"%s\"/>\n",
+		num(namespace.X), num(namespace.Y), num(namespace.Width), num(namespaceTitle), escape(style["fillColor"]), escape(style["strokeColor"]))
+	fmt.Fprintf(b, "<text x=\"%s\" y=\"%s\" text-anchor=\"middle\" dominant-baseline=\"central\">%s</text>\n",
+		num(namespace.X+namespace.Width/2), num(namespace.Y+namespaceTitle/2), escape(label))
+	b.WriteString("</g>\n")
+}
+
+// writeNode writes the shape of a resource with its label
+func writeNode(b *bufio.Writer, node models.DiagramNode) {
+	shape, style := drawio.ParseStyle(drawio.GetShapeStyle(node.Kind))
+
+	label := node.Kind + "\n" + node.Label
+	if node.Kind == "VaultSecret" {
+		label = node.Label
+	}
+
+	fmt.Fprintf(b, "<g id=\"%s\">\n", escape(node.ID))
+	fmt.Fprintf(b, "<title>%s</title>\n", escape(node.Kind+" "+node.Label))
+	b.WriteString(shapeElements(shape, style, node))
+
+	cx, cy, textWidth := labelArea(shape, node)
+	var lines []string
+	for _, line := range strings.Split(label, "\n") {
+		lines = append(lines, wrapLine(line, int(textWidth/charWidth))...)
+	}
+	writeText(b, cx, cy, lines, "")
+	b.WriteString("</g>\n")
+}
+
+// writeEdge writes a connection as an arrowed line with its label at the middle
+func writeEdge(b *bufio.Writer, e edge) {
+	strokeWidth := e.style["strokeWidth"]
+	if strokeWidth == "" {
+		strokeWidth = "1"
+	}
+
+	attrs := fmt.Sprintf("stroke=\"%s\" stroke-width=\"%s\"", escape(e.style["strokeColor"]), escape(strokeWidth))
+	if e.style["dashed"] == "1" {
+		pattern := e.style["dashPattern"]
+		if pattern == "" {
+			pattern = "3 3"
+		}
+		attrs += fmt.Sprintf(" stroke-dasharray=\"%s\"", escape(pattern))
+	}
+	if opacity, err := strconv.ParseFloat(e.style["opacity"], 64); err == nil {
+		attrs += fmt.Sprintf(" opacity=\"%s\"", num(opacity/100))
+	}
+
+	fmt.Fprintf(b, "<g id=\"%s\">\n", escape(e.connection.ID))
+	fmt.Fprintf(b, "<line x1=\"%s\" y1=\"%s\" x2=\"%s\" y2=\"%s\" %s marker-end=\"url(#%s)\"/>\n",
+		num(e.x1), num(e.y1), num(e.x2), num(e.y2), attrs, e.marker)
+	if e.label != "" {
+		writeText(b, (e.x1+e.x2)/2, (e.y1+e.y2)/2, []string{e.label},
+			" font-size=\"10\" fill=\"#333333\" stroke=\"#ffffff\" stroke-width=\"3\" paint-order=\"stroke\"")
+	}
+	b.WriteString("</g>\n")
+}
+
+// writeText writes lines of text centred on a point
+func writeText(b *bufio.Writer, x, y float64, lines []string, attrs string) {
+	fmt.Fprintf(b, "<text x=\"%s\" y=\"%s\" text-anchor=\"middle\" dominant-baseline=\"central\"%s>", num(x), num(y), attrs)
+	if len(lines) == 1 {
+		b.WriteString(escape(lines[0]))
+	} else {
+		first := -float64(len(lines)-1) * lineHeight / 2
+		for i, line := range lines {
+			dy := lineHeight
+			if i == 0 {
+				dy = first
+			}
+			fmt.Fprintf(b, "<tspan x=\"%s\" dy=\"%sem\">%s</tspan>", num(x), num(dy), escape(line))
+		}
+	}
+	b.WriteString("</text>\n")
+}
+
+// markerID returns the ID of the arrow head marker of an edge style
+func markerID(style map[string]string) string {
+	kind := "filled"
+	switch {
+	case style["endArrow"] == "open":
+		kind = "open"
+	case style["endFill"] == "0":
+		kind = "hollow"
+	}
+	return fmt.Sprintf("arrow-%s-%s", kind, strings.TrimPrefix(strings.ToLower(style["strokeColor"]), "#"))
+}
+
+// wrapLine breaks a line of a label into lines of at most maxChars characters,
+// preferably after a '-', '.' or '/' separating the parts of resource names
+func wrapLine(line string, maxChars int) []string {
+	if maxChars < 1 {
+		maxChars = 1
+	}
+
+	var lines []string
+	runes := []rune(line)
+	for len(runes) > maxChars {
+		cut := maxChars
+		for i := maxChars - 1; i > 0; i-- {
+			if runes[i] == '-' || runes[i] == '.' || runes[i] == '/' {
+				cut = i + 1
+				break
+			}
+		}
+		lines = append(lines, string(runes[:cut]))
+		runes = runes[cut:]
+	}
+	return append(lines, string(runes))
+}
+
+// num formats a coordinate without exponent or trailing zeros
+func num(v float64) string {
+	return strconv.FormatFloat(math.Round(v*100)/100, 'f', -1, 64)
+}
+
+// escape escapes text for XML character data and attribute values
+func escape(text string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(text))
+	return b.String()
+}
@@ -0,0 +1,219 @@
+package svg
+
+import (
+	"bytes"
+	"encoding/xml"
+	"reflect"
+	"strings"
+	"testing"
+
+	"k8s-to-drawio/pkg/models"
+)
+
+// element is a generic SVG element
+type element struct {
+	XMLName  xml.Name
+	Attrs    []xml.Attr `xml:",any,attr"`
+	Text     string     `xml:",chardata"`
+	Children []element  `xml:",any"`
+}
+
+func (e element) attr(name string) string {
+	for _, attr := range e.Attrs {
+		if attr.Name.Local == name {
+			return attr.Value
+		}
+	}
+	return ""
+}
+
+// text returns the character data of an element and its descendants, without the
+// whitespace between elements
+func (e element) text() string {
+	text := strings.TrimSpace(e.Text)
+	for _, child := range e.Children {
+		text += child.text()
+	}
+	return text
+}
+
+// group returns the child group with a class
+func (e element) group(class string) element {
+	for _, child := range e.Children {
+		if child.XMLName.Local == "g" && child.attr("class") == class {
+			return child
+		}
+	}
+	return element{}
+}
+
+func TestGenerate(t *testing.T) {
+	out := generate(t, NewGenerator("hierarchical", false), testDiagram())
+
+	var svg element
+	if err := xml.Unmarshal(out, &svg); err != nil {
+		t.Fatalf("output is not well-formed XML: %v\n%s", err, out)
+	}
+	if svg.XMLName.Local != "svg" || svg.XMLName.Space != "http://www.w3.org/2000/svg" {
+		t.Fatalf("root element = %v, want svg", svg.XMLName)
+	}
+
+	var namespaces []string
+	for _, namespace := range svg.group("namespaces").Children {
+		namespaces = append(namespaces, namespace.attr("id")+"="+namespace.text())
+	}
+	if want := []string{"ns-shop=Namespace: shop", "ns-vaultstore=vaultstore"}; !reflect.DeepEqual(namespaces, want) {
+		t.Errorf("namespaces = %v, want %v", namespaces, want)
+	}
+
+	// Labels read back unchanged, whatever characters they contain
+	nodes := make(map[string]element)
+	for _, node := range svg.group("nodes").Children {
+		nodes[node.attr("id")] = node
+	}
+	configMap := nodes["configmap-shop-settings-0a1b2c3d"]
+	if got, want := configMap.Children[0].text(), `ConfigMap say "hi" <b> & 'co'`; got != want {
+		t.Errorf("title = %q, want %q", got, want)
+	}
+	if got := nodes["vaultsecret-vaultstore-secret-data-db-61bca63b"].Children[0].text(); got != "VaultSecret secret/data/db" {
+		t.Errorf("VaultSecret title = %q", got)
+	}
+
+	var labels []string
+	for _, connection := range svg.group("connections").Children {
+		labels = append(labels, connection.attr("id")+"="+connection.text())
+	}
+	if want := []string{"edge-1=mounts", "edge-2=a&b (optional)"}; !reflect.DeepEqual(labels, want) {
+		t.Errorf("connections = %v, want %v", labels, want)
+	}
+
+	// The markup itself is escaped
+	for _, want := range []string{
+		`<title>ConfigMap say &#34;hi&#34; &lt;b&gt; &amp; &#39;co&#39;</title>`,
+		`>a&amp;b (optional)</text>`,
+	} {
+		if !bytes.Contains(out, []byte(want)) {
+			t.Errorf("output does not contain %s", want)
+		}
+	}
+}
+
+func TestGenerateMarkers(t *testing.T) {
+	out := generate(t, NewGenerator("hierarchical", false), testDiagram())
+
+	var svg element
+	if err := xml.Unmarshal(out, &svg); err != nil {
+		t.Fatalf("output is not well-formed XML: %v", err)
+	}
+
+	markers := make(map[string]bool)
+	for _, child := range svg.Children {
+		if child.XMLName.Local == "defs" {
+			for _, marker := range child.Children {
+				markers[marker.attr("id")] = true
+			}
+		}
+	}
+	for _, connection := range svg.group("connections").Children {
+		line := connection.Children[0]
+		id := strings.TrimSuffix(strings.TrimPrefix(line.attr("marker-end"), "url(#"), ")")
+		if !markers[id] {
+			t.Errorf("connection %s uses undefined marker %q", connection.attr("id"), id)
+		}
+	}
+	if len(markers) != 2 {
+		t.Errorf("got %d markers, want one per edge style", len(markers))
+	}
+}
+
+func TestGenerateWithoutNamespaces(t *testing.T) {
+	out := generate(t, NewGenerator("hierarchical", true), testDiagram())
+
+	var svg element
+	if err := xml.Unmarshal(out, &svg); err != nil {
+		t.Fatalf("output is not well-formed XML: %v", err)
+	}
+	if namespaces := svg.group("namespaces").Children; len(namespaces) != 0 {
+		t.Errorf("got %d namespace containers, want none", len(namespaces))
+	}
+	if nodes := svg.group("nodes").Children; len(nodes) != 3 {
+		t.Errorf("got %d nodes, want 3", len(nodes))
+	}
+}
+
+func TestWrapLine(t *testing.T) {
+	tests := []struct {
+		line     string
+		maxChars int
+		want     []string
+	}{
+		{"short", 10, []string{"short"}},
+		{"my-very-long-name", 8, []string{"my-very-", "long-", "name"}},
+		{"abcdefghij", 4, []string{"abcd", "efgh", "ij"}},
+		{"secret/data/db", 9, []string{"secret/", "data/db"}},
+		{"grüße", 0, []string{"g", "r", "ü", "ß", "e"}},
+	}
+
+	for _, tt := range tests {
+		if got := wrapLine(tt.line, tt.maxChars); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("wrapLine(%q, %d) = %q, want %q", tt.line, tt.maxChars, got, tt.want)
+		}
+	}
+}
+
+func TestClip(t *testing.T) {
+	box := models.DiagramNode{Kind: "Deployment", X: 0, Y: 0, Width: 100, Height: 50}
+	ellipse := models.DiagramNode{Kind: "Pod", X: 0, Y: 0, Width: 100, Height: 50}
+
+	tests := []struct {
+		name   string
+		node   models.DiagramNode
+		toward [2]float64
+		want   [2]float64
+	}{
+		{"box right", box, [2]float64{200, 25}, [2]float64{100, 25}},
+		{"box below", box, [2]float64{50, 100}, [2]float64{50, 50}},
+		{"box inside", box, [2]float64{60, 25}, [2]float64{60, 25}},
+		{"ellipse above", ellipse, [2]float64{50, -100}, [2]float64{50, 0}},
+	}
+
+	for _, tt := range tests {
+		x, y := clip(tt.node, tt.toward[0], tt.toward[1])
+		if got := [2]float64{x, y}; got != tt.want {
+			t.Errorf("%s: clip() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestEscape(t *testing.T) {
+	if got, want := escape(`<a href="x">&'`), "&lt;a href=&#34;x&#34;&gt;&amp;&#39;"; got != want {
+		t.Errorf("escape() = %q, want %q", got, want)
+	}
+}
+
+// testDiagram returns two namespaces, a label to escape and an optional connection
+func testDiagram() *models.Diagram {
+	return &models.Diagram{
+		Nodes: []models.DiagramNode{
+			{ID: "deployment-shop-web-f9619453", Kind: "Deployment", Label: "web", Namespace: "shop"},
+			{ID: "configmap-shop-settings-0a1b2c3d", Kind: "ConfigMap", Label: `say "hi" <b> & 'co'`, Namespace: "shop"},
+			{ID: "vaultsecret-vaultstore-secret-data-db-61bca63b", Kind: "VaultSecret", Label: "secret/data/db", Namespace: models.VaultSecretNamespace},
+		},
+		Connections: []models.Connection{
+			{ID: "edge-1", SourceID: "deployment-shop-web-f9619453", TargetID: "configmap-shop-settings-0a1b2c3d", Label: "mounts", Relation: models.RelationMounts},
+			{ID: "edge-2", SourceID: "deployment-shop-web-f9619453", TargetID: "vaultsecret-vaultstore-secret-data-db-61bca63b", Label: "a&b", Relation: models.RelationInjectsVaultSecret, Optional: true},
+		},
+		Namespaces: make(map[string]models.NamespaceGroup),
+	}
+}
+
+// generate returns the output of a generator
+func generate(t *testing.T, generator *Generator, diagram *models.Diagram) []byte {
+	t.Helper()
+
+	var out bytes.Buffer
+	if err := generator.Generate(&out, diagram); err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	return out.Bytes()
+}
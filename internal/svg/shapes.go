@@ -0,0 +1,134 @@
+package svg
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+
+	"k8s-to-drawio/internal/drawio"
+	"k8s-to-drawio/pkg/models"
+)
+
+// Default sizes of Draw.io shapes without a size attribute
+const (
+	defaultNoteSize      = 30.0 // folded corner of notes, in pixels
+	defaultCylinderSize  = 15.0 // height of the top of cylinders, in pixels
+	defaultHexagonSize   = 0.25 // slanted sides of hexagons, relative to the width
+	defaultTrapezoidSize = 0.2  // slanted sides of trapezoids, relative to the width
+	monitorStandHeight   = 12.0 // stand below the screen of monitors
+)
+
+// shapeElements returns the SVG elements of a Draw.io shape filling the bounds of a
+// node. Shapes without an SVG equivalent are drawn as rectangles.
+func shapeElements(shape string, style map[string]string, node models.DiagramNode) string {
+	x, y, w, h := node.X, node.Y, node.Width, node.Height
+	stroke := fmt.Sprintf("stroke=\"%s\"", escape(style["strokeColor"]))
+	paint := fmt.Sprintf("fill=\"%s\" %s", escape(style["fillColor"]), stroke)
+
+	switch shape {
+	case "ellipse":
+		return fmt.Sprintf("<ellipse cx=\"%s\" cy=\"%s\" rx=\"%s\" ry=\"%s\" %s/>\n", num(x+w/2), num(y+h/2), num(w/2), num(h/2), paint)
+	case "rhombus":
+		return polygon(paint, x+w/2, y, x+w, y+h/2, x+w/2, y+h, x, y+h/2)
+	case "hexagon":
+		s := w * size(style, defaultHexagonSize)
+		return polygon(paint, x+s, y, x+w-s, y, x+w, y+h/2, x+w-s, y+h, x+s, y+h, x, y+h/2)
+	case "trapezoid":
+		s := w * size(style, defaultTrapezoidSize)
+		return polygon(paint, x+s, y, x+w-s, y, x+w, y+h, x, y+h)
+	case "note":
+		s := math.Min(size(style, defaultNoteSize), math.Min(w, h))
+		return polygon(paint, x, y, x+w-s, y, x+w, y+s, x+w, y+h, x, y+h) +
+			fmt.Sprintf("<path d=\"M%s,%s V%s H%s\" fill=\"none\" %s/>\n", num(x+w-s), num(y), num(y+s), num(x+w), stroke)
+	case "cylinder3":
+		ry := math.Min(size(style, defaultCylinderSize), h/2) / 2
+		rx := w / 2
+		return fmt.Sprintf("<path d=\"M%s,%s A%s,%s 0 0 1 %s,%s V%s A%s,%s 0 0 1 %s,%s Z\" %s/>\n",
+			num(x), num(y+ry), num(rx), num(ry), num(x+w), num(y+ry), num(y+h-ry), num(rx), num(ry), num(x), num(y+h-ry), paint) +
+			fmt.Sprintf("<ellipse cx=\"%s\" cy=\"%s\" rx=\"%s\" ry=\"%s\" %s/>\n", num(x+rx), num(y+ry), num(rx), num(ry), paint)
+	case "monitor":
+		screen := h - monitorStandHeight
+		return fmt.Sprintf("<rect x=\"%s\" y=\"%s\" width=\"%s\" height=\"%s\" rx=\"4\" %s/>\n", num(x), num(y), num(w), num(screen), paint) +
+			polygon(paint, x+w/2-10, y+screen, x+w/2+10, y+screen, x+w/2+16, y+h, x+w/2-16, y+h)
+	default:
+		radius := 0.0
+		if style["rounded"] == "1" {
+			radius = math.Min(w, h) * 0.15
+		}
+		return fmt.Sprintf("<rect x=\"%s\" y=\"%s\" width=\"%s\" height=\"%s\" rx=\"%s\" %s/>\n", num(x), num(y), num(w), num(h), num(radius), paint)
+	}
+}
+
+// labelArea returns the centre and the width available to the label of a shape
+func labelArea(shape string, node models.DiagramNode) (float64, float64, float64) {
+	cx, cy := center(node)
+	width := node.Width - 10
+
+	switch shape {
+	case "rhombus":
+		width = node.Width * 0.6
+	case "ellipse", "hexagon", "trapezoid":
+		width = node.Width * 0.75
+	case "cylinder3":
+		cy += defaultCylinderSize / 4
+	case "monitor":
+		cy -= monitorStandHeight / 2
+	}
+	return cx, cy, width
+}
+
+// center returns the centre of a node
+func center(node models.DiagramNode) (float64, float64) {
+	return node.X + node.Width/2, node.Y + node.Height/2
+}
+
+// clip returns the point where the line from the centre of a node towards a point
+// leaves the shape of the node. Ellipses and rhombi are clipped at their outline,
+// other shapes at their bounds.
+func clip(node models.DiagramNode, towardX, towardY float64) (float64, float64) {
+	cx, cy := center(node)
+	dx, dy := towardX-cx, towardY-cy
+	if dx == 0 && dy == 0 {
+		return cx, cy
+	}
+	rx, ry := node.Width/2, node.Height/2
+
+	shape, _ := drawio.ParseStyle(drawio.GetShapeStyle(node.Kind))
+	var t float64
+	switch shape {
+	case "ellipse":
+		t = 1 / math.Sqrt(dx*dx/(rx*rx)+dy*dy/(ry*ry))
+	case "rhombus":
+		t = 1 / (math.Abs(dx)/rx + math.Abs(dy)/ry)
+	default:
+		t = math.Inf(1)
+		if dx != 0 {
+			t = math.Min(t, rx/math.Abs(dx))
+		}
+		if dy != 0 {
+			t = math.Min(t, ry/math.Abs(dy))
+		}
+	}
+	t = math.Min(t, 1)
+	return cx + dx*t, cy + dy*t
+}
+
+// polygon returns an SVG polygon through the given x, y coordinates
+func polygon(paint string, coordinates ...float64) string {
+	points := ""
+	for i := 0; i+1 < len(coordinates); i += 2 {
+		if i > 0 {
+			points += " "
+		}
+		points += num(coordinates[i]) + "," + num(coordinates[i+1])
+	}
+	return fmt.Sprintf("<polygon points=\"%s\" %s/>\n", points, paint)
+}
+
+// size returns the size attribute of a style, or the default if it is not set
+func size(style map[string]string, defaultSize float64) float64 {
+	if value, err := strconv.ParseFloat(style["size"], 64); err == nil {
+		return value
+	}
+	return defaultSize
+}
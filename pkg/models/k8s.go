@@ -1,6 +1,9 @@
 package models
 
 import (
+	"fmt"
+	"sort"
+
 	"k8s.io/apimachinery/pkg/runtime"
 )
 
@@ -12,50 +15,160 @@ type K8sResource struct {
 	Namespace   string
 	Labels      map[string]string
 	Annotations map[string]string
+	SourceFile  string // path of the manifest the resource was read from
+}
+
+// ResourceID uniquely identifies a resource across groups and namespaces
+type ResourceID struct {
+	Group     string
+	Kind      string
+	Namespace string // empty for cluster-scoped resources
+	Name      string
+}
+
+// String returns the identity as group/kind/namespace/name, using "core" for the empty group
+func (id ResourceID) String() string {
+	group := id.Group
+	if group == "" {
+		group = "core"
+	}
+	return fmt.Sprintf("%s/%s/%s/%s", group, id.Kind, id.Namespace, id.Name)
+}
+
+// Relation describes how a resource depends on the resource it references
+type Relation string
+
+const (
+	RelationMounts             Relation = "mounts"               // volume backed by a ConfigMap, Secret or PVC
+	RelationEnvFrom            Relation = "envFrom"              // environment variables sourced from a ConfigMap or Secret
+	RelationSelects            Relation = "selects"              // label selector matching workloads or Services
+	RelationRoutesTo           Relation = "routes-to"            // Ingress or Route backend
+	RelationBinds              Relation = "binds"                // RoleBinding subject or role reference
+	RelationRunsAs             Relation = "runs-as"              // pod ServiceAccount
+	RelationImagePull          Relation = "image-pull"           // imagePullSecrets entry
+	RelationScrapes            Relation = "scrapes"              // ServiceMonitor target
+	RelationInjectsVaultSecret Relation = "injects-vault-secret" // Bank-Vaults secret injection
+	RelationVaultAuth          Relation = "vault-auth"           // Bank-Vaults TLS secret or auth ServiceAccount
+)
+
+// Dependency is a typed edge from a resource to a resource it references
+type Dependency struct {
+	Source   ResourceID
+	Target   ResourceID
+	Relation Relation
+	Path     string   // JSON path of the reference within the source, e.g. spec.template.spec.volumes[0].configMap.name
+	Optional bool     // the reference is marked optional, so the target may be missing
+	Keys     []string // keys consumed from a ConfigMap or Secret, empty if the whole object is used
 }
 
 // ResourceCollection holds all parsed Kubernetes resources
 type ResourceCollection struct {
 	Resources    []K8sResource
-	Dependencies map[string][]string // resource name -> dependent resource names
+	Dependencies []Dependency
+	Diagnostics  []ParseDiagnostic // documents that were skipped because they failed to parse
+	Skipped      []ParseDiagnostic // documents that are not manifests, such as Helm values or kustomizations
+}
+
+// ParseDiagnostic describes a YAML document that could not be decoded
+type ParseDiagnostic struct {
+	File     string
+	Document int // 1-based index of the document within the file
+	Line     int // approximate 1-based line of the error, 0 for generated documents without a file
+	Err      error
+}
+
+func (d ParseDiagnostic) Error() string {
+	if d.Line == 0 {
+		return fmt.Sprintf("%s: document %d: %v", d.File, d.Document, d.Err)
+	}
+	return fmt.Sprintf("%s:%d: document %d: %v", d.File, d.Line, d.Document, d.Err)
+}
+
+func (d ParseDiagnostic) Unwrap() error {
+	return d.Err
+}
+
+// Property is a named value describing a resource, such as its container images
+type Property struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
 }
 
 // DiagramNode represents a node in the diagram
 type DiagramNode struct {
-	ID          string
-	Label       string
-	Kind        string
-	Namespace   string
-	X           float64
-	Y           float64
-	Width       float64
-	Height      float64
-	Style       string
-	Connections []Connection
+	ID          string            `json:"id"`
+	Label       string            `json:"label"`
+	Kind        string            `json:"kind"`
+	Namespace   string            `json:"namespace,omitempty"`
+	Labels      map[string]string `json:"labels,omitempty"`
+	Properties  []Property        `json:"properties,omitempty"` // custom properties shown in Draw.io's Edit Data dialog
+	X           float64           `json:"x"`
+	Y           float64           `json:"y"`
+	Width       float64           `json:"width"`
+	Height      float64           `json:"height"`
+	Style       string            `json:"-"`
+	Connections []Connection      `json:"-"`
 }
 
 // Connection represents a connection between nodes
 type Connection struct {
-	SourceID string
-	TargetID string
-	Label    string
-	Style    string
+	ID       string   `json:"id"`
+	SourceID string   `json:"source"`
+	TargetID string   `json:"target"`
+	Label    string   `json:"label"`
+	Style    string   `json:"-"`
+	Relation Relation `json:"relation"`
+	Optional bool     `json:"optional,omitempty"` // all references behind the connection are optional
 }
 
 // Diagram represents the complete diagram structure
 type Diagram struct {
-	Nodes       []DiagramNode
-	Connections []Connection
-	Layout      string
-	Namespaces  map[string]NamespaceGroup
+	Nodes       []DiagramNode             `json:"nodes"`
+	Connections []Connection              `json:"connections"`
+	Layout      string                    `json:"layout"`
+	Namespaces  map[string]NamespaceGroup `json:"namespaces,omitempty"`
 }
 
 // NamespaceGroup represents a namespace grouping in the diagram
 type NamespaceGroup struct {
-	Name    string
-	X       float64
-	Y       float64
-	Width   float64
-	Height  float64
-	NodeIDs []string
+	Name    string   `json:"name"`
+	X       float64  `json:"x"`
+	Y       float64  `json:"y"`
+	Width   float64  `json:"width"`
+	Height  float64  `json:"height"`
+	NodeIDs []string `json:"nodeIds"`
+}
+
+// VaultSecretNamespace is the virtual namespace holding Vault secrets referenced by Bank-Vaults annotations
+const VaultSecretNamespace = "vaultstore"
+
+// NamespaceLabel returns the title of the container of a namespace in diagrams. The
+// Vault secrets namespace is shown by name only.
+func NamespaceLabel(namespace string) string {
+	if namespace == VaultSecretNamespace {
+		return namespace
+	}
+	return "Namespace: " + namespace
+}
+
+// GroupByNamespace returns the names of the namespaces of diagram nodes in
+// alphabetical order and the indices of the nodes of each namespace. Nodes without
+// a namespace, such as cluster-scoped resources, are grouped in "default".
+func GroupByNamespace(nodes []DiagramNode) ([]string, map[string][]int) {
+	groups := make(map[string][]int)
+	for i, node := range nodes {
+		namespace := node.Namespace
+		if namespace == "" {
+			namespace = "default"
+		}
+		groups[namespace] = append(groups[namespace], i)
+	}
+
+	names := make([]string, 0, len(groups))
+	for name := range groups {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names, groups
 }
@@ -0,0 +1,36 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGroupByNamespace(t *testing.T) {
+	nodes := []DiagramNode{
+		{ID: "web", Namespace: "shop"},
+		{ID: "view", Kind: "ClusterRole"},
+		{ID: "prometheus", Namespace: "monitoring"},
+		{ID: "db", Namespace: "shop"},
+		{ID: "config", Namespace: "default"},
+	}
+
+	names, groups := GroupByNamespace(nodes)
+	if want := []string{"default", "monitoring", "shop"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("names = %v, want %v", names, want)
+	}
+	want := map[string][]int{"default": {1, 4}, "monitoring": {2}, "shop": {0, 3}}
+	if !reflect.DeepEqual(groups, want) {
+		t.Errorf("groups = %v, want %v", groups, want)
+	}
+}
+
+func TestNamespaceLabel(t *testing.T) {
+	for namespace, want := range map[string]string{
+		"shop":               "Namespace: shop",
+		VaultSecretNamespace: "vaultstore",
+	} {
+		if got := NamespaceLabel(namespace); got != want {
+			t.Errorf("NamespaceLabel(%q) = %q, want %q", namespace, got, want)
+		}
+	}
+}